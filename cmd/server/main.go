@@ -2,12 +2,24 @@
 package main
 
 import (
+	"os"
+
 	"github.com/pavlegich/banners-service/internal/app"
 	"github.com/pavlegich/banners-service/internal/infra/logger"
 	"go.uber.org/zap"
 )
 
 func main() {
+	// Banners export and import commands run without the server
+	if len(os.Args) > 1 && (os.Args[1] == app.CommandExport || os.Args[1] == app.CommandImport) {
+		if err := app.Transfer(os.Args[1], os.Args[2:]); err != nil {
+			logger.Log.Error("main: run transfer failed",
+				zap.Error(err))
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := app.Run(); err != nil {
 		logger.Log.Error("main: run app failed",
 			zap.Error(err))
@@ -3,7 +3,9 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,11 +14,10 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pavlegich/banners-service/internal/controllers/handlers"
+	"github.com/pavlegich/banners-service/internal/domains/banner"
 	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
 	"github.com/pavlegich/banners-service/internal/infra/config"
-	"github.com/pavlegich/banners-service/internal/infra/database"
 	"github.com/pavlegich/banners-service/internal/infra/logger"
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/zap"
@@ -43,31 +44,100 @@ func Run() error {
 		return fmt.Errorf("Run: parse flags failed %w", err)
 	}
 
-	// Database
-	db, err := database.Init(ctx, cfg.DSN)
+	// Storage
+	cache := repository.NewBannerCache(ctx, cfg.DefaultExpiration, cfg.CleanupInterval)
+
+	st, err := openStorage(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("Run: database initialization failed %w", err)
+		return fmt.Errorf("Run: storage initialization failed %w", err)
 	}
-	defer db.Close()
+	defer st.Close()
 
-	// Storage
-	cache := repository.NewBannerCache(ctx, cfg.DefaultExpiration, cfg.CleanupInterval)
-	repo := repository.NewBannerRepository(ctx, db)
+	repo, catalogRepo, auditRepo, webhookRepo, statsRepo, capRepo := st.repo, st.catalogRepo, st.auditRepo, st.webhookRepo, st.statsRepo, st.capRepo
 
 	var wg sync.WaitGroup
+
+	// Read replicas
+	if len(cfg.ReplicaDSNs) != 0 {
+		replicas := make([]repository.ReplicaRepository, 0, len(cfg.ReplicaDSNs))
+		for i, dsn := range cfg.ReplicaDSNs {
+			replica, closeReplica, err := openReplica(ctx, cfg, dsn)
+			if err != nil {
+				logger.Log.Error("Run: replica initialization failed, reads fall back to the primary",
+					zap.Int("replica", i),
+					zap.Error(err))
+
+				continue
+			}
+			defer closeReplica()
+
+			replicas = append(replicas, replica)
+		}
+
+		router := repository.NewReplicaRouter(ctx, repo, replicas, cfg.ReplicaMaxLag, cfg.ReplicaCheck)
+		wg.Add(1)
+		go func() {
+			router.HealthCheck(ctx)
+			wg.Done()
+		}()
+
+		repo = router
+	}
+
 	wg.Add(1)
 	go func() {
 		cache.GarbageCollect(ctx)
 		wg.Done()
 	}()
 
+	// Deleted banners purge
+	service := banner.NewBannerService(ctx, repo, cache, nil, nil, nil, nil, cfg.TemplateVars)
+	wg.Add(1)
+	go func() {
+		service.Purge(ctx, cfg.DeletedRetention, cfg.PurgeInterval)
+		wg.Done()
+	}()
+
 	// Router
-	ctrl := handlers.NewController(ctx, repo, cache, cfg)
+	ctrl := handlers.NewController(ctx, repo, cache, catalogRepo, auditRepo, webhookRepo, statsRepo, capRepo, cfg)
 	mh, err := ctrl.BuildRoute(ctx)
 	if err != nil {
 		return fmt.Errorf("Run: build server route failed %w", err)
 	}
 
+	// Banner changes outbox relay
+	wg.Add(1)
+	go func() {
+		ctrl.Relay(ctx)
+		wg.Done()
+	}()
+
+	// Banner stats flush
+	wg.Add(1)
+	go func() {
+		ctrl.FlushStats(ctx)
+		wg.Done()
+	}()
+
+	// Expired frequency caps purge
+	wg.Add(1)
+	go func() {
+		ctrl.PurgeCaps(ctx)
+		wg.Done()
+	}()
+
+	// Webhook deliveries
+	wg.Add(1)
+	go func() {
+		ctrl.Deliver(ctx)
+		wg.Done()
+	}()
+
+	grpcSrv, err := ctrl.BuildGRPCServer(ctx)
+	if err != nil {
+		return fmt.Errorf("Run: build gRPC server failed %w", err)
+	}
+
 	// Server
 	r := chi.NewRouter()
 	r.Mount("/", mh)
@@ -75,28 +145,68 @@ func Run() error {
 		Addr:    cfg.Address,
 		Handler: r,
 	}
+	srv.RegisterOnShutdown(ctrl.Shutdown)
+
+	lis, err := net.Listen("tcp", cfg.GRPCAddress)
+	if err != nil {
+		return fmt.Errorf("Run: listen gRPC address failed %w", err)
+	}
+
+	go func() {
+		logger.Log.Info("running gRPC server", zap.String("addr", cfg.GRPCAddress))
+
+		err := grpcSrv.Serve(lis)
+		if err != nil {
+			logger.Log.Error("gRPC server failed",
+				zap.Error(err))
+			stop()
+		}
+	}()
 
-	// Server graceful shutdown
+	// Servers graceful shutdown
+	shutdown := make(chan struct{})
 	go func() {
+		defer close(shutdown)
+
 		<-ctx.Done()
-		if ctx.Err() != nil {
-			ctxShutdown, cancelShutdown := context.WithTimeout(ctx, 5*time.Second)
-			defer cancelShutdown()
+		ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdown()
 
-			logger.Log.Info("shutting down gracefully...",
-				zap.Error(ctx.Err()))
+		logger.Log.Info("shutting down gracefully...",
+			zap.Error(ctx.Err()))
 
-			err := srv.Shutdown(ctxShutdown)
-			if err != nil {
-				logger.Log.Error("server shutdown failed",
-					zap.Error(err))
-			}
+		err := srv.Shutdown(ctxShutdown)
+		if err != nil {
+			logger.Log.Error("server shutdown failed",
+				zap.Error(err))
+		}
 
-			wg.Wait()
+		// Wait for the running calls until the shutdown timeout
+		stopped := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctxShutdown.Done():
+			logger.Log.Error("gRPC server shutdown failed",
+				zap.Error(ctxShutdown.Err()))
+			grpcSrv.Stop()
 		}
+
+		wg.Wait()
 	}()
 
 	logger.Log.Info("running server", zap.String("addr", srv.Addr))
 
-	return srv.ListenAndServe()
+	err = srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		stop()
+		return fmt.Errorf("Run: listen and serve failed %w", err)
+	}
+
+	<-shutdown
+
+	return nil
 }
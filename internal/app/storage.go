@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	auditrepo "github.com/pavlegich/banners-service/internal/domains/audit/repository"
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	"github.com/pavlegich/banners-service/internal/domains/capping"
+	caprepo "github.com/pavlegich/banners-service/internal/domains/capping/repository"
+	"github.com/pavlegich/banners-service/internal/domains/catalog"
+	catalogrepo "github.com/pavlegich/banners-service/internal/domains/catalog/repository"
+	"github.com/pavlegich/banners-service/internal/domains/stats"
+	statsrepo "github.com/pavlegich/banners-service/internal/domains/stats/repository"
+	"github.com/pavlegich/banners-service/internal/domains/webhook"
+	webhookrepo "github.com/pavlegich/banners-service/internal/domains/webhook/repository"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/infra/database"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// storage contains the repositories of the configured storage.
+type storage struct {
+	repo        banner.Repository
+	catalogRepo catalog.Repository
+	auditRepo   audit.Repository
+	webhookRepo webhook.Repository
+	statsRepo   stats.Repository
+	capRepo     capping.Repository
+	closers     []func()
+}
+
+// openStorage initializes the configured storage and returns its repositories.
+func openStorage(ctx context.Context, cfg *config.Config) (*storage, error) {
+	st := &storage{}
+
+	switch cfg.Storage {
+	case config.StoragePgx:
+		pool, err := database.InitPool(ctx, cfg, repository.PrepareStatements)
+		if err != nil {
+			return nil, fmt.Errorf("openStorage: database pool initialization failed %w", err)
+		}
+		st.closers = append(st.closers, pool.Close)
+
+		// Only the banners are read on the hot path, so other storages share the pool through database/sql
+		db := stdlib.OpenDBFromPool(pool)
+		st.closers = append(st.closers, func() { db.Close() })
+
+		st.repo = repository.NewBannerPoolRepository(ctx, pool)
+		st.catalogRepo = catalogrepo.NewCatalogRepository(ctx, db)
+		st.auditRepo = auditrepo.NewAuditRepository(ctx, db)
+		st.webhookRepo = webhookrepo.NewWebhookRepository(ctx, db)
+		st.statsRepo = statsrepo.NewStatsRepository(ctx, db)
+		st.capRepo = caprepo.NewCapRepository(ctx, db)
+	case config.StorageMemory:
+		st.auditRepo = auditrepo.NewAuditMemoryRepository(ctx)
+		st.repo = repository.NewBannerMemoryRepository(ctx, st.auditRepo)
+		st.catalogRepo = catalogrepo.NewCatalogMemoryRepository(ctx)
+		st.webhookRepo = webhookrepo.NewWebhookMemoryRepository(ctx)
+		st.statsRepo = statsrepo.NewStatsMemoryRepository(ctx)
+		st.capRepo = caprepo.NewCapMemoryRepository(ctx)
+	default:
+		db, err := database.Init(ctx, cfg.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("openStorage: database initialization failed %w", err)
+		}
+		st.closers = append(st.closers, func() { db.Close() })
+
+		st.repo = repository.NewBannerRepository(ctx, db)
+		st.catalogRepo = catalogrepo.NewCatalogRepository(ctx, db)
+		st.auditRepo = auditrepo.NewAuditRepository(ctx, db)
+		st.webhookRepo = webhookrepo.NewWebhookRepository(ctx, db)
+		st.statsRepo = statsrepo.NewStatsRepository(ctx, db)
+		st.capRepo = caprepo.NewCapRepository(ctx, db)
+	}
+
+	return st, nil
+}
+
+// Close closes the storage connections in the reverse order.
+func (st *storage) Close() {
+	for i := len(st.closers) - 1; i >= 0; i-- {
+		st.closers[i]()
+	}
+}
+
+// openReplica opens the read-only replica and returns its repository and the close function.
+// The replica not available on start is connected lazily, so it is considered unhealthy
+// until the health check succeeds and the reads fall back to the primary meanwhile.
+func openReplica(ctx context.Context, cfg *config.Config, dsn string) (repository.ReplicaRepository, func(), error) {
+	switch cfg.Storage {
+	case config.StoragePgx:
+		pool, err := database.OpenPool(ctx, dsn, cfg, repository.PrepareStatements)
+		if err != nil {
+			logger.Log.Warn("openReplica: replica is not available, connecting lazily",
+				zap.Error(err))
+
+			pool, err = database.ConnectPool(ctx, dsn, cfg, repository.PrepareStatements)
+			if err != nil {
+				return nil, nil, fmt.Errorf("openReplica: replica pool initialization failed %w", err)
+			}
+		}
+
+		return repository.NewBannerPoolRepository(ctx, pool), pool.Close, nil
+	default:
+		db, err := database.Open(ctx, dsn)
+		if err != nil {
+			logger.Log.Warn("openReplica: replica is not available, connecting lazily",
+				zap.Error(err))
+
+			db, err = database.Connect(ctx, dsn)
+			if err != nil {
+				return nil, nil, fmt.Errorf("openReplica: replica initialization failed %w", err)
+			}
+		}
+
+		return repository.NewBannerRepository(ctx, db), func() { db.Close() }, nil
+	}
+}
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"os/user"
+	"syscall"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	"github.com/pavlegich/banners-service/internal/domains/banner/transfer"
+	"github.com/pavlegich/banners-service/internal/domains/catalog"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+)
+
+// List of the banners transfer commands.
+const (
+	CommandExport = "export"
+	CommandImport = "import"
+)
+
+// transferOptions contains values of the transfer command flags.
+type transferOptions struct {
+	format    string
+	file      string
+	featureID int
+	tagID     int
+	limit     int
+	offset    int
+	deleted   bool
+	mode      string
+	dryRun    bool
+}
+
+// Transfer runs the banners export or import command directly on the configured storage.
+// The banners are exported into the file or standard output and imported
+// from the file or standard input, the import report is written to standard output.
+func Transfer(command string, args []string) error {
+	// Context
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt,
+		syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	// Logger
+	err := logger.Init(ctx, "Error")
+	if err != nil {
+		return fmt.Errorf("Transfer: logger initialization failed %w", err)
+	}
+	defer logger.Log.Sync()
+
+	// Configuration
+	var opts transferOptions
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.StringVar(&opts.format, "format", transfer.FormatNDJSON, "file format: ndjson or csv")
+	switch command {
+	case CommandExport:
+		fs.StringVar(&opts.file, "o", "", "output file, standard output by default")
+		fs.IntVar(&opts.featureID, "feature", 0, "export banners of the feature only")
+		fs.IntVar(&opts.tagID, "tag", 0, "export banners of the tag only")
+		fs.IntVar(&opts.limit, "limit", 0, "maximum number of exported banners, no limit by default")
+		fs.IntVar(&opts.offset, "offset", 0, "number of skipped banners")
+		fs.BoolVar(&opts.deleted, "deleted", false, "export deleted banners only")
+	case CommandImport:
+		fs.StringVar(&opts.file, "i", "", "input file, standard input by default")
+		fs.StringVar(&opts.mode, "mode", banner.ImportCreate, "import mode: create or upsert")
+		fs.BoolVar(&opts.dryRun, "dry-run", false, "report the import results without storing the banners")
+	default:
+		return fmt.Errorf("Transfer: unknown command %s", command)
+	}
+
+	cfg := config.NewConfig(ctx)
+	err = cfg.ParseArgs(ctx, fs, args)
+	if err != nil {
+		return fmt.Errorf("Transfer: parse arguments failed %w", err)
+	}
+
+	err = transfer.CheckFormat(opts.format)
+	if err != nil {
+		return fmt.Errorf("Transfer: incorrect format %w", err)
+	}
+
+	// Storage
+	st, err := openStorage(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("Transfer: storage initialization failed %w", err)
+	}
+	defer st.Close()
+
+	cache := repository.NewBannerCache(ctx, cfg.DefaultExpiration, cfg.CleanupInterval)
+	catalogService := catalog.NewCatalogService(ctx, st.catalogRepo, st.repo)
+	service := banner.NewBannerService(ctx, st.repo, cache, catalogService, nil, nil, nil, cfg.TemplateVars)
+
+	// The changes made from the command line are recorded into the audit log
+	// by the operating system user with the cli role
+	ctx = context.WithValue(ctx, utils.ContextRoleKey, "cli")
+	if u, err := user.Current(); err == nil {
+		ctx = utils.WithSubject(ctx, "os:"+u.Username)
+	}
+
+	if command == CommandExport {
+		return exportBanners(ctx, service, &opts)
+	}
+	return importBanners(ctx, service, &opts)
+}
+
+// exportBanners writes the banners by filter into the output file.
+func exportBanners(ctx context.Context, s banner.Service, opts *transferOptions) error {
+	out := io.Writer(os.Stdout)
+	if opts.file != "" {
+		f, err := os.Create(opts.file)
+		if err != nil {
+			return fmt.Errorf("exportBanners: create output file failed %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	enc, err := transfer.NewEncoder(w, opts.format)
+	if err != nil {
+		return fmt.Errorf("exportBanners: create encoder failed %w", err)
+	}
+
+	err = s.Export(ctx, opts.featureID, opts.tagID, opts.limit, opts.offset, opts.deleted, enc.Encode)
+	if err != nil {
+		return fmt.Errorf("exportBanners: export banners failed %w", err)
+	}
+
+	err = enc.Flush()
+	if err != nil {
+		return fmt.Errorf("exportBanners: flush banners failed %w", err)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return fmt.Errorf("exportBanners: write output failed %w", err)
+	}
+
+	return nil
+}
+
+// importBanners stores the banners from the input file and writes the report.
+func importBanners(ctx context.Context, s banner.Service, opts *transferOptions) error {
+	in := io.Reader(os.Stdin)
+	if opts.file != "" {
+		f, err := os.Open(opts.file)
+		if err != nil {
+			return fmt.Errorf("importBanners: open input file failed %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	banners, err := transfer.Decode(bufio.NewReader(in), opts.format)
+	if err != nil {
+		return fmt.Errorf("importBanners: decode banners failed %w", err)
+	}
+
+	results, err := s.Import(ctx, banners, opts.mode, opts.dryRun)
+	if err != nil && !errors.Is(err, errs.ErrBatchNotApplied) {
+		return fmt.Errorf("importBanners: import banners failed %w", err)
+	}
+
+	summary := make(map[string]int)
+	for _, res := range results {
+		summary[res.Status]++
+
+		switch {
+		case res.Err != nil:
+			fmt.Printf("banner %d: %s: %s\n", res.Index+1, res.Status, res.Err)
+		case len(res.Conflicts) != 0:
+			for _, c := range res.Conflicts {
+				with := fmt.Sprintf("stored banner %d", c.BannerID)
+				if c.BannerID == 0 {
+					with = fmt.Sprintf("imported banner %d", c.Index+1)
+				}
+				if c.TagID == 0 {
+					fmt.Printf("banner %d: %s: feature %d default banner is %s\n", res.Index+1, res.Status, c.FeatureID, with)
+					continue
+				}
+				fmt.Printf("banner %d: %s: feature %d and tag %d are used by %s\n", res.Index+1, res.Status, c.FeatureID, c.TagID, with)
+			}
+		case res.Banner != nil:
+			fmt.Printf("banner %d: %s: banner_id %d\n", res.Index+1, res.Status, res.Banner.ID)
+		default:
+			fmt.Printf("banner %d: %s\n", res.Index+1, res.Status)
+		}
+	}
+
+	fmt.Printf("created %d, updated %d, conflicts %d, failed %d, dry run %t\n", summary[banner.BatchCreated],
+		summary[banner.BatchUpdated], summary[banner.ImportConflict], summary[banner.BatchFailed], opts.dryRun)
+
+	if err != nil {
+		return fmt.Errorf("importBanners: import is not applied %w", err)
+	}
+
+	return nil
+}
@@ -4,40 +4,125 @@ package handlers
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/pavlegich/banners-service/internal/controllers/interceptors"
 	"github.com/pavlegich/banners-service/internal/controllers/middlewares"
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	audits "github.com/pavlegich/banners-service/internal/domains/audit/controllers/http"
 	"github.com/pavlegich/banners-service/internal/domains/banner"
+	bannersgrpc "github.com/pavlegich/banners-service/internal/domains/banner/controllers/grpc"
 	banners "github.com/pavlegich/banners-service/internal/domains/banner/controllers/http"
+	"github.com/pavlegich/banners-service/internal/domains/capping"
+	"github.com/pavlegich/banners-service/internal/domains/catalog"
+	catalogs "github.com/pavlegich/banners-service/internal/domains/catalog/controllers/http"
+	"github.com/pavlegich/banners-service/internal/domains/stats"
+	stat "github.com/pavlegich/banners-service/internal/domains/stats/controllers/http"
+	"github.com/pavlegich/banners-service/internal/domains/webhook"
+	webhooks "github.com/pavlegich/banners-service/internal/domains/webhook/controllers/http"
 	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"google.golang.org/grpc"
 )
 
 // Controller contains database and configuration
 // for building the server router.
 type Controller struct {
-	repo  banner.Repository
-	cache banner.Cache
-	cfg   *config.Config
+	repo        banner.Repository
+	cache       banner.Cache
+	catalogRepo catalog.Repository
+	auditRepo   audit.Repository
+	broker      *banner.Broker
+	hooks       *webhook.WebhookService
+	stats       *stats.StatsService
+	caps        *capping.CapService
+	cfg         *config.Config
 }
 
+// historySize is the number of recent banner change events kept for resuming the streams.
+const historySize = 1000
+
 // NewController creates and returns new server controller.
-func NewController(ctx context.Context, repo banner.Repository, cache banner.Cache, cfg *config.Config) *Controller {
+func NewController(ctx context.Context, repo banner.Repository, cache banner.Cache, catalogRepo catalog.Repository, auditRepo audit.Repository, webhookRepo webhook.Repository, statsRepo stats.Repository, capRepo capping.Repository, cfg *config.Config) *Controller {
+	client := &http.Client{Timeout: cfg.WebhookTimeout}
+
 	return &Controller{
-		repo:  repo,
-		cache: cache,
-		cfg:   cfg,
+		repo:        repo,
+		cache:       cache,
+		catalogRepo: catalogRepo,
+		auditRepo:   auditRepo,
+		broker:      banner.NewBroker(historySize),
+		hooks:       webhook.NewWebhookService(ctx, webhookRepo, client, cfg.WebhookAttempts, cfg.WebhookBackoff),
+		stats:       stats.NewStatsService(ctx, statsRepo, repo),
+		caps:        capping.NewCapService(ctx, capRepo),
+		cfg:         cfg,
 	}
 }
 
+// Deliver sends the banner changes to the webhooks until the context is done.
+func (c *Controller) Deliver(ctx context.Context) {
+	c.hooks.Deliver(ctx, c.cfg.WebhookInterval)
+}
+
+// Relay publishes the banner changes from the outbox to the webhooks until the context is done.
+func (c *Controller) Relay(ctx context.Context) {
+	banner.NewRelay(ctx, c.repo, banner.DefaultRelayLease, banner.NewNotifierSink(c.hooks)).Run(ctx, c.cfg.OutboxInterval)
+}
+
+// FlushStats stores the counted banner impressions and clicks until the context is done.
+func (c *Controller) FlushStats(ctx context.Context) {
+	c.stats.Run(ctx, c.cfg.StatsInterval)
+}
+
+// PurgeCaps deletes the expired impressions counters of the frequency caps until the context is done.
+func (c *Controller) PurgeCaps(ctx context.Context) {
+	c.caps.Purge(ctx, c.cfg.PurgeInterval)
+}
+
+// Shutdown finishes the banner change streams, so the server might be shut down gracefully.
+func (c *Controller) Shutdown() {
+	c.broker.Close()
+}
+
 // BuildRoute creates new router and appends handlers and middlewares to it.
 func (c *Controller) BuildRoute(ctx context.Context) (*chi.Mux, error) {
 	r := chi.NewRouter()
 
+	r.Use(middleware.RequestID)
 	r.Use(middlewares.WithLogging)
 	r.Use(middlewares.Recovery)
 	r.Use(middlewares.WithAuth)
 
-	banners.Activate(ctx, r, c.cfg, c.repo, c.cache)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "resource not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteError(w, r, http.StatusMethodNotAllowed, utils.CodeMethodNotAllowed, "method not allowed for the resource")
+	})
+
+	auditService := audit.NewAuditService(ctx, c.auditRepo)
+	catalogService := catalog.NewCatalogService(ctx, c.catalogRepo, c.repo)
+	audits.Activate(ctx, r, c.cfg, auditService)
+	webhooks.Activate(ctx, r, c.cfg, c.hooks)
+	catalogs.Activate(ctx, r, c.cfg, catalogService)
+	stat.Activate(ctx, r, c.cfg, c.stats)
+	banners.Activate(ctx, r, c.cfg, c.repo, c.cache, catalogService, c.broker, c.stats, c.caps)
 
 	return r, nil
 }
+
+// BuildGRPCServer creates new gRPC server and registers services and interceptors on it.
+func (c *Controller) BuildGRPCServer(ctx context.Context) (*grpc.Server, error) {
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		interceptors.WithLogging,
+		interceptors.Recovery,
+		interceptors.WithAuth,
+	))
+
+	catalogService := catalog.NewCatalogService(ctx, c.catalogRepo, c.repo)
+	bannersgrpc.Activate(ctx, s, c.repo, c.cache, catalogService, c.broker, c.stats, c.caps, c.cfg.TemplateVars)
+
+	return s, nil
+}
@@ -0,0 +1,66 @@
+// Package interceptors contains gRPC server interceptors for logging,
+// authorization check and recovery actions.
+package interceptors
+
+import (
+	"context"
+
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+	bannerv1 "github.com/pavlegich/banners-service/proto/banner/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// userMethods contains the methods allowed for the user role.
+var userMethods = map[string]bool{
+	bannerv1.BannerService_GetUserBanner_FullMethodName: true,
+}
+
+// WithAuth checks and validates authorization token from the request metadata.
+func WithAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	token := tokenFromMetadata(ctx)
+
+	role := utils.RoleByToken(token)
+	switch role {
+	case "admin":
+	case "user":
+		if !userMethods[info.FullMethod] {
+			logger.Log.Error("WithAuth: no permissions to access method",
+				zap.String("role", role),
+				zap.String("method", info.FullMethod))
+			return nil, status.Error(codes.PermissionDenied, "no permissions to access the method")
+		}
+	default:
+		logger.Log.Error("WithAuth: unknown user role",
+			zap.String("role", token))
+		return nil, status.Error(codes.Unauthenticated, "authorization token is missing or unknown")
+	}
+
+	ctx = context.WithValue(ctx, utils.ContextRoleKey, role)
+	ctx = utils.WithUserID(ctx, valueFromMetadata(ctx, "user-id"))
+	return handler(ctx, req)
+}
+
+// tokenFromMetadata returns the authorization token from the request metadata.
+func tokenFromMetadata(ctx context.Context) string {
+	return valueFromMetadata(ctx, "token")
+}
+
+// valueFromMetadata returns the first value of the key from the request metadata.
+func valueFromMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -0,0 +1,30 @@
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// WithLogging logs calls of the gRPC methods.
+func WithLogging(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	duration := time.Since(start)
+
+	logger.Log.Info("incoming gRPC request",
+		zap.String("method", info.FullMethod),
+		zap.Duration("duration", duration),
+		zap.String("user_role", utils.RoleByToken(tokenFromMetadata(ctx))),
+		zap.String("code", status.Code(err).String()),
+	)
+
+	return resp, err
+}
@@ -0,0 +1,27 @@
+package interceptors
+
+import (
+	"context"
+
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Recovery recovers server operation when a gRPC method panic occurs.
+func Recovery(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			logger.Log.Error("server panic",
+				zap.Any("error", r),
+			)
+
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+
+	return handler(ctx, req)
+}
@@ -9,32 +9,44 @@ import (
 	"go.uber.org/zap"
 )
 
+// userPaths contains the resources allowed for the user role.
+var userPaths = map[string]bool{
+	"/user_banner":        true,
+	"/user_banner/batch":  true,
+	"/user_banner/stream": true,
+	"/user_banner/click":  true,
+}
+
+// UserIDHeader is the header with the ID of the user,
+// which the banner variants are chosen for.
+const UserIDHeader = "X-User-ID"
+
 // WithAuth checks and validates authorization token.
 func WithAuth(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := r.Header.Get("token")
 
-		var role string
-		switch token {
-		case "admin_token":
-			role = "admin"
-		case "user_token":
-			role = "user"
-			if r.URL.Path != "/user_banner" {
+		role := utils.RoleByToken(token)
+		switch role {
+		case "admin":
+		case "user":
+			if !userPaths[r.URL.Path] {
 				logger.Log.Error("WithAuth: no permissions to access resource",
 					zap.String("role", role),
 					zap.String("uri", r.RequestURI))
-				w.WriteHeader(http.StatusForbidden)
+				utils.WriteError(w, r, http.StatusForbidden, utils.CodeForbidden, "no permissions to access the resource")
 				return
 			}
 		default:
 			logger.Log.Error("WithAuth: unknown user role",
 				zap.String("role", token))
-			w.WriteHeader(http.StatusUnauthorized)
+			utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "authorization token is missing or unknown")
 			return
 		}
 
 		ctx := context.WithValue(r.Context(), utils.ContextRoleKey, role)
+		ctx = utils.WithSubject(ctx, utils.SubjectByToken(token))
+		ctx = utils.WithUserID(ctx, r.Header.Get(UserIDHeader))
 		h.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
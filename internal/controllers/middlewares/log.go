@@ -2,11 +2,12 @@
 package middlewares
 
 import (
-	"bytes"
 	"net/http"
 	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
 	"go.uber.org/zap"
 )
 
@@ -15,10 +16,10 @@ func WithLogging(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		// The body is not captured, so the streamed responses are not kept in memory
 		responseData := &logger.ResponseData{
 			Status: 0,
 			Size:   0,
-			Body:   bytes.NewBufferString(""),
 		}
 		lw := logger.LoggingResponseWriter{
 			ResponseWriter: w,
@@ -28,13 +29,13 @@ func WithLogging(h http.Handler) http.Handler {
 		h.ServeHTTP(&lw, r)
 
 		duration := time.Since(start)
-		// role, _ := utils.GetUserRoleFromContext(r.Context())
 
 		logger.Log.Info("incoming HTTP request",
 			zap.String("uri", r.RequestURI),
 			zap.String("method", r.Method),
 			zap.Duration("duration", duration),
-			// zap.String("user_role", role),
+			zap.String("user_role", utils.RoleByToken(r.Header.Get("token"))),
+			zap.String("request_id", middleware.GetReqID(r.Context())),
 			zap.Int("status", responseData.Status),
 			zap.Int("size", responseData.Size),
 			// zap.String("body", responseData.Body.String()),
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
 	"go.uber.org/zap"
 )
 
@@ -17,7 +18,7 @@ func Recovery(next http.Handler) http.Handler {
 					zap.Any("error", err),
 				)
 
-				w.WriteHeader(http.StatusInternalServerError)
+				utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
 			}
 		}()
 		next.ServeHTTP(w, r)
@@ -0,0 +1,117 @@
+// Package http contains audit object functions
+// for activating the handler in controller, and handlers.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"go.uber.org/zap"
+)
+
+// AuditHandler contains objects for work with audit handlers.
+type AuditHandler struct {
+	Config  *config.Config
+	Service audit.Service
+}
+
+// Activate activates handler for audit object.
+func Activate(ctx context.Context, r *chi.Mux, cfg *config.Config, s audit.Service) {
+	h := &AuditHandler{
+		Config:  cfg,
+		Service: s,
+	}
+
+	r.Get("/audit", h.HandleGetAudit)
+}
+
+// HandleGetAudit handles admin's request to get list of audit log entries.
+func (h *AuditHandler) HandleGetAudit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var filter audit.Filter
+	want := map[string]struct{}{
+		"banner_id": {},
+		"actor":     {},
+		"from":      {},
+		"to":        {},
+		"limit":     {},
+		"offset":    {},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	queries := r.URL.Query()
+	for val := range queries {
+		_, ok := want[val]
+		if !ok {
+			logger.Log.Error("HandleGetAudit: incorrect query",
+				zap.String("query", val))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query in request url")
+			return
+		}
+
+		if len(queries[val]) != 1 {
+			logger.Log.Error("HandleGetAudit: incorrect queries number",
+				zap.String("query_name", val),
+				zap.Int("query_number", len(queries[val])))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
+			return
+		}
+
+		var err error
+		switch val {
+		case "actor":
+			filter.Actor = queries[val][0]
+		case "from":
+			filter.From, err = time.Parse(time.RFC3339, queries[val][0])
+		case "to":
+			filter.To, err = time.Parse(time.RFC3339, queries[val][0])
+		case "banner_id":
+			filter.BannerID, err = strconv.Atoi(queries[val][0])
+		case "limit":
+			filter.Limit, err = strconv.Atoi(queries[val][0])
+		case "offset":
+			filter.Offset, err = strconv.Atoi(queries[val][0])
+		}
+		if err != nil {
+			logger.Log.Error("HandleGetAudit: convert query failed",
+				zap.String("query_name", val),
+				zap.String("query_value", queries[val][0]))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "convert query failed")
+			return
+		}
+	}
+
+	entries, err := h.Service.List(ctx, &filter)
+	if err != nil {
+		logger.Log.Error("HandleGetAudit: get audit log entries failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	entriesJSON, err := json.Marshal(entries)
+	if err != nil {
+		logger.Log.Error("HandleGetAudit: marshal audit log entries failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(entriesJSON)
+}
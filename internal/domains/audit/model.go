@@ -0,0 +1,64 @@
+// Package audit contains object and methods
+// for recording and reading the banners changes.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// List of actions with banners recorded in the audit log.
+const (
+	ActionCreated     = "created"
+	ActionUpdated     = "updated"
+	ActionActivated   = "activated"
+	ActionDeactivated = "deactivated"
+	ActionDeleted     = "deleted"
+	ActionRestored    = "restored"
+)
+
+// Entry contains data for the audit log entry.
+type Entry struct {
+	ID        int64             `json:"id"`
+	BannerID  int               `json:"banner_id"`
+	Actor     string            `json:"actor"`
+	Role      string            `json:"role"`
+	Action    string            `json:"action"`
+	RequestID string            `json:"request_id"`
+	Before    json.RawMessage   `json:"before"`
+	After     json.RawMessage   `json:"after"`
+	Diff      map[string]Change `json:"diff"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Change contains the field values before and after the change.
+type Change struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// Filter contains data for filtering the audit log entries,
+// zero values mean no filtering by the field.
+type Filter struct {
+	BannerID int
+	Actor    string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
+
+// Service describes methods for communication between
+// handlers, other services and repositories.
+type Service interface {
+	Record(ctx context.Context, action string, bannerID int, before any, after any) error
+	List(ctx context.Context, filter *Filter) ([]*Entry, error)
+}
+
+// Repository describes methods related with audit log
+// for interaction with the storage.
+type Repository interface {
+	CreateEntry(ctx context.Context, entry *Entry) (*Entry, error)
+	GetEntriesByFilter(ctx context.Context, filter *Filter) ([]*Entry, error)
+}
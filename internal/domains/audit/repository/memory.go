@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+)
+
+// MemoryRepository contains audit log stored in memory
+// for tests and local development.
+type MemoryRepository struct {
+	sync.RWMutex
+	entries []audit.Entry
+}
+
+// NewAuditMemoryRepository returns new in-memory audit log repository object.
+func NewAuditMemoryRepository(ctx context.Context) *MemoryRepository {
+	return &MemoryRepository{
+		entries: make([]audit.Entry, 0),
+	}
+}
+
+// CreateEntry stores new audit log entry into the storage.
+func (r *MemoryRepository) CreateEntry(ctx context.Context, e *audit.Entry) (*audit.Entry, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	e.ID = int64(len(r.entries) + 1)
+	e.CreatedAt = time.Now().Round(time.Microsecond)
+	r.entries = append(r.entries, *e)
+
+	return e, nil
+}
+
+// GetEntriesByFilter gets and returns the audit log entries by filter
+// from the storage sorted from newest to oldest.
+func (r *MemoryRepository) GetEntriesByFilter(ctx context.Context, f *audit.Filter) ([]*audit.Entry, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	entries := make([]*audit.Entry, 0)
+	skipped := 0
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		e := r.entries[i]
+		if f.BannerID != 0 && e.BannerID != f.BannerID {
+			continue
+		}
+		if f.Actor != "" && e.Actor != f.Actor {
+			continue
+		}
+		if !f.From.IsZero() && e.CreatedAt.Before(f.From) {
+			continue
+		}
+		if !f.To.IsZero() && !e.CreatedAt.Before(f.To) {
+			continue
+		}
+		if skipped < f.Offset {
+			skipped++
+			continue
+		}
+		if f.Limit != 0 && len(entries) == f.Limit {
+			break
+		}
+
+		entries = append(entries, &e)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,119 @@
+// Package repository contains repository objects
+// and methods for interaction with audit log storage.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+)
+
+// Repository contains storage objects for storing the audit log.
+type Repository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository returns new audit log repository object.
+func NewAuditRepository(ctx context.Context, db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateEntry stores new audit log entry into the storage.
+func (r *Repository) CreateEntry(ctx context.Context, e *audit.Entry) (*audit.Entry, error) {
+	diff, err := json.Marshal(e.Diff)
+	if err != nil {
+		return nil, fmt.Errorf("CreateEntry: marshal diff failed %w", err)
+	}
+
+	row := r.db.QueryRowContext(ctx, `INSERT INTO audit_log (banner_id, actor, role, action, request_id, before, after, diff) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`,
+		e.BannerID, e.Actor, e.Role, e.Action, e.RequestID, nullJSON(e.Before), nullJSON(e.After), diff)
+
+	err = row.Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("CreateEntry: scan row failed %w", err)
+	}
+
+	err = row.Err()
+	if err != nil {
+		return nil, fmt.Errorf("CreateEntry: row.Err %w", err)
+	}
+
+	return e, nil
+}
+
+// GetEntriesByFilter gets and returns the audit log entries by filter
+// from the storage sorted from newest to oldest.
+func (r *Repository) GetEntriesByFilter(ctx context.Context, f *audit.Filter) ([]*audit.Entry, error) {
+	query := "SELECT id, banner_id, actor, role, action, request_id, before, after, diff, created_at FROM audit_log WHERE true"
+	args := make([]any, 0)
+	if f.BannerID != 0 {
+		args = append(args, f.BannerID)
+		query += fmt.Sprintf(" AND banner_id = $%d", len(args))
+	}
+	if f.Actor != "" {
+		args = append(args, f.Actor)
+		query += fmt.Sprintf(" AND actor = $%d", len(args))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		query += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		query += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+
+	query += " ORDER BY id DESC"
+
+	if f.Limit != 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+	query += fmt.Sprintf(" OFFSET %d", f.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetEntriesByFilter: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*audit.Entry, 0)
+	for rows.Next() {
+		var e audit.Entry
+		var before, after, diff []byte
+		err = rows.Scan(&e.ID, &e.BannerID, &e.Actor, &e.Role, &e.Action, &e.RequestID, &before, &after, &diff, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("GetEntriesByFilter: scan row failed %w", err)
+		}
+
+		e.Before = before
+		e.After = after
+		err = json.Unmarshal(diff, &e.Diff)
+		if err != nil {
+			return nil, fmt.Errorf("GetEntriesByFilter: unmarshal diff failed %w", err)
+		}
+
+		entries = append(entries, &e)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetEntriesByFilter: rows.Err %w", err)
+	}
+
+	return entries, nil
+}
+
+// nullJSON returns JSON document for storing or nil for storing NULL.
+func nullJSON(data json.RawMessage) any {
+	if data == nil {
+		return nil
+	}
+
+	return string(data)
+}
@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/pavlegich/banners-service/internal/utils"
+)
+
+// AuditService contains objects for audit service.
+type AuditService struct {
+	repo Repository
+}
+
+// NewAuditService returns new audit service.
+func NewAuditService(ctx context.Context, repo Repository) *AuditService {
+	return &AuditService{
+		repo: repo,
+	}
+}
+
+// Record stores new audit log entry about the banner change made by the actor
+// from context. Before and after are the banner states, nil if there is no state.
+func (s *AuditService) Record(ctx context.Context, action string, bannerID int, before any, after any) error {
+	role, err := utils.GetUserRoleFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("Record: get user role from context failed %w", err)
+	}
+
+	entry, err := NewEntry(Actor(ctx, role), role, middleware.GetReqID(ctx), action, bannerID, before, after)
+	if err != nil {
+		return fmt.Errorf("Record: build entry failed %w", err)
+	}
+
+	_, err = s.repo.CreateEntry(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("Record: create entry failed %w", err)
+	}
+
+	return nil
+}
+
+// Actor returns the identity of the token holder from context, which made the change,
+// or the role, if the change is made without the token.
+func Actor(ctx context.Context, role string) string {
+	if subject := utils.GetSubjectFromContext(ctx); subject != "" {
+		return subject
+	}
+	return role
+}
+
+// NewEntry returns new audit log entry about the banner change made by the actor
+// with the role with the changed fields. Before and after are the banner states,
+// nil if there is no state.
+func NewEntry(actor string, role string, requestID string, action string, bannerID int, before any, after any) (*Entry, error) {
+	entry := &Entry{
+		BannerID:  bannerID,
+		Actor:     actor,
+		Role:      role,
+		Action:    action,
+		RequestID: requestID,
+	}
+
+	var err error
+	entry.Before, err = marshalState(before)
+	if err != nil {
+		return nil, fmt.Errorf("NewEntry: marshal state before failed %w", err)
+	}
+	entry.After, err = marshalState(after)
+	if err != nil {
+		return nil, fmt.Errorf("NewEntry: marshal state after failed %w", err)
+	}
+
+	entry.Diff, err = diff(entry.Before, entry.After)
+	if err != nil {
+		return nil, fmt.Errorf("NewEntry: get states diff failed %w", err)
+	}
+
+	return entry, nil
+}
+
+// List returns list of audit log entries by filter from newest to oldest.
+func (s *AuditService) List(ctx context.Context, filter *Filter) ([]*Entry, error) {
+	entries, err := s.repo.GetEntriesByFilter(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("List: get entries by filter failed %w", err)
+	}
+
+	return entries, nil
+}
+
+// marshalState returns JSON representation of the state or nil, if there is no state.
+func marshalState(state any) (json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("marshalState: marshal failed %w", err)
+	}
+	if bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+
+	return data, nil
+}
+
+// diff returns the changed top-level fields of the JSON objects.
+func diff(before json.RawMessage, after json.RawMessage) (map[string]Change, error) {
+	var beforeFields, afterFields map[string]json.RawMessage
+	if before != nil {
+		err := json.Unmarshal(before, &beforeFields)
+		if err != nil {
+			return nil, fmt.Errorf("diff: unmarshal before failed %w", err)
+		}
+	}
+	if after != nil {
+		err := json.Unmarshal(after, &afterFields)
+		if err != nil {
+			return nil, fmt.Errorf("diff: unmarshal after failed %w", err)
+		}
+	}
+
+	changes := make(map[string]Change)
+	for name, value := range beforeFields {
+		if !bytes.Equal(value, afterFields[name]) {
+			changes[name] = Change{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = Change{After: value}
+		}
+	}
+
+	return changes, nil
+}
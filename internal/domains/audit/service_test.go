@@ -0,0 +1,83 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	"github.com/pavlegich/banners-service/internal/domains/audit/repository"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// state is the tracked object state.
+type state struct {
+	Title    string `json:"title"`
+	IsActive bool   `json:"is_active"`
+}
+
+func TestAuditService_Record(t *testing.T) {
+	ctx := context.WithValue(context.Background(), utils.ContextRoleKey, "admin")
+	ctx = utils.WithSubject(ctx, "token:1")
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, "request-1")
+
+	s := audit.NewAuditService(ctx, repository.NewAuditMemoryRepository(ctx))
+
+	err := s.Record(ctx, audit.ActionCreated, 1, nil, &state{Title: "old", IsActive: true})
+	require.NoError(t, err)
+	err = s.Record(ctx, audit.ActionDeactivated, 1, &state{Title: "old", IsActive: true}, &state{Title: "old", IsActive: false})
+	require.NoError(t, err)
+	err = s.Record(ctx, audit.ActionDeleted, 2, &state{Title: "other"}, nil)
+	require.NoError(t, err)
+
+	// Role is required
+	err = s.Record(context.Background(), audit.ActionDeleted, 2, nil, nil)
+	assert.Error(t, err)
+
+	// Role is the actor of the change made without the token
+	err = s.Record(context.WithValue(context.Background(), utils.ContextRoleKey, "cli"), audit.ActionCreated, 3, nil, &state{Title: "cli"})
+	require.NoError(t, err)
+
+	entries, err := s.List(ctx, &audit.Filter{BannerID: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	deactivated := entries[0]
+	assert.Equal(t, audit.ActionDeactivated, deactivated.Action)
+	assert.Equal(t, "token:1", deactivated.Actor)
+	assert.Equal(t, "admin", deactivated.Role)
+	assert.Equal(t, "request-1", deactivated.RequestID)
+	assert.Equal(t, map[string]audit.Change{
+		"is_active": {Before: json.RawMessage("true"), After: json.RawMessage("false")},
+	}, deactivated.Diff)
+
+	created := entries[1]
+	assert.Equal(t, audit.ActionCreated, created.Action)
+	assert.Nil(t, created.Before)
+	assert.JSONEq(t, `{"title": "old", "is_active": true}`, string(created.After))
+	assert.Len(t, created.Diff, 2)
+
+	cli, err := s.List(ctx, &audit.Filter{BannerID: 3})
+	require.NoError(t, err)
+	require.Len(t, cli, 1)
+	assert.Equal(t, "cli", cli[0].Actor)
+	assert.Equal(t, "cli", cli[0].Role)
+
+	deleted, err := s.List(ctx, &audit.Filter{Actor: "token:1", From: time.Now().Add(-time.Minute), Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, 2, deleted[0].BannerID)
+	assert.Nil(t, deleted[0].After)
+
+	none, err := s.List(ctx, &audit.Filter{Actor: "user"})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	none, err = s.List(ctx, &audit.Filter{To: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
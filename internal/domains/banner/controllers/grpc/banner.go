@@ -0,0 +1,324 @@
+// Package grpc contains banners object functions
+// for registering the gRPC service, and its methods.
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	bannerv1 "github.com/pavlegich/banners-service/proto/banner/v1"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// VariantHeader is the response header with the ID of the chosen weighted banner variant.
+const VariantHeader = "x-banner-variant"
+
+// FallbackHeader is the response header reporting that the default banner of the feature
+// is returned, since no banner of the requested tag is available for the user.
+const FallbackHeader = "x-banner-fallback"
+
+// LocaleHeader is the response header with the locale of the returned banner content,
+// the content is localized by the accept-language request metadata.
+const LocaleHeader = "content-language"
+
+// BannerServer contains objects for work with banner gRPC methods.
+type BannerServer struct {
+	bannerv1.UnimplementedBannerServiceServer
+	Service      banner.Service
+	TemplateVars []string
+}
+
+// Activate registers banner gRPC service on the server. The configured template
+// variables are got from the x-banner-var-<name> request metadata.
+func Activate(ctx context.Context, s *grpc.Server, repo banner.Repository, cache banner.Cache, catalog banner.Catalog, broker *banner.Broker, tracker banner.Tracker, capper banner.Capper, templateVars []string) {
+	bannerv1.RegisterBannerServiceServer(s, &BannerServer{
+		Service:      banner.NewBannerService(ctx, repo, cache, catalog, broker, tracker, capper, templateVars),
+		TemplateVars: templateVars,
+	})
+}
+
+// GetUserBanner returns user's banner by feature and tag.
+func (h *BannerServer) GetUserBanner(ctx context.Context, req *bannerv1.GetUserBannerRequest) (*bannerv1.GetUserBannerResponse, error) {
+	if req.GetFeatureId() < 1 || req.GetTagId() < 1 {
+		logger.Log.Error("GetUserBanner: unexpected request value",
+			zap.Int64("feature_id", req.GetFeatureId()),
+			zap.Int64("tag_id", req.GetTagId()))
+
+		return nil, status.Error(codes.InvalidArgument, "feature_id and tag_id must be positive integers")
+	}
+
+	tagIDs := []int{int(req.GetTagId())}
+	b, err := h.Service.Unload(ctx, int(req.GetFeatureId()), tagIDs, req.GetUseLastRevision(), nil)
+	if err != nil {
+		logger.Log.Error("GetUserBanner: get user banner failed",
+			zap.Error(err))
+
+		return nil, statusError(err)
+	}
+
+	if b.Weight > 0 {
+		err = grpc.SetHeader(ctx, metadata.Pairs(VariantHeader, strconv.Itoa(b.ID)))
+		if err != nil {
+			logger.Log.Error("GetUserBanner: set variant header failed",
+				zap.Error(err))
+		}
+	}
+
+	if b.IsFallback(tagIDs) {
+		err = grpc.SetHeader(ctx, metadata.Pairs(FallbackHeader, "true"))
+		if err != nil {
+			logger.Log.Error("GetUserBanner: set fallback header failed",
+				zap.Error(err))
+		}
+	}
+
+	var accepted []string
+	params := make(map[string]string, len(h.TemplateVars))
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		accepted = banner.ParseAcceptLanguage(strings.Join(md.Get("accept-language"), ","))
+		for _, name := range h.TemplateVars {
+			if values := md.Get(banner.TemplateVarHeader(name)); len(values) != 0 {
+				params[name] = values[0]
+			}
+		}
+	}
+	content, locale, err := h.Service.Localize(ctx, b, accepted)
+	if err != nil {
+		logger.Log.Error("GetUserBanner: localize banner content failed",
+			zap.Error(err))
+
+		return nil, statusError(err)
+	}
+
+	if locale != "" {
+		err = grpc.SetHeader(ctx, metadata.Pairs(LocaleHeader, locale))
+		if err != nil {
+			logger.Log.Error("GetUserBanner: set locale header failed",
+				zap.Error(err))
+		}
+	}
+
+	content, _ = h.Service.Render(ctx, b, content, tagIDs, locale, params)
+
+	return &bannerv1.GetUserBannerResponse{
+		Content: contentToProto(content),
+	}, nil
+}
+
+// CreateBanner creates new banner.
+func (h *BannerServer) CreateBanner(ctx context.Context, req *bannerv1.CreateBannerRequest) (*bannerv1.CreateBannerResponse, error) {
+	content := banner.Content(req.GetContent())
+	if content == nil {
+		content = banner.Content{}
+	}
+
+	id, err := h.Service.Create(ctx, &banner.Banner{
+		TagIDs:    intsFromProto(req.GetTagIds()),
+		FeatureID: int(req.GetFeatureId()),
+		Content:   &content,
+		IsActive:  req.GetIsActive(),
+	})
+	if err != nil {
+		logger.Log.Error("CreateBanner: create banner failed",
+			zap.Error(err))
+
+		return nil, statusError(err)
+	}
+
+	return &bannerv1.CreateBannerResponse{
+		BannerId: int64(id),
+	}, nil
+}
+
+// ListBanners returns banners by filter.
+func (h *BannerServer) ListBanners(ctx context.Context, req *bannerv1.ListBannersRequest) (*bannerv1.ListBannersResponse, error) {
+	if req.GetFeatureId() < 0 || req.GetTagId() < 0 || req.GetLimit() < 0 || req.GetOffset() < 0 {
+		logger.Log.Error("ListBanners: unexpected request value",
+			zap.Int64("feature_id", req.GetFeatureId()),
+			zap.Int64("tag_id", req.GetTagId()),
+			zap.Int64("limit", req.GetLimit()),
+			zap.Int64("offset", req.GetOffset()))
+
+		return nil, status.Error(codes.InvalidArgument, "filters, limit and offset must not be negative")
+	}
+
+	banners, err := h.Service.List(ctx, int(req.GetFeatureId()), int(req.GetTagId()),
+		int(req.GetLimit()), int(req.GetOffset()), req.GetDeleted())
+	if err != nil {
+		logger.Log.Error("ListBanners: get banners list failed",
+			zap.Error(err))
+
+		return nil, statusError(err)
+	}
+
+	resp := &bannerv1.ListBannersResponse{
+		Banners: make([]*bannerv1.Banner, 0, len(banners)),
+	}
+	for _, b := range banners {
+		resp.Banners = append(resp.Banners, bannerToProto(b))
+	}
+
+	return resp, nil
+}
+
+// UpdateBanner applies the set fields to the banner with expected version.
+func (h *BannerServer) UpdateBanner(ctx context.Context, req *bannerv1.UpdateBannerRequest) (*bannerv1.UpdateBannerResponse, error) {
+	if req.GetBannerId() < 1 || req.GetVersion() < 0 {
+		logger.Log.Error("UpdateBanner: unexpected request value",
+			zap.Int64("banner_id", req.GetBannerId()),
+			zap.Int64("version", req.GetVersion()))
+
+		return nil, status.Error(codes.InvalidArgument, "banner_id must be a positive integer and version must not be negative")
+	}
+
+	var patch banner.Patch
+	if req.TagIds != nil {
+		tagIDs := intsFromProto(req.GetTagIds().GetValues())
+		patch.TagIDs = &tagIDs
+	}
+	if req.FeatureId != nil {
+		featureID := int(req.GetFeatureId())
+		patch.FeatureID = &featureID
+	}
+	if req.IsActive != nil {
+		isActive := req.GetIsActive()
+		patch.IsActive = &isActive
+	}
+	if req.Content != nil {
+		content, err := json.Marshal(req.GetContent().AsMap())
+		if err != nil {
+			logger.Log.Error("UpdateBanner: marshal content failed",
+				zap.Error(err))
+
+			return nil, status.Error(codes.InvalidArgument, "content must be an object with string values")
+		}
+		patch.Content = content
+	}
+
+	b, err := h.Service.Update(ctx, int(req.GetBannerId()), int(req.GetVersion()), &patch)
+	if err != nil {
+		logger.Log.Error("UpdateBanner: update banner failed",
+			zap.Error(err))
+
+		return nil, statusError(err)
+	}
+
+	return &bannerv1.UpdateBannerResponse{
+		Banner: bannerToProto(b),
+	}, nil
+}
+
+// DeleteBanner deletes the banner with expected version.
+func (h *BannerServer) DeleteBanner(ctx context.Context, req *bannerv1.DeleteBannerRequest) (*bannerv1.DeleteBannerResponse, error) {
+	if req.GetBannerId() < 1 || req.GetVersion() < 0 {
+		logger.Log.Error("DeleteBanner: unexpected request value",
+			zap.Int64("banner_id", req.GetBannerId()),
+			zap.Int64("version", req.GetVersion()))
+
+		return nil, status.Error(codes.InvalidArgument, "banner_id must be a positive integer and version must not be negative")
+	}
+
+	err := h.Service.Delete(ctx, int(req.GetBannerId()), int(req.GetVersion()))
+	if err != nil {
+		logger.Log.Error("DeleteBanner: delete banner failed",
+			zap.Error(err))
+
+		return nil, statusError(err)
+	}
+
+	return &bannerv1.DeleteBannerResponse{}, nil
+}
+
+// statusError converts the service error into the gRPC status error. The field
+// violations of the validation and reference errors are returned in the status details.
+func statusError(err error) error {
+	var verr *errs.ValidationError
+	var rerr *errs.ReferenceError
+	switch {
+	case errors.As(err, &verr):
+		return fieldsError(codes.InvalidArgument, errs.ErrValidationFailed.Error(), verr.Fields)
+	case errors.As(err, &rerr):
+		return fieldsError(codes.FailedPrecondition, errs.ErrUnknownReference.Error(), rerr.Fields)
+	case errors.Is(err, errs.ErrBannerNotFound):
+		return status.Error(codes.NotFound, "banner not found")
+	case errors.Is(err, errs.ErrBannerNotAllowed):
+		return status.Error(codes.PermissionDenied, "banner is not active")
+	case errors.Is(err, errs.ErrBannerCapReached):
+		return status.Error(codes.NotFound, "banner frequency cap reached")
+	case errors.Is(err, errs.ErrBannerVersionConflict):
+		return status.Error(codes.Aborted, "banner version does not match the expected version")
+	default:
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+// fieldsError returns the gRPC status error with the fields in the status details.
+func fieldsError(code codes.Code, msg string, fields []errs.FieldError) error {
+	st := status.New(code, msg)
+	details := &errdetails.BadRequest{}
+	for _, f := range fields {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       f.Field,
+			Description: f.Code + ": " + f.Message,
+		})
+	}
+	if withDetails, err := st.WithDetails(details); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// bannerToProto converts the banner into the protobuf message.
+func bannerToProto(b *banner.Banner) *bannerv1.Banner {
+	pb := &bannerv1.Banner{
+		BannerId:  int64(b.ID),
+		TagIds:    make([]int64, 0, len(b.TagIDs)),
+		FeatureId: int64(b.FeatureID),
+		Content:   contentToProto(b.Content),
+		IsActive:  b.IsActive,
+		CreatedAt: timestamppb.New(b.CreatedAt),
+		UpdatedAt: timestamppb.New(b.UpdatedAt),
+		Version:   int64(b.Version),
+	}
+	for _, id := range b.TagIDs {
+		pb.TagIds = append(pb.TagIds, int64(id))
+	}
+	if b.DeletedAt != nil {
+		pb.DeletedAt = timestamppb.New(*b.DeletedAt)
+	}
+
+	return pb
+}
+
+// contentToProto returns the banner content as the protobuf map.
+func contentToProto(c *banner.Content) map[string]string {
+	if c == nil {
+		return nil
+	}
+	return *c
+}
+
+// intsFromProto converts the protobuf integers.
+func intsFromProto(values []int64) []int {
+	if values == nil {
+		return nil
+	}
+
+	res := make([]int, 0, len(values))
+	for _, v := range values {
+		res = append(res, int(v))
+	}
+	return res
+}
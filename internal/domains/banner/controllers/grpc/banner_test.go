@@ -0,0 +1,211 @@
+package grpc_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/controllers/handlers"
+	auditrepo "github.com/pavlegich/banners-service/internal/domains/audit/repository"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	caprepo "github.com/pavlegich/banners-service/internal/domains/capping/repository"
+	"github.com/pavlegich/banners-service/internal/domains/catalog"
+	catalogrepo "github.com/pavlegich/banners-service/internal/domains/catalog/repository"
+	statsrepo "github.com/pavlegich/banners-service/internal/domains/stats/repository"
+	webhookrepo "github.com/pavlegich/banners-service/internal/domains/webhook/repository"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	bannerv1 "github.com/pavlegich/banners-service/proto/banner/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newCatalogRepo returns the in-memory catalog with the features and tags from 1 to count.
+func newCatalogRepo(t *testing.T, count int) *catalogrepo.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+
+	repo := catalogrepo.NewCatalogMemoryRepository(ctx)
+	for i := 1; i <= count; i++ {
+		for _, kind := range []string{catalog.KindFeature, catalog.KindTag} {
+			_, err := repo.CreateEntity(ctx, kind, &catalog.Entity{Name: fmt.Sprintf("%s %d", kind, i)})
+			require.NoError(t, err)
+		}
+	}
+
+	return repo
+}
+
+// newClient runs the gRPC server on the in-memory storage and returns the client.
+func newClient(t *testing.T) bannerv1.BannerServiceClient {
+	t.Helper()
+	ctx := context.Background()
+
+	auditRepo := auditrepo.NewAuditMemoryRepository(ctx)
+	repo := repository.NewBannerMemoryRepository(ctx, auditRepo)
+	cache := repository.NewBannerCache(ctx, time.Minute, time.Minute)
+	ctrl := handlers.NewController(ctx, repo, cache, newCatalogRepo(t, 10), auditRepo, webhookrepo.NewWebhookMemoryRepository(ctx), statsrepo.NewStatsMemoryRepository(ctx), caprepo.NewCapMemoryRepository(ctx), &config.Config{})
+	srv, err := ctrl.BuildGRPCServer(ctx)
+	require.NoError(t, err)
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return bannerv1.NewBannerServiceClient(conn)
+}
+
+// withToken returns the context with the authorization token in metadata.
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "token", token)
+}
+
+func TestBannerServer_Auth(t *testing.T) {
+	client := newClient(t)
+
+	_, err := client.ListBanners(context.Background(), &bannerv1.ListBannersRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.ListBanners(withToken("unknown_token"), &bannerv1.ListBannersRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.CreateBanner(withToken("user_token"), &bannerv1.CreateBannerRequest{
+		TagIds:    []int64{1},
+		FeatureId: 1,
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetUserBanner(withToken("user_token"), &bannerv1.GetUserBannerRequest{
+		TagId:     1,
+		FeatureId: 1,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestBannerServer_Banners(t *testing.T) {
+	client := newClient(t)
+	admin := withToken("admin_token")
+	user := withToken("user_token")
+
+	created, err := client.CreateBanner(admin, &bannerv1.CreateBannerRequest{
+		TagIds:    []int64{1, 2},
+		FeatureId: 1,
+		Content:   map[string]string{"title": "some_title", "text": "some_text"},
+		IsActive:  true,
+	})
+	require.NoError(t, err)
+	assert.Positive(t, created.GetBannerId())
+
+	got, err := client.GetUserBanner(user, &bannerv1.GetUserBannerRequest{
+		TagId:           2,
+		FeatureId:       1,
+		UseLastRevision: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"title": "some_title", "text": "some_text"}, got.GetContent())
+
+	list, err := client.ListBanners(admin, &bannerv1.ListBannersRequest{FeatureId: 1})
+	require.NoError(t, err)
+	require.Len(t, list.GetBanners(), 1)
+	assert.Equal(t, []int64{1, 2}, list.GetBanners()[0].GetTagIds())
+	assert.EqualValues(t, 1, list.GetBanners()[0].GetVersion())
+
+	// Stale version is rejected
+	_, err = client.UpdateBanner(admin, &bannerv1.UpdateBannerRequest{
+		BannerId: created.GetBannerId(),
+		Version:  2,
+		IsActive: ptr(false),
+	})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	patch, err := structpb.NewStruct(map[string]any{"text": nil, "url": "some_url"})
+	require.NoError(t, err)
+	updated, err := client.UpdateBanner(admin, &bannerv1.UpdateBannerRequest{
+		BannerId: created.GetBannerId(),
+		Version:  1,
+		Content:  patch,
+		IsActive: ptr(false),
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, updated.GetBanner().GetVersion())
+	assert.Equal(t, []int64{1, 2}, updated.GetBanner().GetTagIds())
+	assert.Equal(t, map[string]string{"title": "some_title", "url": "some_url"}, updated.GetBanner().GetContent())
+	assert.False(t, updated.GetBanner().GetIsActive())
+
+	_, err = client.GetUserBanner(user, &bannerv1.GetUserBannerRequest{
+		TagId:           1,
+		FeatureId:       1,
+		UseLastRevision: true,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.DeleteBanner(admin, &bannerv1.DeleteBannerRequest{
+		BannerId: created.GetBannerId(),
+		Version:  1,
+	})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	_, err = client.DeleteBanner(admin, &bannerv1.DeleteBannerRequest{
+		BannerId: created.GetBannerId(),
+		Version:  2,
+	})
+	require.NoError(t, err)
+
+	list, err = client.ListBanners(admin, &bannerv1.ListBannersRequest{Deleted: true})
+	require.NoError(t, err)
+	require.Len(t, list.GetBanners(), 1)
+	assert.NotNil(t, list.GetBanners()[0].GetDeletedAt())
+
+	_, err = client.UpdateBanner(admin, &bannerv1.UpdateBannerRequest{BannerId: 100})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestBannerServer_InvalidArgument(t *testing.T) {
+	client := newClient(t)
+	admin := withToken("admin_token")
+
+	_, err := client.CreateBanner(admin, &bannerv1.CreateBannerRequest{
+		TagIds: []int64{1, 1},
+	})
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+
+	details, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	fields := make([]string, 0, len(details.GetFieldViolations()))
+	for _, v := range details.GetFieldViolations() {
+		fields = append(fields, v.GetField())
+	}
+	assert.Equal(t, []string{"tag_ids[1]", "feature_id"}, fields)
+
+	_, err = client.ListBanners(admin, &bannerv1.ListBannersRequest{Limit: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.DeleteBanner(admin, &bannerv1.DeleteBannerRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetUserBanner(admin, &bannerv1.GetUserBannerRequest{TagId: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
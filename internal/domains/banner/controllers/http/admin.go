@@ -1,7 +1,6 @@
 package http
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -25,6 +24,7 @@ func (h *BannerHandler) HandleGetBanner(w http.ResponseWriter, r *http.Request)
 		"tag_id":     {},
 		"limit":      {},
 		"offset":     {},
+		"deleted":    {},
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -36,9 +36,7 @@ func (h *BannerHandler) HandleGetBanner(w http.ResponseWriter, r *http.Request)
 			logger.Log.Error("HandleGetBanner: incorrect query",
 				zap.String("query", val))
 
-			w.WriteHeader(http.StatusBadRequest)
-			resp := utils.ParamToJSON("error", "incorrect query in request url")
-			w.Write(resp)
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query in request url")
 			return
 		}
 
@@ -47,21 +45,41 @@ func (h *BannerHandler) HandleGetBanner(w http.ResponseWriter, r *http.Request)
 				zap.String("query_name", val),
 				zap.Int("query_number", len(queries[val])))
 
-			w.WriteHeader(http.StatusBadRequest)
-			resp := utils.ParamToJSON("error", "incorrect query number in request url")
-			w.Write(resp)
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
 			return
 		}
 
+		if val == "deleted" {
+			deleted, err := strconv.ParseBool(queries[val][0])
+			if err != nil {
+				logger.Log.Error("HandleGetBanner: convert query to bool failed",
+					zap.String("query_name", val),
+					zap.String("query_value", queries[val][0]))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "convert query to bool failed")
+				return
+			}
+
+			req.deleted = deleted
+			continue
+		}
+
 		current, err := strconv.Atoi(queries[val][0])
 		if err != nil {
 			logger.Log.Error("HandleGetBanner: convert query to integer failed",
 				zap.String("query_name", val),
 				zap.String("query_value", queries[val][0]))
 
-			w.WriteHeader(http.StatusBadRequest)
-			resp := utils.ParamToJSON("error", "convert query to integer failed")
-			w.Write(resp)
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "convert query to integer failed")
+			return
+		}
+
+		if current < 0 {
+			logger.Log.Error("HandleGetBanner: unexpected query value",
+				zap.String("query_name", val),
+				zap.String("query_value", queries[val][0]))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "unexpected query value")
 			return
 		}
 
@@ -77,14 +95,12 @@ func (h *BannerHandler) HandleGetBanner(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	bannersList, err := h.Service.List(ctx, req.featureID, req.tagID, req.limit, req.offset)
+	bannersList, err := h.Service.List(ctx, req.featureID, req.tagID, req.limit, req.offset, req.deleted)
 	if err != nil {
 		logger.Log.Error("HandleGetBanner: get banners list failed",
 			zap.Error(err))
 
-		w.WriteHeader(http.StatusInternalServerError)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
 		return
 	}
 
@@ -93,9 +109,7 @@ func (h *BannerHandler) HandleGetBanner(w http.ResponseWriter, r *http.Request)
 		logger.Log.Error("HandleGetBanner: marshal banner data failed",
 			zap.Error(err))
 
-		w.WriteHeader(http.StatusInternalServerError)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
 		return
 	}
 
@@ -103,34 +117,69 @@ func (h *BannerHandler) HandleGetBanner(w http.ResponseWriter, r *http.Request)
 	w.Write(bannersJSON)
 }
 
-// HandleCreateBanner handles request to create new banner.
-func (h *BannerHandler) HandleCreateBanner(w http.ResponseWriter, r *http.Request) {
+// HandleGetBannerByID handles admin's request to get the banner by ID.
+func (h *BannerHandler) HandleGetBannerByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	var req banner.Banner
-	var buf bytes.Buffer
 
 	w.Header().Set("Content-Type", "application/json")
-	_, err := buf.ReadFrom(r.Body)
+	idString := chi.URLParam(r, "id")
+	if idString == "" {
+		logger.Log.Error("HandleGetBannerByID: id parameter is empty")
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter is empty")
+		return
+	}
+
+	id, err := strconv.Atoi(idString)
+	if err != nil || id < 1 {
+		logger.Log.Error("HandleGetBannerByID: incorrect id parameter",
+			zap.String("id", idString),
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter must be a positive integer")
+		return
+	}
+
+	b, err := h.Service.Get(ctx, id)
 	if err != nil {
-		logger.Log.Error("HandleCreateBanner: read request body failed",
+		logger.Log.Error("HandleGetBannerByID: get banner failed",
 			zap.Error(err))
 
-		w.WriteHeader(http.StatusBadRequest)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		if errors.Is(err, errs.ErrBannerNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "banner not found")
+			return
+		}
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
 		return
 	}
-	defer r.Body.Close()
 
-	err = json.Unmarshal(buf.Bytes(), &req)
+	bannerJSON, err := json.Marshal(b)
 	if err != nil {
-		logger.Log.Error("HandleCreateBanner: request unmarshal failed",
-			zap.String("body", buf.String()),
+		logger.Log.Error("HandleGetBannerByID: marshal banner data failed",
 			zap.Error(err))
 
-		w.WriteHeader(http.StatusBadRequest)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.Header().Set("ETag", etag(b.Version))
+	w.WriteHeader(http.StatusOK)
+	w.Write(bannerJSON)
+}
+
+// HandleCreateBanner handles request to create new banner.
+func (h *BannerHandler) HandleCreateBanner(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req banner.Banner
+
+	w.Header().Set("Content-Type", "application/json")
+	err := decodeBody(w, r, &req)
+	if err != nil {
+		logger.Log.Error("HandleCreateBanner: decode request body failed",
+			zap.Error(err))
+
+		h.writeBodyError(w, r, err)
 		return
 	}
 
@@ -139,9 +188,19 @@ func (h *BannerHandler) HandleCreateBanner(w http.ResponseWriter, r *http.Reques
 		logger.Log.Error("HandleCreateBanner: create banner failed",
 			zap.Error(err))
 
-		w.WriteHeader(http.StatusInternalServerError)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		var verr *errs.ValidationError
+		if errors.As(err, &verr) {
+			utils.WriteValidationError(w, r, verr)
+			return
+		}
+
+		var rerr *errs.ReferenceError
+		if errors.As(err, &rerr) {
+			utils.WriteReferenceError(w, r, rerr)
+			return
+		}
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
 		return
 	}
 
@@ -159,68 +218,72 @@ func (h *BannerHandler) HandleUpdateBanner(w http.ResponseWriter, r *http.Reques
 	if idString == "" {
 		logger.Log.Error("HandleUpdateBanner: id parameter is empty")
 
-		w.WriteHeader(http.StatusBadRequest)
-		resp := utils.ParamToJSON("error", "id parameter is empty")
-		w.Write(resp)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter is empty")
 		return
 	}
 
 	id, err := strconv.Atoi(idString)
-	if err != nil {
-		logger.Log.Error("HandleUpdateBanner: convert id parameter to integer failed",
+	if err != nil || id < 1 {
+		logger.Log.Error("HandleUpdateBanner: incorrect id parameter",
+			zap.String("id", idString),
 			zap.Error(err))
 
-		w.WriteHeader(http.StatusBadRequest)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter must be a positive integer")
 		return
 	}
 
-	var req banner.Banner
-	var buf bytes.Buffer
-
-	req.ID = id
-
-	_, err = buf.ReadFrom(r.Body)
+	version, err := versionFromIfMatch(r)
 	if err != nil {
-		logger.Log.Error("HandleUpdateBanner: read request body failed",
+		logger.Log.Error("HandleUpdateBanner: get version from If-Match header failed",
 			zap.Error(err))
 
-		w.WriteHeader(http.StatusBadRequest)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		h.writeVersionError(w, r, err)
 		return
 	}
-	defer r.Body.Close()
 
-	err = json.Unmarshal(buf.Bytes(), &req)
+	var req banner.Patch
+
+	err = decodeBody(w, r, &req)
 	if err != nil {
-		logger.Log.Error("HandleUpdateBanner: request unmarshal failed",
-			zap.String("body", buf.String()),
+		logger.Log.Error("HandleUpdateBanner: decode request body failed",
 			zap.Error(err))
 
-		w.WriteHeader(http.StatusBadRequest)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		h.writeBodyError(w, r, err)
 		return
 	}
 
-	err = h.Service.Update(ctx, &req)
+	storedBanner, err := h.Service.Update(ctx, id, version, &req)
 	if err != nil {
 		logger.Log.Error("HandleUpdateBanner: update data failed",
 			zap.Error(err))
 
 		if errors.Is(err, errs.ErrBannerNotFound) {
-			w.WriteHeader(http.StatusNotFound)
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "banner not found")
+			return
+		}
+
+		if errors.Is(err, errs.ErrBannerVersionConflict) {
+			h.writeVersionConflict(w, r, id)
+			return
+		}
+
+		var verr *errs.ValidationError
+		if errors.As(err, &verr) {
+			utils.WriteValidationError(w, r, verr)
+			return
+		}
+
+		var rerr *errs.ReferenceError
+		if errors.As(err, &rerr) {
+			utils.WriteReferenceError(w, r, rerr)
 			return
 		}
 
-		w.WriteHeader(http.StatusInternalServerError)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
 		return
 	}
 
+	w.Header().Set("ETag", etag(storedBanner.Version))
 	w.Header().Set("Content-Type", "text/plain")
 	w.WriteHeader(http.StatusOK)
 }
@@ -232,40 +295,90 @@ func (h *BannerHandler) HandleDeleteBanner(w http.ResponseWriter, r *http.Reques
 	w.Header().Set("Content-Type", "application/json")
 	idString := chi.URLParam(r, "id")
 	if idString == "" {
-		logger.Log.Error("HandleUpdateBanner: id parameter is empty")
+		logger.Log.Error("HandleDeleteBanner: id parameter is empty")
 
-		w.WriteHeader(http.StatusBadRequest)
-		resp := utils.ParamToJSON("error", "id parameter is empty")
-		w.Write(resp)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter is empty")
 		return
 	}
 
 	id, err := strconv.Atoi(idString)
+	if err != nil || id < 1 {
+		logger.Log.Error("HandleDeleteBanner: incorrect id parameter",
+			zap.String("id", idString),
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter must be a positive integer")
+		return
+	}
+
+	version, err := versionFromIfMatch(r)
 	if err != nil {
-		logger.Log.Error("HandleUpdateBanner: convert id parameter to integer failed",
+		logger.Log.Error("HandleDeleteBanner: get version from If-Match header failed",
 			zap.Error(err))
 
-		w.WriteHeader(http.StatusBadRequest)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		h.writeVersionError(w, r, err)
 		return
 	}
 
-	err = h.Service.Delete(ctx, id)
+	err = h.Service.Delete(ctx, id, version)
 	if err != nil {
 		logger.Log.Error("HandleDeleteBanner: delete data failed",
 			zap.Error(err))
 
 		if errors.Is(err, errs.ErrBannerNotFound) {
-			w.WriteHeader(http.StatusNotFound)
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "banner not found")
 			return
 		}
 
-		w.WriteHeader(http.StatusInternalServerError)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		if errors.Is(err, errs.ErrBannerVersionConflict) {
+			h.writeVersionConflict(w, r, id)
+			return
+		}
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// HandleRestoreBanner handles request to restore the deleted banner.
+func (h *BannerHandler) HandleRestoreBanner(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	w.Header().Set("Content-Type", "application/json")
+	idString := chi.URLParam(r, "id")
+	if idString == "" {
+		logger.Log.Error("HandleRestoreBanner: id parameter is empty")
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter is empty")
+		return
+	}
+
+	id, err := strconv.Atoi(idString)
+	if err != nil || id < 1 {
+		logger.Log.Error("HandleRestoreBanner: incorrect id parameter",
+			zap.String("id", idString),
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter must be a positive integer")
+		return
+	}
+
+	err = h.Service.Restore(ctx, id)
+	if err != nil {
+		logger.Log.Error("HandleRestoreBanner: restore data failed",
+			zap.Error(err))
+
+		if errors.Is(err, errs.ErrBannerNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "banner not found")
+			return
+		}
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,584 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/controllers/handlers"
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	auditrepo "github.com/pavlegich/banners-service/internal/domains/audit/repository"
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	caprepo "github.com/pavlegich/banners-service/internal/domains/capping/repository"
+	"github.com/pavlegich/banners-service/internal/domains/catalog"
+	catalogrepo "github.com/pavlegich/banners-service/internal/domains/catalog/repository"
+	statsrepo "github.com/pavlegich/banners-service/internal/domains/stats/repository"
+	webhookrepo "github.com/pavlegich/banners-service/internal/domains/webhook/repository"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCatalogRepo returns the in-memory catalog with the features and tags from 1 to count.
+func newCatalogRepo(t *testing.T, count int) *catalogrepo.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+
+	repo := catalogrepo.NewCatalogMemoryRepository(ctx)
+	for i := 1; i <= count; i++ {
+		for _, kind := range []string{catalog.KindFeature, catalog.KindTag} {
+			_, err := repo.CreateEntity(ctx, kind, &catalog.Entity{Name: fmt.Sprintf("%s %d", kind, i)})
+			require.NoError(t, err)
+		}
+	}
+
+	return repo
+}
+
+// newAdminRoute returns the server route on the in-memory storage with the stored banner.
+func newAdminRoute(t *testing.T) (http.Handler, *banner.Banner) {
+	t.Helper()
+	ctx := context.Background()
+
+	auditRepo := auditrepo.NewAuditMemoryRepository(ctx)
+	repo := repository.NewBannerMemoryRepository(ctx, auditRepo)
+	cache := repository.NewBannerCache(ctx, time.Minute, time.Minute)
+
+	stored, err := repo.CreateBanner(ctx, &banner.Banner{
+		TagIDs:    []int{1, 2},
+		FeatureID: 1,
+		Content:   &banner.Content{"title": "some_title"},
+		IsActive:  true,
+	})
+	require.NoError(t, err)
+
+	ctrl := handlers.NewController(ctx, repo, cache, newCatalogRepo(t, 10), auditRepo, webhookrepo.NewWebhookMemoryRepository(ctx), statsrepo.NewStatsMemoryRepository(ctx), caprepo.NewCapMemoryRepository(ctx), &config.Config{TemplateVars: []string{"city", "promo_code"}})
+	mh, err := ctrl.BuildRoute(ctx)
+	require.NoError(t, err)
+
+	return mh, stored
+}
+
+// serve sends the admin request to the route and returns the response.
+func serve(t *testing.T, h http.Handler, method string, url string, body string, headers map[string]string) (*http.Response, string) {
+	t.Helper()
+
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r.Header.Set("token", "admin_token")
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	gotBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(gotBody)
+}
+
+func TestBannerHandler_OptimisticConcurrency(t *testing.T) {
+	mh, stored := newAdminRoute(t)
+	url := "http://localhost:8080/banner/1"
+	body := `{"tag_ids": [1, 2], "feature_id": 1, "content": {"title": "new_title"}, "is_active": true}`
+
+	resp, _ := serve(t, mh, http.MethodGet, url, "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	assert.Equal(t, 1, stored.Version)
+
+	tests := []struct {
+		name     string
+		method   string
+		ifMatch  string
+		wantCode int
+		wantETag string
+	}{
+		{
+			name:     "update without If-Match",
+			method:   http.MethodPatch,
+			wantCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:     "update with incorrect If-Match",
+			method:   http.MethodPatch,
+			ifMatch:  "version",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "update with actual version",
+			method:   http.MethodPatch,
+			ifMatch:  `"1"`,
+			wantCode: http.StatusOK,
+			wantETag: `"2"`,
+		},
+		{
+			name:     "update with stale version",
+			method:   http.MethodPatch,
+			ifMatch:  `"1"`,
+			wantCode: http.StatusPreconditionFailed,
+			wantETag: `"2"`,
+		},
+		{
+			name:     "update with any version",
+			method:   http.MethodPatch,
+			ifMatch:  "*",
+			wantCode: http.StatusOK,
+			wantETag: `"3"`,
+		},
+		{
+			name:     "delete without If-Match",
+			method:   http.MethodDelete,
+			wantCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:     "delete with stale version",
+			method:   http.MethodDelete,
+			ifMatch:  `W/"2"`,
+			wantCode: http.StatusPreconditionFailed,
+			wantETag: `"3"`,
+		},
+		{
+			name:     "delete with actual version",
+			method:   http.MethodDelete,
+			ifMatch:  `"3"`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "delete deleted banner",
+			method:   http.MethodDelete,
+			ifMatch:  `"4"`,
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.ifMatch != "" {
+				headers["If-Match"] = tt.ifMatch
+			}
+
+			resp, gotBody := serve(t, mh, tt.method, url, body, headers)
+			assert.Equal(t, tt.wantCode, resp.StatusCode, gotBody)
+			assert.Equal(t, tt.wantETag, resp.Header.Get("ETag"))
+			if tt.wantCode == http.StatusPreconditionFailed {
+				assert.Contains(t, gotBody, `"version":`+strings.Trim(tt.wantETag, `"`))
+			}
+		})
+	}
+}
+
+func TestBannerHandler_PartialUpdate(t *testing.T) {
+	url := "http://localhost:8080/banner/1"
+	headers := map[string]string{"If-Match": "*"}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		want     string
+	}{
+		{
+			name:     "activity only",
+			body:     `{"is_active": false}`,
+			wantCode: http.StatusOK,
+			want:     `"tag_ids":[1,2],"feature_id":1,"content":{"title":"some_title"},"is_active":false`,
+		},
+		{
+			name:     "content merge patch",
+			body:     `{"content": {"title": null, "text": "some_text"}}`,
+			wantCode: http.StatusOK,
+			want:     `"tag_ids":[1,2],"feature_id":1,"content":{"text":"some_text"},"is_active":true`,
+		},
+		{
+			name:     "tags and feature",
+			body:     `{"tag_ids": [3], "feature_id": 2}`,
+			wantCode: http.StatusOK,
+			want:     `"tag_ids":[3],"feature_id":2,"content":{"title":"some_title"},"is_active":true`,
+		},
+		{
+			name:     "empty tags",
+			body:     `{"tag_ids": []}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "null content",
+			body:     `{"content": null}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "content not an object",
+			body:     `{"content": "some_text"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "incorrect feature",
+			body:     `{"feature_id": 0}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "frequency cap",
+			body:     `{"frequency_cap": {"impressions": 3, "window": 3600}}`,
+			wantCode: http.StatusOK,
+			want:     `"frequency_cap":{"impressions":3,"window":3600}`,
+		},
+		{
+			name:     "frequency cap without window",
+			body:     `{"frequency_cap": {"impressions": 3}}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mh, _ := newAdminRoute(t)
+
+			resp, gotBody := serve(t, mh, http.MethodPatch, url, tt.body, headers)
+			require.Equal(t, tt.wantCode, resp.StatusCode, gotBody)
+
+			resp, gotBody = serve(t, mh, http.MethodGet, url, "", nil)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			if tt.want != "" {
+				assert.Contains(t, gotBody, tt.want)
+				assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+			} else {
+				assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestBannerHandler_Audit(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+	url := "http://localhost:8080/banner/1"
+
+	headers := map[string]string{"If-Match": "*"}
+
+	resp, gotBody := serve(t, mh, http.MethodPatch, url, `{"is_active": false}`, headers)
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	resp, gotBody = serve(t, mh, http.MethodDelete, url, "", headers)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, gotBody)
+
+	resp, gotBody = serve(t, mh, http.MethodGet, "http://localhost:8080/audit?banner_id=1", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+	var entries []*audit.Entry
+	require.NoError(t, json.Unmarshal([]byte(gotBody), &entries))
+	require.Len(t, entries, 3)
+
+	// The changes are made by the admin token holder
+	admin := utils.SubjectByToken("admin_token")
+	assert.Equal(t, audit.ActionDeleted, entries[0].Action)
+	assert.Equal(t, admin, entries[0].Actor)
+	assert.Equal(t, "admin", entries[0].Role)
+	assert.Equal(t, audit.ActionDeactivated, entries[1].Action)
+	assert.Equal(t, admin, entries[1].Actor)
+	assert.Equal(t, "admin", entries[1].Role)
+	assert.Contains(t, entries[1].Diff, "is_active")
+	assert.Equal(t, audit.ActionCreated, entries[2].Action)
+	assert.Equal(t, "system", entries[2].Actor)
+	assert.Equal(t, "system", entries[2].Role)
+}
+
+func TestBannerHandler_ErrorResponses(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		body        string
+		token       string
+		wantCode    int
+		wantErrCode string
+		wantDetails []errs.FieldError
+	}{
+		{
+			name:     "valid banner",
+			method:   http.MethodPost,
+			url:      "http://localhost:8080/banner",
+			body:     `{"tag_ids": [3], "feature_id": 2, "content": {"title": "some_title"}, "is_active": true}`,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:        "empty banner",
+			method:      http.MethodPost,
+			url:         "http://localhost:8080/banner",
+			body:        `{}`,
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeValidationFailed,
+			wantDetails: []errs.FieldError{
+				{Field: "tag_ids", Code: errs.ValidationRequired, Message: "must contain at least one tag"},
+				{Field: "feature_id", Code: errs.ValidationRequired, Message: "is required"},
+				{Field: "content", Code: errs.ValidationRequired, Message: "is required"},
+			},
+		},
+		{
+			name:        "incorrect tags and feature",
+			method:      http.MethodPost,
+			url:         "http://localhost:8080/banner",
+			body:        `{"tag_ids": [1, 0, 1], "feature_id": -1, "content": {}}`,
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeValidationFailed,
+			wantDetails: []errs.FieldError{
+				{Field: "tag_ids[1]", Code: errs.ValidationPositive, Message: "must be positive"},
+				{Field: "tag_ids[2]", Code: errs.ValidationDuplicate, Message: "tag 1 is duplicated"},
+				{Field: "feature_id", Code: errs.ValidationPositive, Message: "must be positive"},
+			},
+		},
+		{
+			name:        "unknown tags and feature",
+			method:      http.MethodPost,
+			url:         "http://localhost:8080/banner",
+			body:        `{"tag_ids": [3, 17], "feature_id": 42, "content": {}}`,
+			wantCode:    http.StatusUnprocessableEntity,
+			wantErrCode: utils.CodeUnknownReference,
+			wantDetails: []errs.FieldError{
+				{Field: "feature_id", Code: errs.ValidationUnknown, Message: "unknown feature 42"},
+				{Field: "tag_ids[1]", Code: errs.ValidationUnknown, Message: "unknown tag 17"},
+			},
+		},
+		{
+			name:        "incorrect field type",
+			method:      http.MethodPost,
+			url:         "http://localhost:8080/banner",
+			body:        `{"tag_ids": [1], "feature_id": "1", "content": {}}`,
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeValidationFailed,
+			wantDetails: []errs.FieldError{
+				{Field: "feature_id", Code: errs.ValidationInvalidType, Message: "must be an integer"},
+			},
+		},
+		{
+			name:        "incorrect json",
+			method:      http.MethodPost,
+			url:         "http://localhost:8080/banner",
+			body:        `{"tag_ids": [1],`,
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeInvalidBody,
+		},
+		{
+			name:        "too large body",
+			method:      http.MethodPost,
+			url:         "http://localhost:8080/banner",
+			body:        `{"content": {"text": "` + strings.Repeat("a", 1<<20) + `"}}`,
+			wantCode:    http.StatusRequestEntityTooLarge,
+			wantErrCode: utils.CodeBodyTooLarge,
+		},
+		{
+			name:        "incorrect id",
+			method:      http.MethodGet,
+			url:         "http://localhost:8080/banner/0",
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeInvalidPath,
+		},
+		{
+			name:        "negative limit",
+			method:      http.MethodGet,
+			url:         "http://localhost:8080/banner?limit=-1",
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeInvalidQuery,
+		},
+		{
+			name:        "banner not found",
+			method:      http.MethodGet,
+			url:         "http://localhost:8080/banner/100",
+			wantCode:    http.StatusNotFound,
+			wantErrCode: utils.CodeNotFound,
+		},
+		{
+			name:        "unknown resource",
+			method:      http.MethodGet,
+			url:         "http://localhost:8080/unknown",
+			wantCode:    http.StatusNotFound,
+			wantErrCode: utils.CodeNotFound,
+		},
+		{
+			name:        "unauthorized",
+			method:      http.MethodGet,
+			url:         "http://localhost:8080/banner",
+			token:       "unknown_token",
+			wantCode:    http.StatusUnauthorized,
+			wantErrCode: utils.CodeUnauthorized,
+		},
+		{
+			name:        "forbidden",
+			method:      http.MethodGet,
+			url:         "http://localhost:8080/banner",
+			token:       "user_token",
+			wantCode:    http.StatusForbidden,
+			wantErrCode: utils.CodeForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.token != "" {
+				headers["token"] = tt.token
+			}
+
+			resp, gotBody := serve(t, mh, tt.method, tt.url, tt.body, headers)
+			require.Equal(t, tt.wantCode, resp.StatusCode, gotBody)
+			if tt.wantErrCode == "" {
+				return
+			}
+
+			var got utils.ErrorResponse
+			require.NoError(t, json.Unmarshal([]byte(gotBody), &got))
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			assert.Equal(t, tt.wantErrCode, got.Code)
+			assert.NotEmpty(t, got.Message)
+			assert.Equal(t, tt.wantDetails, got.Details)
+		})
+	}
+}
+
+func TestBannerHandler_References(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	resp, gotBody := serve(t, mh, http.MethodGet, "http://localhost:8080/banner", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, gotBody, `"feature_name":"feature 1","tag_names":["tag 1","tag 2"]`)
+
+	resp, gotBody = serve(t, mh, http.MethodGet, "http://localhost:8080/banner/1", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, gotBody, `"feature_name":"feature 1","tag_names":["tag 1","tag 2"]`)
+
+	resp, gotBody = serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1", `{"feature_id": 42, "tag_ids": [1, 2, 17]}`,
+		map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var got utils.ErrorResponse
+	require.NoError(t, json.Unmarshal([]byte(gotBody), &got))
+	assert.Equal(t, utils.CodeUnknownReference, got.Code)
+	assert.Equal(t, []errs.FieldError{
+		{Field: "feature_id", Code: errs.ValidationUnknown, Message: "unknown feature 42"},
+		{Field: "tag_ids[2]", Code: errs.ValidationUnknown, Message: "unknown tag 17"},
+	}, got.Details)
+
+	// Referenced entities are not deleted
+	resp, _ = serve(t, mh, http.MethodDelete, "http://localhost:8080/tag/2", "", nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp, _ = serve(t, mh, http.MethodDelete, "http://localhost:8080/tag/3", "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestBannerHandler_Batch(t *testing.T) {
+	url := "http://localhost:8080/banner/batch"
+
+	tests := []struct {
+		name         string
+		body         string
+		wantCode     int
+		wantStatuses []string
+		wantErrCode  string
+		wantTotal    int
+	}{
+		{
+			name: "create and update",
+			body: `{"banners": [
+				{"tag_ids": [3], "feature_id": 2, "content": {"title": "some_title"}, "is_active": true},
+				{"banner_id": 1, "version": 1, "is_active": false}
+			]}`,
+			wantCode:     http.StatusOK,
+			wantStatuses: []string{banner.BatchCreated, banner.BatchUpdated},
+			wantTotal:    2,
+		},
+		{
+			name: "invalid item",
+			body: `{"banners": [
+				{"tag_ids": [3], "feature_id": 2, "content": {"title": "some_title"}, "is_active": true},
+				{"tag_ids": [], "feature_id": 2, "content": {"title": "some_title"}}
+			]}`,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantStatuses: []string{banner.BatchSkipped, banner.BatchFailed},
+			wantErrCode:  utils.CodeValidationFailed,
+			wantTotal:    1,
+		},
+		{
+			name: "stale version",
+			body: `{"banners": [
+				{"tag_ids": [3], "feature_id": 2, "content": {"title": "some_title"}, "is_active": true},
+				{"banner_id": 1, "version": 2, "is_active": false}
+			]}`,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantStatuses: []string{banner.BatchSkipped, banner.BatchFailed},
+			wantErrCode:  utils.CodeVersionConflict,
+			wantTotal:    1,
+		},
+		{
+			name: "duplicated banner",
+			body: `{"banners": [
+				{"banner_id": 1, "is_active": false},
+				{"banner_id": 1, "is_active": true}
+			]}`,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantStatuses: []string{banner.BatchSkipped, banner.BatchFailed},
+			wantErrCode:  utils.CodeValidationFailed,
+			wantTotal:    1,
+		},
+		{
+			name: "unknown tag",
+			body: `{"banners": [
+				{"tag_ids": [3], "feature_id": 2, "content": {"title": "some_title"}, "is_active": true},
+				{"banner_id": 1, "tag_ids": [1, 17]}
+			]}`,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantStatuses: []string{banner.BatchSkipped, banner.BatchFailed},
+			wantErrCode:  utils.CodeUnknownReference,
+			wantTotal:    1,
+		},
+		{
+			name:      "empty batch",
+			body:      `{"banners": []}`,
+			wantCode:  http.StatusBadRequest,
+			wantTotal: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mh, _ := newAdminRoute(t)
+
+			resp, gotBody := serve(t, mh, http.MethodPost, url, tt.body, nil)
+			require.Equal(t, tt.wantCode, resp.StatusCode, gotBody)
+
+			if tt.wantStatuses != nil {
+				var got struct {
+					Code    string `json:"code"`
+					Applied bool   `json:"applied"`
+					Results []struct {
+						Status string               `json:"status"`
+						Error  *utils.ErrorResponse `json:"error"`
+					} `json:"results"`
+				}
+				require.NoError(t, json.Unmarshal([]byte(gotBody), &got))
+				assert.Equal(t, tt.wantCode == http.StatusOK, got.Applied)
+				require.Len(t, got.Results, len(tt.wantStatuses))
+				for i, res := range got.Results {
+					assert.Equal(t, tt.wantStatuses[i], res.Status)
+					if res.Status == banner.BatchFailed {
+						require.NotNil(t, res.Error)
+						assert.Equal(t, tt.wantErrCode, res.Error.Code)
+					}
+				}
+			}
+
+			_, gotBody = serve(t, mh, http.MethodGet, "http://localhost:8080/banner", "", nil)
+			var list []*banner.Banner
+			require.NoError(t, json.Unmarshal([]byte(gotBody), &list))
+			assert.Len(t, list, tt.wantTotal)
+		})
+	}
+}
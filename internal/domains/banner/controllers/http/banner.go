@@ -3,13 +3,31 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/pavlegich/banners-service/internal/domains/banner"
+	errs "github.com/pavlegich/banners-service/internal/errors"
 	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"go.uber.org/zap"
 )
 
+// errIfMatchRequired is returned when the If-Match header is not set.
+var errIfMatchRequired = errors.New("header If-Match is required")
+
+// maxBodySize is the maximum size of the request body in bytes.
+const maxBodySize = 1 << 20
+
 // requestQuery contains data, which might be in request queries.
 type requestQuery struct {
 	tagID        int
@@ -17,6 +35,8 @@ type requestQuery struct {
 	lastRevision bool
 	limit        int
 	offset       int
+	deleted      bool
+	locale       string
 }
 
 // BannersHandler contains objects for work with banner handlers.
@@ -26,8 +46,8 @@ type BannerHandler struct {
 }
 
 // Activate activates handler for banner object.
-func Activate(ctx context.Context, r *chi.Mux, cfg *config.Config, repo banner.Repository, cache banner.Cache) {
-	s := banner.NewBannerService(ctx, repo, cache)
+func Activate(ctx context.Context, r *chi.Mux, cfg *config.Config, repo banner.Repository, cache banner.Cache, catalog banner.Catalog, broker *banner.Broker, tracker banner.Tracker, capper banner.Capper) {
+	s := banner.NewBannerService(ctx, repo, cache, catalog, broker, tracker, capper, cfg.TemplateVars)
 	newHandler(r, cfg, s)
 }
 
@@ -39,8 +59,128 @@ func newHandler(r *chi.Mux, cfg *config.Config, s banner.Service) {
 	}
 
 	r.Get("/user_banner", h.HandleGetUserBanner)
+	r.Get("/user_banner/batch", h.HandleGetUserBanners)
+	r.Get("/user_banner/stream", h.HandleStreamUserBanners)
 	r.Get("/banner", h.HandleGetBanner)
 	r.Post("/banner", h.HandleCreateBanner)
+	r.Post("/banner/batch", h.HandleBatchBanners)
+	r.Get("/banner/export", h.HandleExportBanners)
+	r.Post("/banner/import", h.HandleImportBanners)
+	r.Get("/banner/{id}", h.HandleGetBannerByID)
 	r.Patch("/banner/{id}", h.HandleUpdateBanner)
 	r.Delete("/banner/{id}", h.HandleDeleteBanner)
+	r.Post("/banner/{id}/restore", h.HandleRestoreBanner)
+}
+
+// etag returns the entity tag for the banner version.
+func etag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// versionFromIfMatch returns the banner version from the required If-Match header.
+// Zero version is returned for the "*" value, which matches any version.
+func versionFromIfMatch(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		return 0, errIfMatchRequired
+	}
+	if value == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(value, "W/"), `"`))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("versionFromIfMatch: incorrect entity tag %s", value)
+	}
+
+	return version, nil
+}
+
+// writeVersionError writes the response for the incorrect If-Match header.
+func (h *BannerHandler) writeVersionError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errIfMatchRequired) {
+		utils.WriteError(w, r, http.StatusPreconditionRequired, utils.CodePreconditionRequired, "If-Match header with banner version is required")
+		return
+	}
+
+	utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidHeader, "incorrect If-Match header")
+}
+
+// writeVersionConflict writes the response with the current banner version,
+// when the banner has been changed since the requested version.
+func (h *BannerHandler) writeVersionConflict(w http.ResponseWriter, r *http.Request, id int) {
+	current, err := h.Service.Get(r.Context(), id)
+	if err != nil {
+		logger.Log.Error("writeVersionConflict: get current banner failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.Header().Set("ETag", etag(current.Version))
+	utils.WriteErrorResponse(w, r, http.StatusPreconditionFailed, &utils.ErrorResponse{
+		Code:    utils.CodeVersionConflict,
+		Message: "banner has been changed since the requested version",
+		Version: current.Version,
+	})
+}
+
+// decodeBody reads the request body limited by maxBodySize and unmarshals it into v.
+// Mismatched JSON types of the fields are returned as validation error.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	var buf bytes.Buffer
+
+	defer r.Body.Close()
+	_, err := buf.ReadFrom(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		return fmt.Errorf("decodeBody: read request body failed %w", err)
+	}
+
+	err = json.Unmarshal(buf.Bytes(), v)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			verr := &errs.ValidationError{}
+			verr.Add(typeErr.Field, errs.ValidationInvalidType, fmt.Sprintf("must be %s", typeName(typeErr.Type.Kind())))
+			return fmt.Errorf("decodeBody: request unmarshal failed %w", verr)
+		}
+		return fmt.Errorf("decodeBody: request unmarshal failed %w", err)
+	}
+
+	return nil
+}
+
+// typeName returns the JSON type name for the kind of the Go type.
+func typeName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int64:
+		return "an integer"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// writeBodyError writes the response for the request body, which could not be decoded.
+func (h *BannerHandler) writeBodyError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		utils.WriteError(w, r, http.StatusRequestEntityTooLarge, utils.CodeBodyTooLarge,
+			fmt.Sprintf("request body must be at most %d bytes", maxErr.Limit))
+		return
+	}
+
+	var verr *errs.ValidationError
+	if errors.As(err, &verr) {
+		utils.WriteValidationError(w, r, verr)
+		return
+	}
+
+	utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "request body must be a JSON object")
 }
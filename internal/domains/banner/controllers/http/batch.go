@@ -0,0 +1,161 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"go.uber.org/zap"
+)
+
+// maxBatchSize is the maximum number of the banners in one batch request.
+const maxBatchSize = 100
+
+// batchRequest contains the banners to create or update.
+type batchRequest struct {
+	Banners []*banner.BatchItem `json:"banners"`
+}
+
+// batchResponse contains the results of the batch items
+// and the error code, if the batch is not applied.
+type batchResponse struct {
+	Code    string               `json:"code,omitempty"`
+	Message string               `json:"error,omitempty"`
+	Applied bool                 `json:"applied"`
+	Results []*batchItemResponse `json:"results"`
+}
+
+// batchItemResponse contains the result of the batch item.
+type batchItemResponse struct {
+	Index    int                  `json:"index"`
+	Status   string               `json:"status"`
+	BannerID int                  `json:"banner_id,omitempty"`
+	Version  int                  `json:"version,omitempty"`
+	Error    *utils.ErrorResponse `json:"error,omitempty"`
+}
+
+// HandleBatchBanners handles request to create and update the banners in one transaction.
+func (h *BannerHandler) HandleBatchBanners(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req batchRequest
+
+	w.Header().Set("Content-Type", "application/json")
+	err := decodeBody(w, r, &req)
+	if err != nil {
+		logger.Log.Error("HandleBatchBanners: decode request body failed",
+			zap.Error(err))
+
+		h.writeBodyError(w, r, err)
+		return
+	}
+
+	verr := &errs.ValidationError{}
+	switch {
+	case len(req.Banners) == 0:
+		verr.Add("banners", errs.ValidationRequired, "must contain at least one banner")
+	case len(req.Banners) > maxBatchSize:
+		verr.Add("banners", errs.ValidationTooMany, fmt.Sprintf("must contain at most %d banners", maxBatchSize))
+	}
+	for i, item := range req.Banners {
+		if item == nil {
+			verr.Add(fmt.Sprintf("banners[%d]", i), errs.ValidationRequired, "must be an object")
+		}
+	}
+	if verr.Err() != nil {
+		logger.Log.Error("HandleBatchBanners: incorrect batch",
+			zap.Error(verr))
+
+		utils.WriteValidationError(w, r, verr)
+		return
+	}
+
+	results, err := h.Service.Batch(ctx, req.Banners)
+	if err != nil && !errors.Is(err, errs.ErrBatchNotApplied) {
+		logger.Log.Error("HandleBatchBanners: apply batch failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	resp := &batchResponse{
+		Applied: err == nil,
+		Results: make([]*batchItemResponse, 0, len(results)),
+	}
+	for _, res := range results {
+		item := &batchItemResponse{
+			Index:  res.Index,
+			Status: res.Status,
+		}
+		if res.Banner != nil {
+			item.BannerID = res.Banner.ID
+			item.Version = res.Banner.Version
+		}
+		if res.Err != nil {
+			item.Error = batchItemError(res.Err)
+		}
+		resp.Results = append(resp.Results, item)
+	}
+
+	status := http.StatusOK
+	if !resp.Applied {
+		logger.Log.Error("HandleBatchBanners: batch not applied",
+			zap.Error(err))
+
+		status = http.StatusUnprocessableEntity
+		resp.Code = utils.CodeBatchNotApplied
+		resp.Message = "batch is not applied because of the failed items"
+	}
+
+	respJSON, err := json.Marshal(resp)
+	if err != nil {
+		logger.Log.Error("HandleBatchBanners: marshal batch results failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(status)
+	w.Write(respJSON)
+}
+
+// batchItemError returns the error response of the failed batch item.
+func batchItemError(err error) *utils.ErrorResponse {
+	var verr *errs.ValidationError
+	var rerr *errs.ReferenceError
+	switch {
+	case errors.As(err, &verr):
+		return &utils.ErrorResponse{
+			Code:    utils.CodeValidationFailed,
+			Message: "banner validation failed",
+			Details: verr.Fields,
+		}
+	case errors.As(err, &rerr):
+		return &utils.ErrorResponse{
+			Code:    utils.CodeUnknownReference,
+			Message: "banner references unknown entities",
+			Details: rerr.Fields,
+		}
+	case errors.Is(err, errs.ErrBannerNotFound):
+		return &utils.ErrorResponse{
+			Code:    utils.CodeNotFound,
+			Message: "banner not found",
+		}
+	case errors.Is(err, errs.ErrBannerVersionConflict):
+		return &utils.ErrorResponse{
+			Code:    utils.CodeVersionConflict,
+			Message: "banner has been changed since the requested version",
+		}
+	default:
+		return &utils.ErrorResponse{
+			Code:    utils.CodeInternal,
+			Message: "internal server error",
+		}
+	}
+}
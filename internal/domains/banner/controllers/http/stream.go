@@ -0,0 +1,248 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"go.uber.org/zap"
+)
+
+// heartbeatInterval is the interval of the comments, which keep the idle stream alive.
+const heartbeatInterval = 15 * time.Second
+
+// streamRetry is the reconnection time in milliseconds suggested to the stream clients.
+const streamRetry = 3000
+
+// eventResponse contains the banner content for the feature and tag pair,
+// the null content means there is no banner for the user.
+type eventResponse struct {
+	FeatureID int             `json:"feature_id"`
+	TagID     int             `json:"tag_id"`
+	BannerID  int             `json:"banner_id,omitempty"`
+	Content   *banner.Content `json:"content"`
+}
+
+// HandleStreamUserBanners handles user's request to subscribe to the banner changes
+// of the features and tags. The actual banners are sent as Server-Sent Events first,
+// then the banners are sent on every change. The banners are resolved for the user
+// the same way as the single user banner, including variants, default banners and caps. The stream is resumed after
+// the Last-Event-ID header event, if the later events are kept.
+func (h *BannerHandler) HandleStreamUserBanners(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var featureIDs, tagIDs []int
+
+	params := make(map[string]string, len(h.Config.TemplateVars))
+	queries := r.URL.Query()
+	for val := range queries {
+		// The configured template variables are set by the var.<name> queries
+		if name, ok := strings.CutPrefix(val, banner.TemplateVarQueryPrefix); ok && slices.Contains(h.Config.TemplateVars, name) {
+			if len(queries[val]) != 1 {
+				logger.Log.Error("HandleStreamUserBanners: incorrect queries number",
+					zap.String("query_name", val),
+					zap.Int("query_number", len(queries[val])))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
+				return
+			}
+
+			params[name] = queries[val][0]
+			continue
+		}
+
+		switch val {
+		case "feature_id", "tag_id":
+			// The duplicated IDs are dropped while parsing, so the raw values are limited before
+			if len(queries[val]) > maxUserBatchPairs {
+				logger.Log.Error("HandleStreamUserBanners: too many query values",
+					zap.String("query_name", val),
+					zap.Int("query_number", len(queries[val])))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery,
+					fmt.Sprintf("at most %d %s values might be requested", maxUserBatchPairs, val))
+				return
+			}
+
+			ids, err := idsFromQuery(queries[val])
+			if err != nil {
+				logger.Log.Error("HandleStreamUserBanners: unexpected query value",
+					zap.String("query_name", val),
+					zap.Error(err))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "unexpected query value")
+				return
+			}
+
+			if val == "feature_id" {
+				featureIDs = ids
+			} else {
+				tagIDs = ids
+			}
+
+		default:
+			logger.Log.Error("HandleStreamUserBanners: incorrect query",
+				zap.String("query", val))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query in request url")
+			return
+		}
+	}
+
+	if len(featureIDs) == 0 || len(tagIDs) == 0 {
+		logger.Log.Error("HandleStreamUserBanners: required queries not set",
+			zap.Int("feature_id", len(featureIDs)),
+			zap.Int("tag_id", len(tagIDs)))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "required queries not set")
+		return
+	}
+
+	// The IDs are unique, so the pairs are checked before they are built
+	if len(featureIDs)*len(tagIDs) > maxUserBatchPairs {
+		logger.Log.Error("HandleStreamUserBanners: too many banners requested",
+			zap.Int("feature_id", len(featureIDs)),
+			zap.Int("tag_id", len(tagIDs)))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery,
+			fmt.Sprintf("at most %d feature and tag pairs might be requested", maxUserBatchPairs))
+		return
+	}
+	pairs := userPairs(featureIDs, tagIDs)
+
+	var lastEventID int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			logger.Log.Error("HandleStreamUserBanners: incorrect Last-Event-ID header",
+				zap.String("last_event_id", v))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidHeader, "Last-Event-ID header must be a non-negative integer")
+			return
+		}
+		lastEventID = id
+	}
+
+	sub, events, err := h.Service.Subscribe(ctx, pairs, lastEventID)
+	if err != nil {
+		logger.Log.Error("HandleStreamUserBanners: subscribe to banner changes failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// The banners are resolved, localized and rendered the same way as the single user banner
+	accepted := banner.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	h.templateParams(r, params)
+
+	rc := http.NewResponseController(w)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+
+	for _, e := range events {
+		err = h.writeEvent(ctx, w, sub, e, accepted, params)
+		if err != nil {
+			logger.Log.Error("HandleStreamUserBanners: write event failed",
+				zap.Error(err))
+			return
+		}
+	}
+	rc.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case e, ok := <-sub.Events():
+			// The subscription is dropped or the server is shutting down,
+			// so the client should reconnect and resume the stream
+			if !ok {
+				return
+			}
+			err = h.writeEvent(ctx, w, sub, e, accepted, params)
+			if err != nil {
+				logger.Log.Error("HandleStreamUserBanners: write event failed",
+					zap.Error(err))
+				return
+			}
+		}
+
+		err = rc.Flush()
+		if err != nil {
+			logger.Log.Error("HandleStreamUserBanners: flush events failed",
+				zap.Error(err))
+			return
+		}
+	}
+}
+
+// writeEvent resolves the banner of the event pair for the subscriber and writes it,
+// if the banner content has changed for the subscriber. The banner content already
+// sent to the subscriber is not counted as shown again.
+func (h *BannerHandler) writeEvent(ctx context.Context, w http.ResponseWriter, sub *banner.Subscription, e *banner.Event, accepted []string, params map[string]string) error {
+	sent := func(b *banner.Banner) bool {
+		return sub.Sent(&banner.Event{Pair: e.Pair, BannerID: b.ID, Version: b.Version})
+	}
+
+	visible := banner.Event{ID: e.ID, Pair: e.Pair}
+	var content *banner.Content
+
+	tagIDs := []int{e.Pair.TagID}
+	b, err := h.Service.Unload(ctx, e.Pair.FeatureID, tagIDs, false, sent)
+	switch {
+	case err == nil:
+		localized, locale, err := h.Service.Localize(ctx, b, accepted)
+		if err != nil {
+			return fmt.Errorf("writeEvent: localize banner content failed %w", err)
+		}
+		content, _ = h.Service.Render(ctx, b, localized, tagIDs, locale, params)
+		visible.BannerID = b.ID
+		visible.Version = b.Version
+	case errors.Is(err, errs.ErrBannerNotFound), errors.Is(err, errs.ErrBannerNotAllowed), errors.Is(err, errs.ErrBannerCapReached):
+	default:
+		return fmt.Errorf("writeEvent: get user banner failed %w", err)
+	}
+
+	if !sub.Changed(&visible) {
+		return nil
+	}
+
+	data, err := json.Marshal(&eventResponse{
+		FeatureID: e.Pair.FeatureID,
+		TagID:     e.Pair.TagID,
+		BannerID:  visible.BannerID,
+		Content:   content,
+	})
+	if err != nil {
+		return fmt.Errorf("writeEvent: marshal event failed %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: banner\ndata: %s\n\n", e.ID, data)
+	if err != nil {
+		return fmt.Errorf("writeEvent: write event failed %w", err)
+	}
+
+	return nil
+}
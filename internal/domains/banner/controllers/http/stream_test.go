@@ -0,0 +1,217 @@
+package http_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamEvent contains the received Server-Sent Event.
+type streamEvent struct {
+	id   string
+	data map[string]any
+}
+
+// openStream subscribes to the banner changes as user with the additional headers
+// and returns the received events.
+func openStream(t *testing.T, url string, headers map[string]string) (<-chan streamEvent, func()) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("token", "user_token")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan streamEvent, 16)
+	go func() {
+		defer close(events)
+
+		var e streamEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				e.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.data)
+			case line == "" && e.data != nil:
+				events <- e
+				e = streamEvent{}
+			}
+		}
+	}()
+
+	return events, func() { resp.Body.Close() }
+}
+
+// nextEvent returns the next received event.
+func nextEvent(t *testing.T, events <-chan streamEvent) streamEvent {
+	t.Helper()
+
+	select {
+	case e, ok := <-events:
+		require.True(t, ok, "stream closed")
+		return e
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "event not received")
+		return streamEvent{}
+	}
+}
+
+func TestBannerHandler_Stream(t *testing.T) {
+	h, stored := newAdminRoute(t)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	url := srv.URL + "/user_banner/stream?feature_id=1&tag_id=1&tag_id=3"
+	events, closeStream := openStream(t, url, nil)
+
+	// Current banners are sent first
+	first := nextEvent(t, events)
+	assert.Equal(t, map[string]any{
+		"feature_id": float64(1),
+		"tag_id":     float64(1),
+		"banner_id":  float64(stored.ID),
+		"content":    map[string]any{"title": "some_title"},
+	}, first.data)
+	assert.Equal(t, map[string]any{
+		"feature_id": float64(1),
+		"tag_id":     float64(3),
+		"content":    nil,
+	}, nextEvent(t, events).data)
+
+	resp, body := serve(t, h, http.MethodPatch, "/banner/1", `{"content":{"title":"new_title"}}`, map[string]string{"If-Match": `"1"`})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	updated := nextEvent(t, events)
+	assert.Equal(t, map[string]any{"title": "new_title"}, updated.data["content"])
+	assert.Greater(t, updated.id, first.id)
+
+	// Inactive banner is removed for users
+	resp, body = serve(t, h, http.MethodPatch, "/banner/1", `{"is_active":false}`, map[string]string{"If-Match": `"2"`})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	deactivated := nextEvent(t, events)
+	assert.Equal(t, float64(1), deactivated.data["tag_id"])
+	assert.Nil(t, deactivated.data["content"])
+
+	closeStream()
+
+	// Changes made while disconnected are sent on resume
+	resp, body = serve(t, h, http.MethodPost, "/banner", `{"tag_ids":[3],"feature_id":1,"content":{"title":"other_title"},"is_active":true}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+
+	events, closeStream = openStream(t, url, map[string]string{"Last-Event-ID": deactivated.id})
+	defer closeStream()
+
+	resumed := nextEvent(t, events)
+	assert.Equal(t, float64(3), resumed.data["tag_id"])
+	assert.Equal(t, map[string]any{"title": "other_title"}, resumed.data["content"])
+
+	select {
+	case e := <-events:
+		assert.Fail(t, "unexpected event", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Unknown event ID leads to the current banners
+	events, closeOther := openStream(t, url, map[string]string{"Last-Event-ID": "1"})
+	defer closeOther()
+
+	assert.Nil(t, nextEvent(t, events).data["content"])
+	assert.Equal(t, map[string]any{"title": "other_title"}, nextEvent(t, events).data["content"])
+}
+
+func TestBannerHandler_StreamResolve(t *testing.T) {
+	h, _ := newAdminRoute(t)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	for _, body := range []string{
+		`{"tag_ids": [3], "feature_id": 2, "content": {"title": "a"}, "is_active": true, "weight": 30}`,
+		`{"tag_ids": [3], "feature_id": 2, "content": {"title": "b"}, "is_active": true, "weight": 70}`,
+		`{"tag_ids": [9], "feature_id": 3, "content": {"title": "default"}, "is_active": true, "is_default": true}`,
+	} {
+		resp, gotBody := serve(t, h, http.MethodPost, "/banner", body, nil)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, gotBody)
+	}
+
+	for i := 0; i < 5; i++ {
+		user := map[string]string{"token": "user_token", "X-User-ID": fmt.Sprintf("user-%d", i)}
+
+		resp, single := serve(t, h, http.MethodGet, "/user_banner?feature_id=2&tag_id=3", "", user)
+		require.Equal(t, http.StatusOK, resp.StatusCode, single)
+		var want map[string]any
+		require.NoError(t, json.Unmarshal([]byte(single), &want))
+
+		// The user gets the same variant as from the single banner request
+		// and the default banner of the feature without banners for the tag
+		events, closeStream := openStream(t, srv.URL+"/user_banner/stream?feature_id=2&feature_id=3&tag_id=3", user)
+		got := make(map[float64]any)
+		for j := 0; j < 2; j++ {
+			e := nextEvent(t, events)
+			got[e.data["feature_id"].(float64)] = e.data["content"]
+		}
+		closeStream()
+
+		assert.Equal(t, map[float64]any{
+			2: want,
+			3: map[string]any{"title": "default"},
+		}, got)
+	}
+}
+
+func TestBannerHandler_StreamErrors(t *testing.T) {
+	h, _ := newAdminRoute(t)
+
+	tests := []struct {
+		name     string
+		url      string
+		headers  map[string]string
+		wantCode int
+	}{
+		{
+			name:     "tag not set",
+			url:      "/user_banner/stream?feature_id=1",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown query",
+			url:      "/user_banner/stream?feature_id=1&tag_id=1&use_last_revision=true",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "too many duplicated ids",
+			url:      "/user_banner/stream?tag_id=1" + strings.Repeat("&feature_id=1", 101),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "incorrect last event ID",
+			url:      "/user_banner/stream?feature_id=1&tag_id=1",
+			headers:  map[string]string{"Last-Event-ID": "abc"},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := serve(t, h, http.MethodGet, tt.url, "", tt.headers)
+			assert.Equal(t, tt.wantCode, resp.StatusCode, body)
+		})
+	}
+}
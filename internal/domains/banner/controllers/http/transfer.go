@@ -0,0 +1,315 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/banner/transfer"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"go.uber.org/zap"
+)
+
+// maxImportSize is the maximum size of the imported file in bytes.
+const maxImportSize = 32 << 20
+
+// exportFlushSize is the number of the exported banners sent to the client at once.
+const exportFlushSize = 100
+
+// importResponse contains the results of the imported banners
+// and the error code, if the import is not applied.
+type importResponse struct {
+	Code    string                `json:"code,omitempty"`
+	Message string                `json:"error,omitempty"`
+	Mode    string                `json:"mode"`
+	DryRun  bool                  `json:"dry_run"`
+	Applied bool                  `json:"applied"`
+	Summary map[string]int        `json:"summary"`
+	Results []*importItemResponse `json:"results"`
+}
+
+// importItemResponse contains the result of the imported banner.
+type importItemResponse struct {
+	batchItemResponse
+	Conflicts []*conflictResponse `json:"conflicts,omitempty"`
+}
+
+// conflictResponse contains the feature and tag pair, for which the imported banner
+// is ambiguous with the stored banner or with the other imported banner.
+type conflictResponse struct {
+	FeatureID int  `json:"feature_id"`
+	TagID     int  `json:"tag_id"`
+	BannerID  *int `json:"banner_id,omitempty"`
+	Index     *int `json:"index,omitempty"`
+}
+
+// HandleExportBanners handles admin's request to stream the banners by filter as NDJSON or CSV.
+func (h *BannerHandler) HandleExportBanners(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req requestQuery
+	format := transfer.FormatNDJSON
+	want := map[string]struct{}{
+		"feature_id": {},
+		"tag_id":     {},
+		"limit":      {},
+		"offset":     {},
+		"deleted":    {},
+		"format":     {},
+	}
+
+	queries := r.URL.Query()
+	for val := range queries {
+		_, ok := want[val]
+		if !ok {
+			logger.Log.Error("HandleExportBanners: incorrect query",
+				zap.String("query", val))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query in request url")
+			return
+		}
+
+		if len(queries[val]) != 1 {
+			logger.Log.Error("HandleExportBanners: incorrect queries number",
+				zap.String("query_name", val),
+				zap.Int("query_number", len(queries[val])))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
+			return
+		}
+
+		var err error
+		switch val {
+		case "format":
+			format = queries[val][0]
+			err = transfer.CheckFormat(format)
+		case "deleted":
+			req.deleted, err = strconv.ParseBool(queries[val][0])
+		case "feature_id":
+			req.featureID, err = strconv.Atoi(queries[val][0])
+		case "tag_id":
+			req.tagID, err = strconv.Atoi(queries[val][0])
+		case "limit":
+			req.limit, err = strconv.Atoi(queries[val][0])
+		case "offset":
+			req.offset, err = strconv.Atoi(queries[val][0])
+		}
+		if err != nil || req.featureID < 0 || req.tagID < 0 || req.limit < 0 || req.offset < 0 {
+			logger.Log.Error("HandleExportBanners: unexpected query value",
+				zap.String("query_name", val),
+				zap.String("query_value", queries[val][0]))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "unexpected query value")
+			return
+		}
+	}
+
+	enc, err := transfer.NewEncoder(w, format)
+	if err != nil {
+		logger.Log.Error("HandleExportBanners: create encoder failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	// The response is streamed, so the errors after the first banner are only logged
+	w.Header().Set("Content-Type", transfer.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="banners.%s"`, format))
+
+	rc := http.NewResponseController(w)
+	count := 0
+	err = h.Service.Export(ctx, req.featureID, req.tagID, req.limit, req.offset, req.deleted, func(b *banner.Banner) error {
+		err := enc.Encode(b)
+		if err != nil {
+			return fmt.Errorf("encode banner failed %w", err)
+		}
+
+		count++
+		if count%exportFlushSize == 0 {
+			err = enc.Flush()
+			if err != nil {
+				return fmt.Errorf("flush banners failed %w", err)
+			}
+			rc.Flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.Log.Error("HandleExportBanners: export banners failed",
+			zap.Int("exported", count),
+			zap.Error(err))
+
+		if count == 0 {
+			utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		}
+		return
+	}
+
+	err = enc.Flush()
+	if err != nil {
+		logger.Log.Error("HandleExportBanners: flush banners failed",
+			zap.Error(err))
+	}
+}
+
+// HandleImportBanners handles admin's request to import the banners from NDJSON or CSV file.
+func (h *BannerHandler) HandleImportBanners(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	format := transfer.FormatNDJSON
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == transfer.ContentType(transfer.FormatCSV) {
+		format = transfer.FormatCSV
+	}
+	mode := banner.ImportCreate
+	dryRun := false
+	want := map[string]struct{}{
+		"format":  {},
+		"mode":    {},
+		"dry_run": {},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	queries := r.URL.Query()
+	for val := range queries {
+		_, ok := want[val]
+		if !ok {
+			logger.Log.Error("HandleImportBanners: incorrect query",
+				zap.String("query", val))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query in request url")
+			return
+		}
+
+		if len(queries[val]) != 1 {
+			logger.Log.Error("HandleImportBanners: incorrect queries number",
+				zap.String("query_name", val),
+				zap.Int("query_number", len(queries[val])))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
+			return
+		}
+
+		var err error
+		switch val {
+		case "format":
+			format = queries[val][0]
+			err = transfer.CheckFormat(format)
+		case "mode":
+			mode = queries[val][0]
+			if mode != banner.ImportCreate && mode != banner.ImportUpsert {
+				err = fmt.Errorf("unknown import mode %s", mode)
+			}
+		case "dry_run":
+			dryRun, err = strconv.ParseBool(queries[val][0])
+		}
+		if err != nil {
+			logger.Log.Error("HandleImportBanners: unexpected query value",
+				zap.String("query_name", val),
+				zap.String("query_value", queries[val][0]))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "unexpected query value")
+			return
+		}
+	}
+
+	defer r.Body.Close()
+	banners, err := transfer.Decode(http.MaxBytesReader(w, r.Body, maxImportSize), format)
+	if err != nil {
+		logger.Log.Error("HandleImportBanners: decode imported banners failed",
+			zap.Error(err))
+
+		h.writeBodyError(w, r, err)
+		return
+	}
+
+	results, err := h.Service.Import(ctx, banners, mode, dryRun)
+	if err != nil && !errors.Is(err, errs.ErrBatchNotApplied) {
+		logger.Log.Error("HandleImportBanners: import banners failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	resp := importResults(results)
+	resp.Mode = mode
+	resp.DryRun = dryRun
+	resp.Applied = err == nil && !dryRun
+
+	status := http.StatusOK
+	if err != nil {
+		logger.Log.Error("HandleImportBanners: import not applied",
+			zap.Error(err))
+
+		status = http.StatusUnprocessableEntity
+		resp.Code = utils.CodeBatchNotApplied
+		resp.Message = "import is not applied because of the failed banners"
+	}
+
+	respJSON, err := json.Marshal(resp)
+	if err != nil {
+		logger.Log.Error("HandleImportBanners: marshal import results failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(status)
+	w.Write(respJSON)
+}
+
+// importResults returns the response with the results of the imported banners.
+func importResults(results []*banner.ImportResult) *importResponse {
+	resp := &importResponse{
+		Summary: map[string]int{
+			banner.BatchCreated:   0,
+			banner.BatchUpdated:   0,
+			banner.ImportConflict: 0,
+			banner.BatchFailed:    0,
+		},
+		Results: make([]*importItemResponse, 0, len(results)),
+	}
+
+	for _, res := range results {
+		resp.Summary[res.Status]++
+
+		item := &importItemResponse{
+			batchItemResponse: batchItemResponse{
+				Index:  res.Index,
+				Status: res.Status,
+			},
+		}
+		if res.Banner != nil {
+			item.BannerID = res.Banner.ID
+			item.Version = res.Banner.Version
+		}
+		if res.Err != nil {
+			item.Error = batchItemError(res.Err)
+		}
+		for _, c := range res.Conflicts {
+			conflict := &conflictResponse{
+				FeatureID: c.FeatureID,
+				TagID:     c.TagID,
+			}
+			if bannerID, index := c.BannerID, c.Index; bannerID != 0 {
+				conflict.BannerID = &bannerID
+			} else {
+				conflict.Index = &index
+			}
+			item.Conflicts = append(item.Conflicts, conflict)
+		}
+		resp.Results = append(resp.Results, item)
+	}
+
+	return resp
+}
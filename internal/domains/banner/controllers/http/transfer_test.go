@@ -0,0 +1,238 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// importReport contains the part of the import response checked by tests.
+type importReport struct {
+	Code    string         `json:"code"`
+	Applied bool           `json:"applied"`
+	Summary map[string]int `json:"summary"`
+	Results []struct {
+		Status    string `json:"status"`
+		BannerID  int    `json:"banner_id"`
+		Conflicts []struct {
+			FeatureID int  `json:"feature_id"`
+			TagID     int  `json:"tag_id"`
+			BannerID  *int `json:"banner_id"`
+			Index     *int `json:"index"`
+		} `json:"conflicts"`
+	} `json:"results"`
+}
+
+func TestBannerHandler_Export(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	_, body := serve(t, mh, http.MethodPost, "http://localhost:8080/banner",
+		`{"tag_ids": [3], "feature_id": 2, "content": {"title": "other_title"}, "is_active": false}`, nil)
+	require.Contains(t, body, "banner_id")
+
+	resp, body := serve(t, mh, http.MethodGet, "http://localhost:8080/banner/export", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	assert.Len(t, strings.Split(strings.TrimSpace(body), "\n"), 2)
+
+	resp, body = serve(t, mh, http.MethodGet, "http://localhost:8080/banner/export?format=csv&feature_id=2", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "banner_id,feature_id,tag_ids"))
+	assert.True(t, strings.HasPrefix(lines[1], "2,2,3,"))
+
+	resp, body = serve(t, mh, http.MethodGet, "http://localhost:8080/banner/export?format=xml", "", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+}
+
+func TestBannerHandler_Import(t *testing.T) {
+	file := strings.Join([]string{
+		`{"banner_id": 1, "tag_ids": [1, 2], "feature_id": 1, "content": {"title": "new_title"}, "is_active": true}`,
+		`{"banner_id": 7, "tag_ids": [5], "feature_id": 1, "content": {"title": "some_title"}, "is_active": true}`,
+		`{"tag_ids": [5, 6], "feature_id": 1, "content": {"title": "some_title"}, "is_active": true}`,
+	}, "\n")
+
+	tests := []struct {
+		name        string
+		query       string
+		body        string
+		wantCode    int
+		wantStatus  []string
+		wantSummary map[string]int
+		wantTotal   int
+	}{
+		{
+			name:       "create only",
+			query:      "mode=create",
+			body:       file,
+			wantCode:   http.StatusOK,
+			wantStatus: []string{"conflict", "created", "conflict"},
+			wantTotal:  2,
+		},
+		{
+			name:       "upsert",
+			query:      "mode=upsert",
+			body:       file,
+			wantCode:   http.StatusOK,
+			wantStatus: []string{"updated", "created", "conflict"},
+			wantTotal:  2,
+		},
+		{
+			name:       "dry run",
+			query:      "mode=upsert&dry_run=true",
+			body:       file,
+			wantCode:   http.StatusOK,
+			wantStatus: []string{"updated", "created", "conflict"},
+			wantTotal:  1,
+		},
+		{
+			name:       "invalid banner",
+			query:      "mode=create",
+			body:       file + "\n" + `{"tag_ids": [], "feature_id": 2, "content": {}}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantStatus: []string{"conflict", "skipped", "conflict", "failed"},
+			wantTotal:  1,
+		},
+		{
+			name:      "incorrect file",
+			query:     "mode=create",
+			body:      "not a json",
+			wantCode:  http.StatusBadRequest,
+			wantTotal: 1,
+		},
+		{
+			name:      "unknown mode",
+			query:     "mode=replace",
+			body:      file,
+			wantCode:  http.StatusBadRequest,
+			wantTotal: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mh, _ := newAdminRoute(t)
+
+			resp, body := serve(t, mh, http.MethodPost, "http://localhost:8080/banner/import?"+tt.query, tt.body, nil)
+			require.Equal(t, tt.wantCode, resp.StatusCode, body)
+
+			if tt.wantStatus != nil {
+				var got importReport
+				require.NoError(t, json.Unmarshal([]byte(body), &got))
+				require.Len(t, got.Results, len(tt.wantStatus))
+				for i, res := range got.Results {
+					assert.Equal(t, tt.wantStatus[i], res.Status, "banner %d", i)
+				}
+			}
+
+			_, body = serve(t, mh, http.MethodGet, "http://localhost:8080/banner", "", nil)
+			var list []json.RawMessage
+			require.NoError(t, json.Unmarshal([]byte(body), &list))
+			assert.Len(t, list, tt.wantTotal)
+		})
+	}
+}
+
+func TestBannerHandler_ImportConflicts(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	body := `{"tag_ids": [2, 3], "feature_id": 1, "content": {"title": "some_title"}, "is_active": true}
+{"tag_ids": [3], "feature_id": 1, "content": {"title": "some_title"}, "is_active": true}`
+
+	resp, gotBody := serve(t, mh, http.MethodPost, "http://localhost:8080/banner/import?dry_run=true", body, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+	var got importReport
+	require.NoError(t, json.Unmarshal([]byte(gotBody), &got))
+	assert.False(t, got.Applied)
+	assert.Equal(t, 1, got.Summary["conflict"])
+	assert.Equal(t, 1, got.Summary["created"])
+
+	require.Len(t, got.Results[0].Conflicts, 1)
+	conflict := got.Results[0].Conflicts[0]
+	assert.Equal(t, 1, conflict.FeatureID)
+	assert.Equal(t, 2, conflict.TagID)
+	require.NotNil(t, conflict.BannerID)
+	assert.Equal(t, 1, *conflict.BannerID)
+
+	// The second banner shares the pair with the first one, which is not imported itself
+	assert.Equal(t, "created", got.Results[1].Status)
+	assert.Empty(t, got.Results[1].Conflicts)
+}
+
+func TestBannerHandler_ImportConflictsAmbiguous(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	body := strings.Join([]string{
+		`{"tag_ids": [2], "feature_id": 1, "content": {"title": "a"}, "is_active": true, "priority": 5}`,
+		`{"tag_ids": [2], "feature_id": 1, "content": {"title": "b"}, "is_active": false}`,
+		`{"tag_ids": [2], "feature_id": 1, "content": {"title": "c"}, "is_active": true, "weight": 50}`,
+		`{"tag_ids": [7], "feature_id": 4, "content": {"title": "d"}, "is_active": true, "is_default": true}`,
+		`{"tag_ids": [8], "feature_id": 4, "content": {"title": "e"}, "is_active": true, "is_default": true}`,
+	}, "\n")
+
+	resp, gotBody := serve(t, mh, http.MethodPost, "http://localhost:8080/banner/import?dry_run=true", body, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+	var got importReport
+	require.NoError(t, json.Unmarshal([]byte(gotBody), &got))
+	require.Len(t, got.Results, 5)
+
+	// Other priority, inactive and weighted banners share the pair unambiguously
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "created", got.Results[i].Status, "banner %d", i)
+	}
+
+	// The feature has only one default banner
+	assert.Equal(t, "conflict", got.Results[4].Status)
+	require.Len(t, got.Results[4].Conflicts, 1)
+	conflict := got.Results[4].Conflicts[0]
+	assert.Equal(t, 4, conflict.FeatureID)
+	assert.Equal(t, 0, conflict.TagID)
+	require.NotNil(t, conflict.Index)
+	assert.Equal(t, 3, *conflict.Index)
+}
+
+func TestBannerHandler_ExportImport(t *testing.T) {
+	source, _ := newAdminRoute(t)
+	for _, body := range []string{
+		`{"tag_ids": [3], "feature_id": 2, "content": {"title": "a"}, "is_active": true, "weight": 30}`,
+		`{"tag_ids": [3], "feature_id": 2, "content": {"title": "b"}, "is_active": true, "weight": 70}`,
+		`{"tag_ids": [9], "feature_id": 3, "content": {"title": "default"}, "is_active": true, "is_default": true}`,
+	} {
+		resp, gotBody := serve(t, source, http.MethodPost, "http://localhost:8080/banner", body, nil)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, gotBody)
+	}
+
+	resp, exported := serve(t, source, http.MethodGet, "http://localhost:8080/banner/export", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, exported)
+
+	// The exported banners are imported without conflicts
+	target, _ := newAdminRoute(t)
+	resp, gotBody := serve(t, target, http.MethodPost, "http://localhost:8080/banner/import?mode=upsert", exported, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+	var got importReport
+	require.NoError(t, json.Unmarshal([]byte(gotBody), &got))
+	assert.True(t, got.Applied)
+	assert.Equal(t, 0, got.Summary["conflict"])
+	assert.Equal(t, 1, got.Summary["updated"])
+	assert.Equal(t, 3, got.Summary["created"])
+
+	// The imported banners are still the variants and the default banner of the features
+	user := map[string]string{"token": "user_token", "X-User-ID": "user"}
+	resp, gotBody = serve(t, target, http.MethodGet, "http://localhost:8080/user_banner?feature_id=2&tag_id=3", "", user)
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.NotEmpty(t, resp.Header.Get("X-Banner-Variant"))
+	assert.Contains(t, []string{`{"title":"a"}`, `{"title":"b"}`}, gotBody)
+
+	resp, gotBody = serve(t, target, http.MethodGet, "http://localhost:8080/user_banner?feature_id=3&tag_id=3", "", user)
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.Equal(t, "true", resp.Header.Get("X-Banner-Fallback"))
+	assert.JSONEq(t, `{"title": "default"}`, gotBody)
+}
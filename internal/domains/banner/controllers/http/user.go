@@ -3,84 +3,111 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"hash/fnv"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/pavlegich/banners-service/internal/controllers/middlewares"
+	"github.com/pavlegich/banners-service/internal/domains/banner"
 	errs "github.com/pavlegich/banners-service/internal/errors"
 	"github.com/pavlegich/banners-service/internal/infra/logger"
 	"github.com/pavlegich/banners-service/internal/utils"
 	"go.uber.org/zap"
 )
 
-// HandleGetUserBanner handles user's request to get banner by filter.
+// maxUserTags is the maximum number of the user tags in one request.
+const maxUserTags = 20
+
+// HandleGetUserBanner handles user's request to get banner by filter. The tag might be
+// repeated for the user with several tags, so the best banner of all the tags is returned.
+// The banner content is localized for the locale from query, then for the Accept-Language,
+// and its placeholders are rendered with the request variables. The configured template
+// variables are got from the var.<name> queries, then from the X-Banner-Var-<Name> headers.
 func (h *BannerHandler) HandleGetUserBanner(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var req requestQuery
+	var tagIDs []int
 	want := map[string]bool{
 		"feature_id":        false,
 		"tag_id":            false,
 		"use_last_revision": false,
+		"locale":            false,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 
+	params := make(map[string]string, len(h.Config.TemplateVars))
 	queries := r.URL.Query()
 	for val := range queries {
+		// The configured template variables are set by the var.<name> queries
+		if name, ok := strings.CutPrefix(val, banner.TemplateVarQueryPrefix); ok && slices.Contains(h.Config.TemplateVars, name) {
+			if len(queries[val]) != 1 {
+				logger.Log.Error("HandleGetUserBanner: incorrect queries number",
+					zap.String("query_name", val),
+					zap.Int("query_number", len(queries[val])))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
+				return
+			}
+
+			params[name] = queries[val][0]
+			continue
+		}
+
 		_, ok := want[val]
 		if !ok {
 			logger.Log.Error("HandleGetUserBanner: incorrect query",
 				zap.String("query", val))
 
-			w.WriteHeader(http.StatusBadRequest)
-			resp := utils.ParamToJSON("error", "incorrect query in request url")
-			w.Write(resp)
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query in request url")
 			return
 		}
 
-		if len(queries[val]) != 1 {
+		// The tag might be repeated for the user, who has several tags
+		if len(queries[val]) != 1 && (val != "tag_id" || len(queries[val]) > maxUserTags) {
 			logger.Log.Error("HandleGetUserBanner: incorrect queries number",
 				zap.String("query_name", val),
 				zap.Int("query_number", len(queries[val])))
 
-			w.WriteHeader(http.StatusBadRequest)
-			resp := utils.ParamToJSON("error", "incorrect query number in request url")
-			w.Write(resp)
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
 			return
 		}
 
 		switch val {
 		case "feature_id", "tag_id":
-			current, err := strconv.Atoi(queries[val][0])
-			if err != nil {
-				logger.Log.Error("HandleGetUserBanner: convert query to integer failed",
-					zap.String("query_name", val),
-					zap.String("query_value", queries[val][0]))
+			for _, v := range queries[val] {
+				current, err := strconv.Atoi(v)
+				if err != nil {
+					logger.Log.Error("HandleGetUserBanner: convert query to integer failed",
+						zap.String("query_name", val),
+						zap.String("query_value", v))
 
-				w.WriteHeader(http.StatusBadRequest)
-				resp := utils.ParamToJSON("error", "convert query to integer failed")
-				w.Write(resp)
-				return
-			}
+					utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "convert query to integer failed")
+					return
+				}
 
-			if current < 1 {
-				logger.Log.Error("HandleGetUserBanner: unexpected query value",
-					zap.String("query_name", val),
-					zap.String("query_value", queries[val][0]))
+				if current < 1 {
+					logger.Log.Error("HandleGetUserBanner: unexpected query value",
+						zap.String("query_name", val),
+						zap.String("query_value", v))
 
-				w.WriteHeader(http.StatusBadRequest)
-				resp := utils.ParamToJSON("error", "unexpected query value")
-				w.Write(resp)
-				return
-			}
+					utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "unexpected query value")
+					return
+				}
 
-			if val == "feature_id" {
-				want["feature_id"] = true
-				req.featureID = current
-			}
-			if val == "tag_id" {
-				want["tag_id"] = true
-				req.tagID = current
+				if val == "feature_id" {
+					want["feature_id"] = true
+					req.featureID = current
+				}
+				if val == "tag_id" && !slices.Contains(tagIDs, current) {
+					want["tag_id"] = true
+					tagIDs = append(tagIDs, current)
+				}
 			}
 
 		case "use_last_revision":
@@ -90,13 +117,22 @@ func (h *BannerHandler) HandleGetUserBanner(w http.ResponseWriter, r *http.Reque
 					zap.String("query_name", val),
 					zap.String("query_value", queries[val][0]))
 
-				w.WriteHeader(http.StatusBadRequest)
-				resp := utils.ParamToJSON("error", "convert query to bool failed")
-				w.Write(resp)
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "convert query to bool failed")
 				return
 			}
 
 			req.lastRevision = current
+
+		case "locale":
+			if !banner.ValidLocale(queries[val][0]) {
+				logger.Log.Error("HandleGetUserBanner: unexpected locale",
+					zap.String("query_value", queries[val][0]))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "locale must be a language tag")
+				return
+			}
+
+			req.locale = queries[val][0]
 		}
 	}
 
@@ -105,44 +141,433 @@ func (h *BannerHandler) HandleGetUserBanner(w http.ResponseWriter, r *http.Reque
 			zap.Bool("feature_id", want["feature_id"]),
 			zap.Bool("tag_id", want["tag_id"]))
 
-		w.WriteHeader(http.StatusBadRequest)
-		resp := utils.ParamToJSON("error", "required queries not set")
-		w.Write(resp)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "required queries not set")
 		return
 	}
 
-	bannerContent, err := h.Service.Unload(ctx, req.featureID, req.tagID, req.lastRevision)
+	accepted := banner.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if req.locale != "" {
+		accepted = append([]string{req.locale}, accepted...)
+	}
+
+	h.templateParams(r, params)
+
+	// The conditional request is checked before the banner is shown, so the
+	// revalidated content is neither counted as the impression nor capped
+	revalidated := func(b *banner.Banner) bool {
+		if r.Header.Get("If-None-Match") == "" && r.Header.Get("If-Modified-Since") == "" {
+			return false
+		}
+		content, locale, err := h.Service.Localize(ctx, b, accepted)
+		if err != nil {
+			return false
+		}
+		_, vars := h.Service.Render(ctx, b, content, tagIDs, locale, params)
+		return notModified(r, b, locale, vars)
+	}
+
+	userBanner, err := h.Service.Unload(ctx, req.featureID, tagIDs, req.lastRevision, revalidated)
 	if err != nil {
 		logger.Log.Error("HandleGetUserBanner: get user banner failed",
 			zap.Error(err))
 
 		if errors.Is(err, errs.ErrBannerNotFound) {
-			w.WriteHeader(http.StatusNotFound)
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "banner not found")
+			return
+		}
+
+		if errors.Is(err, errs.ErrBannerCapReached) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeFrequencyCapped, "banner frequency cap reached")
 			return
 		}
 
 		if errors.Is(err, errs.ErrBannerNotAllowed) {
-			w.WriteHeader(http.StatusForbidden)
+			utils.WriteError(w, r, http.StatusForbidden, utils.CodeBannerNotActive, "banner is not active")
 			return
 		}
 
-		w.WriteHeader(http.StatusInternalServerError)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	content, locale, err := h.Service.Localize(ctx, userBanner, accepted)
+	if err != nil {
+		logger.Log.Error("HandleGetUserBanner: localize banner content failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	content, vars := h.Service.Render(ctx, userBanner, content, tagIDs, locale, params)
+
+	h.setCacheHeaders(w, r, userBanner, locale, vars, req.lastRevision)
+	if userBanner.IsFallback(tagIDs) {
+		w.Header().Set(FallbackHeader, "true")
+	}
+	if notModified(r, userBanner, locale, vars) {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	bannerJSON, err := json.Marshal(bannerContent)
+	bannerJSON, err := json.Marshal(content)
 	if err != nil {
 		logger.Log.Error("HandleGetUserBanner: marshal banner content failed",
 			zap.Error(err))
 
-		w.WriteHeader(http.StatusInternalServerError)
-		resp := utils.ParamToJSON("error", err.Error())
-		w.Write(resp)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write(bannerJSON)
 }
+
+// templateParams adds the configured template variables from the request headers
+// to the params, the variables set by the queries are kept.
+func (h *BannerHandler) templateParams(r *http.Request, params map[string]string) {
+	for _, name := range h.Config.TemplateVars {
+		if _, ok := params[name]; !ok {
+			if v := r.Header.Get(banner.TemplateVarHeader(name)); v != "" {
+				params[name] = v
+			}
+		}
+	}
+}
+
+// contentETag returns the entity tag for the user banner content in the locale rendered
+// with the variables, which changes with every banner update or variable value.
+func contentETag(b *banner.Banner, locale string, vars map[string]string) string {
+	tag := fmt.Sprintf("%d-%d", b.ID, b.UpdatedAt.UnixNano())
+	if locale != "" {
+		tag += "-" + strings.ToLower(locale)
+	}
+
+	// The values might be long and contain any characters, so they are hashed
+	if len(vars) != 0 {
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		h := fnv.New64a()
+		for _, name := range names {
+			fmt.Fprintf(h, "%s=%s\x00", name, vars[name])
+		}
+		tag += fmt.Sprintf("-%x", h.Sum64())
+	}
+
+	return `"` + tag + `"`
+}
+
+// VariantHeader is the response header with the ID of the chosen weighted banner variant.
+const VariantHeader = "X-Banner-Variant"
+
+// FallbackHeader is the response header reporting that the default banner of the feature
+// is returned, since no banner of the requested tags is available for the user.
+const FallbackHeader = "X-Banner-Fallback"
+
+// setCacheHeaders sets the validators of the user banner content and allows clients
+// to reuse the content until the banner cache expires. The last revision
+// must be revalidated on every request. The chosen weighted variant is reported
+// in the header and depends on the user, so it is not shared between users.
+// The frequency capped banner is never reused, since every impression is counted.
+// The localized content depends on the Accept-Language and is reported in the Content-Language.
+// The content rendered with the user ID is not shared between users as well,
+// and the content rendered with the configured variables depends on their headers.
+func (h *BannerHandler) setCacheHeaders(w http.ResponseWriter, r *http.Request, b *banner.Banner, locale string, vars map[string]string, lastRevision bool) {
+	w.Header().Set("ETag", contentETag(b, locale, vars))
+	w.Header().Set("Last-Modified", b.UpdatedAt.UTC().Format(http.TimeFormat))
+	if locale != "" {
+		w.Header().Set("Content-Language", locale)
+	}
+
+	// Inactive banners are returned to admins only, so the content depends on the token
+	vary := []string{"token"}
+	_, personal := vars[banner.VarUserID]
+	if b.Weight > 0 || b.Cap.Enabled() || personal {
+		vary = append(vary, middlewares.UserIDHeader)
+	}
+	if len(b.Locales) != 0 {
+		vary = append(vary, "Accept-Language")
+	}
+	for _, name := range h.Config.TemplateVars {
+		if _, ok := vars[name]; ok {
+			vary = append(vary, banner.TemplateVarHeader(name))
+		}
+	}
+	w.Header().Set("Vary", strings.Join(vary, ", "))
+
+	if b.Weight > 0 {
+		w.Header().Set(VariantHeader, strconv.Itoa(b.ID))
+	}
+
+	if b.Cap.Enabled() {
+		w.Header().Set("Cache-Control", "private, no-store")
+		return
+	}
+
+	if lastRevision {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+
+	// Banners got from cache might be reused until the cache entry expires,
+	// the ones read from the database are cached for the whole expiration
+	ttl := h.Config.DefaultExpiration
+	if !b.CachedUntil.IsZero() {
+		ttl = max(time.Until(b.CachedUntil), 0)
+	}
+
+	visibility := "public"
+	if role, _ := utils.GetUserRoleFromContext(r.Context()); role != "user" || b.Weight > 0 || personal {
+		visibility = "private"
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", visibility, int(ttl.Seconds())))
+}
+
+// notModified checks the conditional request headers and reports whether
+// the client already has the actual banner content in the locale rendered with the variables.
+// If-Modified-Since is ignored, when If-None-Match is set.
+func notModified(r *http.Request, b *banner.Banner, locale string, vars map[string]string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		tag := contentETag(b, locale, vars)
+		for _, v := range strings.Split(inm, ",") {
+			v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+			if v == "*" || v == tag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		return !b.UpdatedAt.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// maxUserBatchPairs is the maximum number of the feature and tag pairs
+// requested in one user banners batch.
+const maxUserBatchPairs = 100
+
+// userBannerResponse contains the user banner content or the error,
+// if the banner is not found or not active.
+type userBannerResponse struct {
+	Content *banner.Content `json:"content,omitempty"`
+	Code    string          `json:"code,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// HandleGetUserBanners handles user's request to get banners for several features and tags.
+// The results are returned as map of tag IDs to the map of feature IDs to the banners.
+func (h *BannerHandler) HandleGetUserBanners(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var featureIDs, tagIDs []int
+	var lastRevision bool
+
+	w.Header().Set("Content-Type", "application/json")
+
+	params := make(map[string]string, len(h.Config.TemplateVars))
+	queries := r.URL.Query()
+	for val := range queries {
+		// The configured template variables are set by the var.<name> queries
+		if name, ok := strings.CutPrefix(val, banner.TemplateVarQueryPrefix); ok && slices.Contains(h.Config.TemplateVars, name) {
+			if len(queries[val]) != 1 {
+				logger.Log.Error("HandleGetUserBanners: incorrect queries number",
+					zap.String("query_name", val),
+					zap.Int("query_number", len(queries[val])))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
+				return
+			}
+
+			params[name] = queries[val][0]
+			continue
+		}
+
+		switch val {
+		case "feature_id", "tag_id":
+			// The duplicated IDs are dropped while parsing, so the raw values are limited before
+			if len(queries[val]) > maxUserBatchPairs {
+				logger.Log.Error("HandleGetUserBanners: too many query values",
+					zap.String("query_name", val),
+					zap.Int("query_number", len(queries[val])))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery,
+					fmt.Sprintf("at most %d %s values might be requested", maxUserBatchPairs, val))
+				return
+			}
+
+			ids, err := idsFromQuery(queries[val])
+			if err != nil {
+				logger.Log.Error("HandleGetUserBanners: unexpected query value",
+					zap.String("query_name", val),
+					zap.Error(err))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "unexpected query value")
+				return
+			}
+
+			if val == "feature_id" {
+				featureIDs = ids
+			} else {
+				tagIDs = ids
+			}
+
+		case "use_last_revision":
+			if len(queries[val]) != 1 {
+				logger.Log.Error("HandleGetUserBanners: incorrect queries number",
+					zap.String("query_name", val),
+					zap.Int("query_number", len(queries[val])))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
+				return
+			}
+
+			current, err := strconv.ParseBool(queries[val][0])
+			if err != nil {
+				logger.Log.Error("HandleGetUserBanners: convert query to bool failed",
+					zap.String("query_name", val),
+					zap.String("query_value", queries[val][0]))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "convert query to bool failed")
+				return
+			}
+			lastRevision = current
+
+		default:
+			logger.Log.Error("HandleGetUserBanners: incorrect query",
+				zap.String("query", val))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query in request url")
+			return
+		}
+	}
+
+	if len(featureIDs) == 0 || len(tagIDs) == 0 {
+		logger.Log.Error("HandleGetUserBanners: required queries not set",
+			zap.Int("feature_id", len(featureIDs)),
+			zap.Int("tag_id", len(tagIDs)))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "required queries not set")
+		return
+	}
+
+	// The IDs are unique, so the pairs are checked before they are built
+	if len(featureIDs)*len(tagIDs) > maxUserBatchPairs {
+		logger.Log.Error("HandleGetUserBanners: too many banners requested",
+			zap.Int("feature_id", len(featureIDs)),
+			zap.Int("tag_id", len(tagIDs)))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery,
+			fmt.Sprintf("at most %d feature and tag pairs might be requested", maxUserBatchPairs))
+		return
+	}
+	pairs := userPairs(featureIDs, tagIDs)
+
+	results, err := h.Service.UnloadBatch(ctx, pairs, lastRevision)
+	if err != nil {
+		logger.Log.Error("HandleGetUserBanners: get user banners failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	// The banners are localized and rendered the same way as the single user banner
+	accepted := banner.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	h.templateParams(r, params)
+
+	resp := make(map[int]map[int]*userBannerResponse, len(tagIDs))
+	for _, res := range results {
+		item := &userBannerResponse{}
+		if res.Err == nil {
+			content, locale, err := h.Service.Localize(ctx, res.Banner, accepted)
+			if err != nil {
+				res.Err = fmt.Errorf("HandleGetUserBanners: localize banner content failed %w", err)
+			} else {
+				item.Content, _ = h.Service.Render(ctx, res.Banner, content, []int{res.TagID}, locale, params)
+			}
+		}
+
+		switch {
+		case errors.Is(res.Err, errs.ErrBannerNotFound):
+			item.Code = utils.CodeNotFound
+			item.Error = "banner not found"
+		case errors.Is(res.Err, errs.ErrBannerNotAllowed):
+			item.Code = utils.CodeBannerNotActive
+			item.Error = "banner is not active"
+		case errors.Is(res.Err, errs.ErrBannerCapReached):
+			item.Code = utils.CodeFrequencyCapped
+			item.Error = "banner frequency cap reached"
+		case res.Err != nil:
+			logger.Log.Error("HandleGetUserBanners: get user banner failed",
+				zap.Int("feature_id", res.FeatureID),
+				zap.Int("tag_id", res.TagID),
+				zap.Error(res.Err))
+
+			item.Code = utils.CodeInternal
+			item.Error = "internal server error"
+		}
+
+		if resp[res.TagID] == nil {
+			resp[res.TagID] = make(map[int]*userBannerResponse)
+		}
+		resp[res.TagID][res.FeatureID] = item
+	}
+
+	respJSON, err := json.Marshal(resp)
+	if err != nil {
+		logger.Log.Error("HandleGetUserBanners: marshal banners failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(respJSON)
+}
+
+// idsFromQuery converts the query values into the unique positive integers.
+func idsFromQuery(values []string) ([]int, error) {
+	ids := make([]int, 0, len(values))
+	seen := make(map[int]struct{}, len(values))
+	for _, v := range values {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("idsFromQuery: convert query to integer failed %w", err)
+		}
+		if id < 1 {
+			return nil, fmt.Errorf("idsFromQuery: unexpected query value %d", id)
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// userPairs returns the feature and tag pairs for each of the unique tags and features.
+func userPairs(featureIDs []int, tagIDs []int) []banner.Pair {
+	pairs := make([]banner.Pair, 0, len(featureIDs)*len(tagIDs))
+	for _, tagID := range tagIDs {
+		for _, featureID := range featureIDs {
+			pairs = append(pairs, banner.Pair{FeatureID: featureID, TagID: tagID})
+		}
+	}
+
+	return pairs
+}
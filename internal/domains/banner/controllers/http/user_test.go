@@ -1,21 +1,30 @@
 package http_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/pavlegich/banners-service/internal/controllers/handlers"
+	auditrepo "github.com/pavlegich/banners-service/internal/domains/audit/repository"
 	"github.com/pavlegich/banners-service/internal/domains/banner"
+	caprepo "github.com/pavlegich/banners-service/internal/domains/capping/repository"
+	statsrepo "github.com/pavlegich/banners-service/internal/domains/stats/repository"
+	webhookrepo "github.com/pavlegich/banners-service/internal/domains/webhook/repository"
 	errs "github.com/pavlegich/banners-service/internal/errors"
 	"github.com/pavlegich/banners-service/internal/infra/config"
 	"github.com/pavlegich/banners-service/internal/mocks"
+	"github.com/pavlegich/banners-service/internal/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var bannersList = map[string]*banner.Banner{
@@ -69,6 +78,24 @@ func TestBannerHandler_HandleGetUserBanner(t *testing.T) {
 	mockRepo := mocks.NewMockRepository(mockCtrl)
 	mockCache := mocks.NewMockCache(mockCtrl)
 
+	// the pair has no weighted variants
+	mockCache.EXPECT().GetBannerVariants(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*banner.Banner{}, nil).AnyTimes()
+	mockRepo.EXPECT().GetBannerVariants(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*banner.Banner{}, nil).AnyTimes()
+	mockCache.EXPECT().CreateBannerVariants(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).AnyTimes()
+	// the banners read from the database are put into cache
+	mockCache.EXPECT().CreateBannerByFilter(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).AnyTimes()
+	// the feature has no default banner
+	mockCache.EXPECT().GetDefaultBanner(gomock.Any(), gomock.Any()).
+		Return(nil, nil).AnyTimes()
+	mockRepo.EXPECT().GetDefaultBanner(gomock.Any(), gomock.Any()).
+		Return(nil, errs.ErrBannerNotFound).AnyTimes()
+	mockCache.EXPECT().CreateDefaultBanner(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).AnyTimes()
+
 	gomock.InOrder(
 		// ok for user with cache
 		mockCache.EXPECT().GetBannerByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
@@ -212,7 +239,7 @@ func TestBannerHandler_HandleGetUserBanner(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Controller
-			ctrl := handlers.NewController(ctx, mockRepo, mockCache, cfg)
+			ctrl := handlers.NewController(ctx, mockRepo, mockCache, newCatalogRepo(t, 10), auditrepo.NewAuditMemoryRepository(ctx), webhookrepo.NewWebhookMemoryRepository(ctx), statsrepo.NewStatsMemoryRepository(ctx), caprepo.NewCapMemoryRepository(ctx), cfg)
 			mh, err := ctrl.BuildRoute(ctx)
 			assert.NoError(t, err)
 
@@ -243,3 +270,812 @@ func TestBannerHandler_HandleGetUserBanner(t *testing.T) {
 		})
 	}
 }
+
+func TestBannerHandler_HandleGetUserBanners(t *testing.T) {
+	ctx := context.Background()
+
+	// Initialize the mocks for storage
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockRepo := mocks.NewMockRepository(mockCtrl)
+	mockCache := mocks.NewMockCache(mockCtrl)
+
+	ok := bannersList["ok"]
+	notActive := bannersList["not_active"]
+
+	// the pairs have no weighted variants
+	mockCache.EXPECT().GetBannerVariants(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*banner.Banner{}, nil).AnyTimes()
+	mockRepo.EXPECT().GetBannerVariants(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*banner.Banner{}, nil).AnyTimes()
+	mockCache.EXPECT().CreateBannerVariants(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).AnyTimes()
+	// the banners read from the database are put into cache
+	mockCache.EXPECT().CreateBannerByFilter(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).AnyTimes()
+	// the features have no default banner
+	mockCache.EXPECT().GetDefaultBanner(gomock.Any(), gomock.Any()).
+		Return(nil, nil).AnyTimes()
+	mockRepo.EXPECT().GetDefaultBanner(gomock.Any(), gomock.Any()).
+		Return(nil, errs.ErrBannerNotFound).AnyTimes()
+	mockCache.EXPECT().CreateDefaultBanner(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).AnyTimes()
+
+	gomock.InOrder(
+		// the first pair is found in cache, the others are got from the database
+		mockCache.EXPECT().GetBannerByFilter(gomock.Any(), 1, []int{1}).
+			Return(ok, nil),
+		mockCache.EXPECT().GetBannerByFilter(gomock.Any(), 2, []int{1}).
+			Return(nil, errs.ErrBannerInCacheNotFound),
+		mockRepo.EXPECT().GetBannerByFilter(gomock.Any(), 2, []int{1}).
+			Return(notActive, nil),
+		mockCache.EXPECT().GetBannerByFilter(gomock.Any(), 3, []int{1}).
+			Return(nil, errs.ErrBannerExpired),
+		mockCache.EXPECT().DeleteBanner(gomock.Any(), 0, 3, 1).
+			Return(nil),
+		mockRepo.EXPECT().GetBannerByFilter(gomock.Any(), 3, []int{1}).
+			Return(nil, errs.ErrBannerNotFound),
+
+		// last revision for admin is got from the database only
+		mockRepo.EXPECT().GetBannerByFilter(gomock.Any(), 2, []int{1}).
+			Return(notActive, nil),
+		mockRepo.EXPECT().GetBannerByFilter(gomock.Any(), 2, []int{2}).
+			Return(ok, nil),
+	)
+
+	cfg := &config.Config{}
+	ctrl := handlers.NewController(ctx, mockRepo, mockCache, newCatalogRepo(t, 10), auditrepo.NewAuditMemoryRepository(ctx), webhookrepo.NewWebhookMemoryRepository(ctx), statsrepo.NewStatsMemoryRepository(ctx), caprepo.NewCapMemoryRepository(ctx), cfg)
+	mh, err := ctrl.BuildRoute(ctx)
+	assert.NoError(t, err)
+
+	content := `{"text":"some_text","title":"some_title","url":"some_url"}`
+	tests := []struct {
+		name     string
+		token    string
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "user banners from cache and database",
+			token:    "user_token",
+			query:    "tag_id=1&feature_id=1&feature_id=2&feature_id=3&feature_id=1",
+			wantCode: http.StatusOK,
+			wantBody: `{"1":{"1":{"content":` + content + `},` +
+				`"2":{"code":"banner_not_active","error":"banner is not active"},` +
+				`"3":{"code":"not_found","error":"banner not found"}}}`,
+		},
+		{
+			name:     "admin banners with last revision",
+			token:    "admin_token",
+			query:    "tag_id=1&tag_id=2&feature_id=2&use_last_revision=true",
+			wantCode: http.StatusOK,
+			wantBody: `{"1":{"2":{"content":` + content + `}},"2":{"2":{"content":` + content + `}}}`,
+		},
+		{
+			name:     "tag not set",
+			token:    "user_token",
+			query:    "feature_id=1",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unexpected feature",
+			token:    "user_token",
+			query:    "tag_id=1&feature_id=0",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown query",
+			token:    "user_token",
+			query:    "tag_id=1&feature_id=1&limit=1",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "too many pairs",
+			token:    "user_token",
+			query:    "tag_id=1&tag_id=2" + featureQueries(51),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "too many ids",
+			token:    "user_token",
+			query:    strings.ReplaceAll(featureQueries(20000), "feature_id", "tag_id")[1:] + featureQueries(20000),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "too many duplicated ids",
+			token:    "user_token",
+			query:    "tag_id=1" + strings.Repeat("&feature_id=1", 101),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unexpected user role",
+			token:    "unexpected_user",
+			query:    "tag_id=1&feature_id=1",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080/user_banner/batch?"+tt.query, nil)
+			r.Header.Set("token", tt.token)
+			w := httptest.NewRecorder()
+
+			mh.ServeHTTP(w, r)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			gotBody, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.wantCode, resp.StatusCode, string(gotBody))
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, string(gotBody))
+			}
+		})
+	}
+}
+
+func TestBannerHandler_HandleGetUserBannersResolve(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	for _, body := range []string{
+		`{"tag_ids": [3], "feature_id": 2, "content": {"title": "a"}, "is_active": true, "weight": 30}`,
+		`{"tag_ids": [3], "feature_id": 2, "content": {"title": "b"}, "is_active": true, "weight": 70}`,
+		`{"tag_ids": [9], "feature_id": 3, "content": {"title": "default"}, "is_active": true, "is_default": true}`,
+		`{"tag_ids": [3], "feature_id": 4, "content": {"title": "hi {{user_id}} from {{city | nowhere}}"}, "is_active": true}`,
+	} {
+		resp, gotBody := serve(t, mh, http.MethodPost, "http://localhost:8080/banner", body, nil)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, gotBody)
+	}
+
+	for i := 0; i < 10; i++ {
+		user := map[string]string{"token": "user_token", "X-User-ID": fmt.Sprintf("user-%d", i), "X-Banner-Var-City": "Moscow"}
+
+		// The user gets the same variant as from the single banner request
+		resp, single := serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner?feature_id=2&tag_id=3", "", user)
+		require.Equal(t, http.StatusOK, resp.StatusCode, single)
+
+		resp, gotBody := serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner/batch?feature_id=2&feature_id=3&feature_id=4&tag_id=3", "", user)
+		require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+		var got map[string]map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal([]byte(gotBody), &got))
+		assert.JSONEq(t, `{"content": `+single+`}`, string(got["3"]["2"]))
+		assert.JSONEq(t, `{"content": {"title": "default"}}`, string(got["3"]["3"]))
+		assert.JSONEq(t, fmt.Sprintf(`{"content": {"title": "hi user-%d from Moscow"}}`, i), string(got["3"]["4"]))
+	}
+
+	// The template variables are set by the queries as well
+	resp, gotBody := serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner/batch?feature_id=4&tag_id=3&var.city=Paris", "",
+		map[string]string{"token": "user_token"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.JSONEq(t, `{"3": {"4": {"content": {"title": "hi  from Paris"}}}}`, gotBody)
+}
+
+// featureQueries returns the feature ID queries from 1 to n.
+func featureQueries(n int) string {
+	var b bytes.Buffer
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "&feature_id=%d", i)
+	}
+	return b.String()
+}
+
+func TestBannerHandler_HandleGetUserBannerCaching(t *testing.T) {
+	ctx := context.Background()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockRepo := mocks.NewMockRepository(mockCtrl)
+	mockCache := mocks.NewMockCache(mockCtrl)
+
+	mockCache.EXPECT().GetBannerVariants(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*banner.Banner{}, nil).AnyTimes()
+	mockRepo.EXPECT().GetBannerVariants(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*banner.Banner{}, nil).AnyTimes()
+	mockCache.EXPECT().CreateBannerVariants(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).AnyTimes()
+	// the banners read from the database are put into cache
+	mockCache.EXPECT().CreateBannerByFilter(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).AnyTimes()
+	// the feature has no default banner
+	mockCache.EXPECT().GetDefaultBanner(gomock.Any(), gomock.Any()).
+		Return(nil, nil).AnyTimes()
+	mockRepo.EXPECT().GetDefaultBanner(gomock.Any(), gomock.Any()).
+		Return(nil, errs.ErrBannerNotFound).AnyTimes()
+	mockCache.EXPECT().CreateDefaultBanner(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).AnyTimes()
+
+	// The banner updated long ago is got from the cache entry expiring soon
+	updatedAt := time.Now().Add(-time.Hour)
+	b := &banner.Banner{
+		ID:          7,
+		TagIDs:      []int{1},
+		FeatureID:   1,
+		Content:     &banner.Content{"title": "some_title"},
+		IsActive:    true,
+		UpdatedAt:   updatedAt,
+		CachedUntil: time.Now().Add(time.Minute),
+	}
+	old := *b
+	old.UpdatedAt = time.Now().Add(-2 * time.Hour)
+	old.CachedUntil = time.Time{}
+
+	mockCache.EXPECT().GetBannerByFilter(gomock.Any(), 1, []int{1}).Return(b, nil).AnyTimes()
+	mockCache.EXPECT().GetBannerByFilter(gomock.Any(), 2, []int{1}).Return(nil, errs.ErrBannerInCacheNotFound).AnyTimes()
+	mockRepo.EXPECT().GetBannerByFilter(gomock.Any(), 1, []int{1}).Return(b, nil).AnyTimes()
+	mockRepo.EXPECT().GetBannerByFilter(gomock.Any(), 2, []int{1}).Return(&old, nil).AnyTimes()
+
+	cfg := &config.Config{DefaultExpiration: 5 * time.Minute}
+	ctrl := handlers.NewController(ctx, mockRepo, mockCache, newCatalogRepo(t, 10), auditrepo.NewAuditMemoryRepository(ctx), webhookrepo.NewWebhookMemoryRepository(ctx), statsrepo.NewStatsMemoryRepository(ctx), caprepo.NewCapMemoryRepository(ctx), cfg)
+	mh, err := ctrl.BuildRoute(ctx)
+	assert.NoError(t, err)
+
+	etag := fmt.Sprintf(`"7-%d"`, updatedAt.UnixNano())
+	lastModified := updatedAt.UTC().Format(http.TimeFormat)
+
+	tests := []struct {
+		name         string
+		token        string
+		query        string
+		headers      map[string]string
+		wantCode     int
+		wantControl  string
+		wantMaxAge   time.Duration
+		wantModified bool
+	}{
+		{
+			name:        "user content cached until cache entry expiration",
+			token:       "user_token",
+			query:       "feature_id=1&tag_id=1",
+			wantCode:    http.StatusOK,
+			wantControl: "public",
+			wantMaxAge:  time.Minute,
+		},
+		{
+			name:        "admin content is private",
+			token:       "admin_token",
+			query:       "feature_id=1&tag_id=1",
+			wantCode:    http.StatusOK,
+			wantControl: "private",
+			wantMaxAge:  time.Minute,
+		},
+		{
+			name:        "old content from database cached for whole expiration",
+			token:       "user_token",
+			query:       "feature_id=2&tag_id=1",
+			wantCode:    http.StatusOK,
+			wantControl: "public",
+			wantMaxAge:  5 * time.Minute,
+		},
+		{
+			name:        "last revision not cached",
+			token:       "user_token",
+			query:       "feature_id=1&tag_id=1&use_last_revision=true",
+			wantCode:    http.StatusOK,
+			wantControl: "no-cache",
+		},
+		{
+			name:        "matching entity tag",
+			token:       "user_token",
+			query:       "feature_id=1&tag_id=1",
+			headers:     map[string]string{"If-None-Match": `"1-1", ` + etag},
+			wantCode:    http.StatusNotModified,
+			wantControl: "public",
+			wantMaxAge:  time.Minute,
+		},
+		{
+			name:        "matching entity tag with last revision",
+			token:       "user_token",
+			query:       "feature_id=1&tag_id=1&use_last_revision=true",
+			headers:     map[string]string{"If-None-Match": etag},
+			wantCode:    http.StatusNotModified,
+			wantControl: "no-cache",
+		},
+		{
+			name:        "changed entity tag",
+			token:       "user_token",
+			query:       "feature_id=1&tag_id=1",
+			headers:     map[string]string{"If-None-Match": `"7-1"`, "If-Modified-Since": lastModified},
+			wantCode:    http.StatusOK,
+			wantControl: "public",
+			wantMaxAge:  time.Minute,
+		},
+		{
+			name:        "not modified since",
+			token:       "user_token",
+			query:       "feature_id=1&tag_id=1",
+			headers:     map[string]string{"If-Modified-Since": lastModified},
+			wantCode:    http.StatusNotModified,
+			wantControl: "public",
+			wantMaxAge:  time.Minute,
+		},
+		{
+			name:        "modified since",
+			token:       "user_token",
+			query:       "feature_id=1&tag_id=1",
+			headers:     map[string]string{"If-Modified-Since": updatedAt.Add(-time.Hour).UTC().Format(http.TimeFormat)},
+			wantCode:    http.StatusOK,
+			wantControl: "public",
+			wantMaxAge:  time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080/user_banner?"+tt.query, nil)
+			r.Header.Set("token", tt.token)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			mh.ServeHTTP(w, r)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			gotBody, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.wantCode, resp.StatusCode, string(gotBody))
+			assert.Equal(t, "token", resp.Header.Get("Vary"))
+			if tt.wantCode == http.StatusNotModified {
+				assert.Empty(t, gotBody)
+			}
+			if tt.query == "feature_id=2&tag_id=1" {
+				assert.Equal(t, old.UpdatedAt.UTC().Format(http.TimeFormat), resp.Header.Get("Last-Modified"))
+			} else {
+				assert.Equal(t, etag, resp.Header.Get("ETag"))
+				assert.Equal(t, lastModified, resp.Header.Get("Last-Modified"))
+			}
+
+			control := resp.Header.Get("Cache-Control")
+			if tt.wantMaxAge == 0 {
+				assert.Equal(t, tt.wantControl, control)
+				return
+			}
+
+			var visibility string
+			var maxAge int
+			_, err = fmt.Sscanf(control, "%s max-age=%d", &visibility, &maxAge)
+			assert.NoError(t, err, control)
+			assert.Equal(t, tt.wantControl+",", visibility)
+			assert.InDelta(t, tt.wantMaxAge.Seconds(), maxAge, 5)
+		})
+	}
+}
+
+func TestBannerHandler_HandleGetUserBannerVariants(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	for _, body := range []string{
+		`{"tag_ids": [3], "feature_id": 2, "content": {"title": "a"}, "is_active": true, "weight": 30}`,
+		`{"tag_ids": [3], "feature_id": 2, "content": {"title": "b"}, "is_active": true, "weight": 70}`,
+	} {
+		resp, gotBody := serve(t, mh, http.MethodPost, "http://localhost:8080/banner", body, nil)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, gotBody)
+	}
+
+	variants := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		headers := map[string]string{"token": "user_token", "X-User-ID": fmt.Sprintf("user-%d", i)}
+		resp, _ := serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner?feature_id=2&tag_id=3", "", headers)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "token, X-User-ID", resp.Header.Get("Vary"))
+		assert.Contains(t, resp.Header.Get("Cache-Control"), "private")
+
+		variant := resp.Header.Get("X-Banner-Variant")
+		variants[variant]++
+
+		// The user sees the same variant
+		resp, _ = serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner?feature_id=2&tag_id=3", "", headers)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, variant, resp.Header.Get("X-Banner-Variant"))
+	}
+	assert.Len(t, variants, 2)
+	assert.Greater(t, variants["3"], variants["2"])
+
+	// The variant without weight is not shown
+	resp, gotBody := serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/3", `{"weight": 0}`,
+		map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+	for i := 0; i < 10; i++ {
+		headers := map[string]string{"token": "user_token", "X-User-ID": fmt.Sprintf("user-%d", i)}
+		resp, gotBody = serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner?feature_id=2&tag_id=3", "", headers)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("X-Banner-Variant"))
+		assert.JSONEq(t, `{"title": "a"}`, gotBody)
+	}
+}
+
+func TestBannerHandler_HandleGetUserBannerVariantsQuery(t *testing.T) {
+	ctx := context.Background()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockRepo := mocks.NewMockRepository(mockCtrl)
+	mockCache := mocks.NewMockCache(mockCtrl)
+
+	variant := &banner.Banner{
+		ID:        5,
+		TagIDs:    []int{3},
+		FeatureID: 1,
+		Content:   &banner.Content{"title": "variant"},
+		IsActive:  true,
+		Weight:    100,
+	}
+
+	// The variants of the tags missed in cache are read with one query and cached by tag
+	mockCache.EXPECT().GetBannerVariants(gomock.Any(), 1, 1).Return([]*banner.Banner{}, nil)
+	mockCache.EXPECT().GetBannerVariants(gomock.Any(), 1, 2).Return(nil, errs.ErrBannerInCacheNotFound)
+	mockCache.EXPECT().GetBannerVariants(gomock.Any(), 1, 3).Return(nil, errs.ErrBannerExpired)
+	mockRepo.EXPECT().GetBannerVariants(gomock.Any(), 1, []int{2, 3}).Return([]*banner.Banner{variant}, nil).Times(1)
+	mockCache.EXPECT().CreateBannerVariants(gomock.Any(), 1, 2, []*banner.Banner{}).Return(nil)
+	mockCache.EXPECT().CreateBannerVariants(gomock.Any(), 1, 3, []*banner.Banner{variant}).Return(nil)
+
+	// The tags without variants have no banner, so the variant is returned
+	mockCache.EXPECT().GetBannerByFilter(gomock.Any(), 1, []int{1, 2}).Return(nil, errs.ErrBannerInCacheNotFound)
+	mockRepo.EXPECT().GetBannerByFilter(gomock.Any(), 1, []int{1, 2}).Return(nil, errs.ErrBannerNotFound)
+
+	ctrl := handlers.NewController(ctx, mockRepo, mockCache, newCatalogRepo(t, 10), auditrepo.NewAuditMemoryRepository(ctx), webhookrepo.NewWebhookMemoryRepository(ctx), statsrepo.NewStatsMemoryRepository(ctx), caprepo.NewCapMemoryRepository(ctx), &config.Config{})
+	mh, err := ctrl.BuildRoute(ctx)
+	require.NoError(t, err)
+
+	resp, gotBody := serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner?feature_id=1&tag_id=1&tag_id=2&tag_id=3", "",
+		map[string]string{"token": "user_token"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.JSONEq(t, `{"title": "variant"}`, gotBody)
+	assert.Equal(t, "5", resp.Header.Get("X-Banner-Variant"))
+}
+
+func TestBannerHandler_HandleGetUserBannerFrequencyCap(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	resp, gotBody := serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1",
+		`{"frequency_cap": {"impressions": 2, "window": 3600}}`, map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+	url := "http://localhost:8080/user_banner?feature_id=1&tag_id=1"
+	user := map[string]string{"token": "user_token", "X-User-ID": "user-1"}
+	for i := 0; i < 2; i++ {
+		resp, gotBody = serve(t, mh, http.MethodGet, url, "", user)
+		require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+		assert.Equal(t, "private, no-store", resp.Header.Get("Cache-Control"))
+		assert.Equal(t, "token, X-User-ID", resp.Header.Get("Vary"))
+
+		// The revalidated content is not shown again, so it is not counted
+		revalidate := map[string]string{"If-None-Match": resp.Header.Get("ETag")}
+		for k, v := range user {
+			revalidate[k] = v
+		}
+		resp, gotBody = serve(t, mh, http.MethodGet, url, "", revalidate)
+		require.Equal(t, http.StatusNotModified, resp.StatusCode, gotBody)
+	}
+
+	resp, gotBody = serve(t, mh, http.MethodGet, url, "", user)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Contains(t, gotBody, utils.CodeFrequencyCapped)
+
+	resp, gotBody = serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner/batch?feature_id=1&tag_id=1&tag_id=2", "", user)
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.Contains(t, gotBody, `"1":{"1":{"code":"`+utils.CodeFrequencyCapped)
+
+	// The cap is counted per user, the anonymous users and admins are not limited
+	for _, headers := range []map[string]string{
+		{"token": "user_token", "X-User-ID": "user-2"},
+		{"token": "user_token"},
+		{"token": "user_token"},
+		{"token": "user_token"},
+		nil,
+	} {
+		resp, gotBody = serve(t, mh, http.MethodGet, url, "", headers)
+		require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	}
+}
+
+func TestBannerHandler_HandleGetUserBannerPriority(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	for _, body := range []string{
+		`{"tag_ids": [3], "feature_id": 1, "content": {"title": "prioritized"}, "is_active": true, "priority": 5}`,
+		`{"tag_ids": [1], "feature_id": 1, "content": {"title": "latest"}, "is_active": true}`,
+	} {
+		resp, gotBody := serve(t, mh, http.MethodPost, "http://localhost:8080/banner", body, nil)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, gotBody)
+	}
+
+	user := map[string]string{"token": "user_token"}
+	get := func(t *testing.T, query string, want string) {
+		t.Helper()
+
+		// The second request is served from cache
+		for i := 0; i < 2; i++ {
+			resp, gotBody := serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner?"+query, "", user)
+			require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+			assert.JSONEq(t, `{"title": "`+want+`"}`, gotBody)
+		}
+	}
+
+	get(t, "feature_id=1&tag_id=1", "latest")
+	get(t, "feature_id=1&tag_id=1&tag_id=3", "prioritized")
+	get(t, "feature_id=1&tag_id=3&tag_id=1&tag_id=3", "prioritized")
+
+	// The cached banners are replaced by the banner with the higher priority
+	resp, gotBody := serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1", `{"priority": 7}`, map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	get(t, "feature_id=1&tag_id=1", "some_title")
+	get(t, "feature_id=1&tag_id=1&tag_id=3", "some_title")
+
+	// The banner losing the priority is not served from cache anymore,
+	// but it is still the latest updated one for its tag
+	resp, gotBody = serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1", `{"priority": 0}`, map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	get(t, "feature_id=1&tag_id=1", "some_title")
+	get(t, "feature_id=1&tag_id=1&tag_id=3", "prioritized")
+
+	// The deactivated banner is replaced by the next one
+	resp, gotBody = serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1", `{"is_active": false}`, map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	get(t, "feature_id=1&tag_id=1", "latest")
+
+	tooMany := "feature_id=1"
+	for i := 1; i <= 21; i++ {
+		tooMany += fmt.Sprintf("&tag_id=%d", i)
+	}
+	for _, query := range []string{tooMany, "feature_id=1&feature_id=2&tag_id=1", "feature_id=1&tag_id=1&tag_id=0"} {
+		resp, gotBody = serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner?"+query, "", user)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, gotBody)
+	}
+}
+
+func TestBannerHandler_HandleGetUserBannerFallback(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	user := map[string]string{"token": "user_token"}
+	get := func(t *testing.T, query string, want string, fallback bool) {
+		t.Helper()
+
+		// The second request is served from cache
+		for i := 0; i < 2; i++ {
+			resp, gotBody := serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner?"+query, "", user)
+			require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+			assert.JSONEq(t, `{"title": "`+want+`"}`, gotBody)
+			if fallback {
+				assert.Equal(t, "true", resp.Header.Get("X-Banner-Fallback"))
+			} else {
+				assert.Empty(t, resp.Header.Get("X-Banner-Fallback"))
+			}
+		}
+	}
+
+	// The feature without default banner has nothing to return
+	resp, gotBody := serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner?feature_id=1&tag_id=3", "", user)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode, gotBody)
+
+	resp, gotBody = serve(t, mh, http.MethodPost, "http://localhost:8080/banner",
+		`{"tag_ids": [9], "feature_id": 1, "content": {"title": "default"}, "is_active": true, "is_default": true}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, gotBody)
+
+	get(t, "feature_id=1&tag_id=3", "default", true)
+	get(t, "feature_id=1&tag_id=1", "some_title", false)
+	get(t, "feature_id=1&tag_id=9", "default", false)
+
+	// The default banner is returned instead of the inactive one
+	resp, gotBody = serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1", `{"is_active": false}`, map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	get(t, "feature_id=1&tag_id=1", "default", true)
+
+	// The default banner of the other feature is not used
+	resp, gotBody = serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner?feature_id=2&tag_id=3", "", user)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode, gotBody)
+
+	resp, gotBody = serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/2", `{"is_default": false}`, map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	resp, gotBody = serve(t, mh, http.MethodGet, "http://localhost:8080/user_banner?feature_id=1&tag_id=3", "", user)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode, gotBody)
+}
+
+func TestBannerHandler_HandleGetUserBannerLocale(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	resp, gotBody := serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1",
+		`{"localized_content": {"en": {"title": "english"}, "pt-BR": {"title": "brasileiro"}, "de": {"title": "deutsch"}}}`, map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+	url := "http://localhost:8080/user_banner?feature_id=1&tag_id=1"
+	tests := []struct {
+		name       string
+		query      string
+		language   string
+		wantTitle  string
+		wantLocale string
+	}{
+		{name: "exact locale", language: "pt-BR", wantTitle: "brasileiro", wantLocale: "pt-BR"},
+		{name: "case insensitive", language: "PT-br", wantTitle: "brasileiro", wantLocale: "pt-BR"},
+		{name: "language without region", language: "en-GB", wantTitle: "english", wantLocale: "en"},
+		{name: "quality order", language: "fr;q=0.9, de;q=0.5, en;q=0.7", wantTitle: "english", wantLocale: "en"},
+		{name: "zero quality", language: "en;q=0, de", wantTitle: "deutsch", wantLocale: "de"},
+		{name: "query before header", query: "&locale=de", language: "en", wantTitle: "deutsch", wantLocale: "de"},
+		{name: "query falls back to header", query: "&locale=fr", language: "en", wantTitle: "english", wantLocale: "en"},
+		{name: "not localized content", language: "fr", wantTitle: "some_title"},
+		{name: "no language", wantTitle: "some_title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, gotBody := serve(t, mh, http.MethodGet, url+tt.query, "", map[string]string{"token": "user_token", "Accept-Language": tt.language})
+			require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+			assert.JSONEq(t, `{"title": "`+tt.wantTitle+`"}`, gotBody)
+			assert.Equal(t, tt.wantLocale, resp.Header.Get("Content-Language"))
+			assert.Equal(t, "token, Accept-Language", resp.Header.Get("Vary"))
+		})
+	}
+
+	// The content of every locale has its own entity tag
+	en, _ := serve(t, mh, http.MethodGet, url, "", map[string]string{"token": "user_token", "Accept-Language": "en"})
+	de, _ := serve(t, mh, http.MethodGet, url, "", map[string]string{"token": "user_token", "Accept-Language": "de"})
+	assert.NotEqual(t, en.Header.Get("ETag"), de.Header.Get("ETag"))
+
+	resp, _ = serve(t, mh, http.MethodGet, url, "", map[string]string{"token": "user_token", "Accept-Language": "de", "If-None-Match": en.Header.Get("ETag")})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = serve(t, mh, http.MethodGet, url, "", map[string]string{"token": "user_token", "Accept-Language": "en", "If-None-Match": en.Header.Get("ETag")})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	// The default locale of the feature is used, if none of the user locales is available
+	resp, gotBody = serve(t, mh, http.MethodPatch, "http://localhost:8080/feature/1", `{"default_locale": "de-AT"}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+	resp, gotBody = serve(t, mh, http.MethodGet, url, "", map[string]string{"token": "user_token", "Accept-Language": "fr"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.JSONEq(t, `{"title": "deutsch"}`, gotBody)
+	assert.Equal(t, "de", resp.Header.Get("Content-Language"))
+
+	// The locale is removed by null
+	resp, gotBody = serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1", `{"localized_content": {"de": null}}`, map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	resp, gotBody = serve(t, mh, http.MethodGet, "http://localhost:8080/banner/1", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.NotContains(t, gotBody, "deutsch")
+	assert.Contains(t, gotBody, "brasileiro")
+
+	for _, body := range []string{
+		`{"localized_content": {"english": {"title": "a"}}}`,
+		`{"localized_content": {"en": {"": "a"}}}`,
+	} {
+		resp, gotBody = serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1", body, map[string]string{"If-Match": "*"})
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, gotBody)
+		assert.Contains(t, gotBody, utils.CodeValidationFailed)
+	}
+
+	resp, gotBody = serve(t, mh, http.MethodGet, url+"&locale=not_a_locale", "", map[string]string{"token": "user_token"})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode, gotBody)
+}
+
+func TestBannerHandler_HandleGetUserBannerTemplate(t *testing.T) {
+	mh, _ := newAdminRoute(t)
+
+	resp, gotBody := serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1",
+		`{"content": {"title": "Hello, {{ user_id | guest }}!", "url": "https://example.com/?f={{feature_id}}&t={{tag_id}}&l={{locale}}"},
+		"localized_content": {"de": {"title": "Hallo, {{user_id|Gast}}!"}}}`, map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+	url := "http://localhost:8080/user_banner?feature_id=1&tag_id=3&tag_id=2"
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		wantBody  string
+		wantVary  string
+		wantCache string
+	}{
+		{
+			name:      "user variables",
+			headers:   map[string]string{"token": "user_token", "X-User-ID": "alice"},
+			wantBody:  `{"title": "Hello, alice!", "url": "https://example.com/?f=1&t=2&l="}`,
+			wantVary:  "token, X-User-ID, Accept-Language",
+			wantCache: "private",
+		},
+		{
+			name:      "fallback value",
+			headers:   map[string]string{"token": "user_token"},
+			wantBody:  `{"title": "Hello, guest!", "url": "https://example.com/?f=1&t=2&l="}`,
+			wantVary:  "token, X-User-ID, Accept-Language",
+			wantCache: "private",
+		},
+		{
+			name:      "localized template",
+			headers:   map[string]string{"token": "user_token", "X-User-ID": "bob", "Accept-Language": "de-DE"},
+			wantBody:  `{"title": "Hallo, bob!"}`,
+			wantVary:  "token, X-User-ID, Accept-Language",
+			wantCache: "private",
+		},
+		{
+			name:      "control characters dropped",
+			headers:   map[string]string{"token": "user_token", "X-User-ID": "eve\x7f"},
+			wantBody:  `{"title": "Hello, eve!", "url": "https://example.com/?f=1&t=2&l="}`,
+			wantVary:  "token, X-User-ID, Accept-Language",
+			wantCache: "private",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, gotBody := serve(t, mh, http.MethodGet, url, "", tt.headers)
+			require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+			assert.JSONEq(t, tt.wantBody, gotBody)
+			assert.Equal(t, tt.wantVary, resp.Header.Get("Vary"))
+			assert.Contains(t, resp.Header.Get("Cache-Control"), tt.wantCache)
+		})
+	}
+
+	// The locale variable is the resolved locale of the content
+	resp, gotBody = serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1",
+		`{"content": {"title": "{{locale|none}}"}, "localized_content": {"en": {"title": "{{locale}}"}}}`, map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+	resp, gotBody = serve(t, mh, http.MethodGet, url, "", map[string]string{"token": "user_token", "Accept-Language": "en-US"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.JSONEq(t, `{"title": "en"}`, gotBody)
+	assert.Equal(t, "token, Accept-Language", resp.Header.Get("Vary"))
+	assert.Contains(t, resp.Header.Get("Cache-Control"), "public")
+
+	resp, gotBody = serve(t, mh, http.MethodGet, url, "", map[string]string{"token": "user_token"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.JSONEq(t, `{"title": "none", "url": "https://example.com/?f=1&t=2&l="}`, gotBody)
+
+	// The configured variables are got from the queries, then from the headers
+	resp, gotBody = serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1",
+		`{"content": {"title": "Sale in {{city|your city}}", "url": "https://example.com/?code={{ promo_code }}"}}`, map[string]string{"If-Match": "*"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+
+	resp, gotBody = serve(t, mh, http.MethodGet, url+"&var.city=Berlin", "", map[string]string{"token": "user_token", "X-Banner-Var-City": "Paris", "X-Banner-Var-Promo-Code": "SALE10"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.JSONEq(t, `{"title": "Sale in Berlin", "url": "https://example.com/?code=SALE10"}`, gotBody)
+	assert.Equal(t, "token, Accept-Language, X-Banner-Var-City, X-Banner-Var-Promo-Code", resp.Header.Get("Vary"))
+
+	resp, gotBody = serve(t, mh, http.MethodGet, url, "", map[string]string{"token": "user_token", "X-Banner-Var-City": "Paris"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.JSONEq(t, `{"title": "Sale in Paris", "url": "https://example.com/?code="}`, gotBody)
+
+	resp, gotBody = serve(t, mh, http.MethodGet, url, "", map[string]string{"token": "user_token"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.JSONEq(t, `{"title": "Sale in your city", "url": "https://example.com/?code="}`, gotBody)
+
+	// The entity tag depends on the values of the used variables
+	resp, gotBody = serve(t, mh, http.MethodGet, url+"&var.city=Berlin", "", map[string]string{"token": "user_token"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	etag := resp.Header.Get("ETag")
+
+	resp, _ = serve(t, mh, http.MethodGet, url+"&var.city=Berlin", "", map[string]string{"token": "user_token", "If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, gotBody = serve(t, mh, http.MethodGet, url+"&var.city=Rome", "", map[string]string{"token": "user_token", "If-None-Match": etag})
+	require.Equal(t, http.StatusOK, resp.StatusCode, gotBody)
+	assert.JSONEq(t, `{"title": "Sale in Rome", "url": "https://example.com/?code="}`, gotBody)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+
+	// Only the configured variables might be set by the queries
+	resp, gotBody = serve(t, mh, http.MethodGet, url+"&var.price=10", "", map[string]string{"token": "user_token"})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode, gotBody)
+	resp, gotBody = serve(t, mh, http.MethodGet, url+"&var.city=Berlin&var.city=Paris", "", map[string]string{"token": "user_token"})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode, gotBody)
+
+	// The broken templates are rejected on create and update
+	for _, body := range []string{
+		`{"content": {"title": "Hello, {{user_id"}}`,
+		`{"content": {"title": "Hello, {{password}}"}}`,
+		`{"content": {"title": "Only {{price}}"}}`,
+		`{"content": {"title": "Hello, {{user_id|{{locale}}}}"}}`,
+		`{"localized_content": {"en": {"title": "{{ }}"}}}`,
+		`{"content": {"title": "` + strings.Repeat("{{user_id}}", banner.MaxTemplatePlaceholders+1) + `"}}`,
+	} {
+		resp, gotBody = serve(t, mh, http.MethodPatch, "http://localhost:8080/banner/1", body, map[string]string{"If-Match": "*"})
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, gotBody)
+		assert.Contains(t, gotBody, utils.CodeValidationFailed)
+	}
+
+	resp, gotBody = serve(t, mh, http.MethodPost, "http://localhost:8080/banner",
+		`{"tag_ids": [5], "feature_id": 5, "content": {"title": "{{unknown}}"}, "is_active": true}`, nil)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode, gotBody)
+	assert.Contains(t, gotBody, "template unknown variable")
+}
@@ -0,0 +1,90 @@
+package banner
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// localePattern is the pattern of the language tag (RFC 5646), e.g. en, en-US or zh-Hant-TW.
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ValidLocale reports whether the locale is the language tag.
+func ValidLocale(locale string) bool {
+	return len(locale) <= MaxLocaleLength && localePattern.MatchString(locale)
+}
+
+// ParseAcceptLanguage returns the language tags of the Accept-Language header value
+// from the most preferred one to the least preferred one. The wildcard, invalid tags
+// and the tags with zero quality are skipped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+
+	list := make([]weighted, 0)
+	for _, part := range strings.Split(header, ",") {
+		locale, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		locale = strings.TrimSpace(locale)
+		if !ValidLocale(locale) {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			v, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = v
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		list = append(list, weighted{locale: locale, quality: quality})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].quality > list[j].quality })
+
+	locales := make([]string, len(list))
+	for i, w := range list {
+		locales[i] = w.locale
+	}
+
+	return locales
+}
+
+// Localize returns the banner content for the most preferred of the accepted locales
+// and the resolved locale. Every locale is looked up by the whole tag, then by the
+// language without region, e.g. en-US and then en. If none of the accepted locales
+// is available, the content of the default locale of the feature is returned,
+// and the not localized content with the empty locale is the last resort.
+func (b *Banner) Localize(accepted []string, defaultLocale string) (*Content, string) {
+	if len(b.Locales) == 0 {
+		return b.Content, ""
+	}
+
+	chain := make([]string, 0, 2*len(accepted)+2)
+	for _, locale := range append(slices.Clone(accepted), defaultLocale) {
+		if locale == "" {
+			continue
+		}
+		chain = append(chain, locale)
+		if language, _, ok := strings.Cut(locale, "-"); ok {
+			chain = append(chain, language)
+		}
+	}
+
+	for _, locale := range chain {
+		for k, content := range b.Locales {
+			if strings.EqualFold(k, locale) {
+				return &content, k
+			}
+		}
+	}
+
+	return b.Content, ""
+}
@@ -3,31 +3,155 @@
 package banner
 
 import (
+	"bytes"
 	"context"
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"time"
+
+	errs "github.com/pavlegich/banners-service/internal/errors"
 )
 
 // Banner contains data for banners.
 type Banner struct {
-	ID        int       `json:"banner_id"`
-	TagIDs    []int     `json:"tag_ids"`
-	FeatureID int       `json:"feature_id"`
-	Content   *Content  `json:"content"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int        `json:"banner_id"`
+	TagIDs    []int      `json:"tag_ids"`
+	FeatureID int        `json:"feature_id"`
+	Content   *Content   `json:"content"`
+	Locales   Locales    `json:"localized_content,omitempty"`
+	IsActive  bool       `json:"is_active"`
+	Weight    int        `json:"weight"`
+	Priority  int        `json:"priority"`
+	IsDefault bool       `json:"is_default"`
+	Cap       Cap        `json:"frequency_cap"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Version   int        `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	FeatureName string   `json:"feature_name,omitempty"`
+	TagNames    []string `json:"tag_names,omitempty"`
+
+	// CachedUntil is the expiration of the cache entry the banner is got from,
+	// zero for the banner read from the storage.
+	CachedUntil time.Time `json:"-"`
+}
+
+// Patch contains the banner fields requested for partial update.
+// Nil fields are not changed, the content is applied as JSON Merge Patch (RFC 7396).
+// The localized content replaces the content of the locales, the null removes the locale.
+type Patch struct {
+	TagIDs    *[]int              `json:"tag_ids"`
+	FeatureID *int                `json:"feature_id"`
+	Content   json.RawMessage     `json:"content"`
+	Locales   map[string]*Content `json:"localized_content"`
+	IsActive  *bool               `json:"is_active"`
+	Weight    *int                `json:"weight"`
+	Priority  *int                `json:"priority"`
+	IsDefault *bool               `json:"is_default"`
+	Cap       *Cap                `json:"frequency_cap"`
+}
+
+// Cap contains the frequency cap of the banner: the maximum number of the banner
+// impressions for the user during the window in seconds since the first impression.
+// The zero cap means the impressions are not limited.
+type Cap struct {
+	Impressions int `json:"impressions"`
+	Window      int `json:"window"`
+}
+
+// Enabled reports whether the impressions are limited by the cap.
+func (c Cap) Enabled() bool {
+	return c.Impressions > 0
+}
+
+// BatchItem contains the banner to create or, if the ID is set,
+// the patch of the stored banner with the expected version to update.
+type BatchItem struct {
+	ID      int `json:"banner_id"`
+	Version int `json:"version"`
+	Patch
+}
+
+// List of the batch item result statuses.
+const (
+	BatchCreated = "created"
+	BatchUpdated = "updated"
+	BatchFailed  = "failed"
+	BatchSkipped = "skipped"
+)
+
+// BatchResult contains the result of the batch item: the stored banner,
+// if the batch is applied, or the error, if the item failed.
+type BatchResult struct {
+	Index  int
+	Status string
+	Banner *Banner
+	Err    error
+}
+
+// List of the import modes: only new banners are created
+// or the stored banners are updated by ID.
+const (
+	ImportCreate = "create"
+	ImportUpsert = "upsert"
+)
+
+// ImportConflict is the status of the banner, which is not imported
+// because of the conflicts with other banners.
+const ImportConflict = "conflict"
+
+// Conflict contains the feature and tag pair, for which the imported banner is ambiguous
+// with the stored banner or, if the banner ID is zero, with the other imported banner.
+// Zero tag ID means both banners are the default banners of the feature.
+type Conflict struct {
+	FeatureID int
+	TagID     int
+	BannerID  int
+	Index     int
+}
+
+// ImportResult contains the result of the imported banner.
+type ImportResult struct {
+	BatchResult
+	Conflicts []Conflict
+}
+
+// Pair contains the feature and tag pair, which identifies the banner for users.
+type Pair struct {
+	FeatureID int
+	TagID     int
+}
+
+// UserBanner contains the user banner found by the feature and tag pair
+// or the error, if the banner is not found or not allowed for the user.
+type UserBanner struct {
+	Pair
+	Banner *Banner
+	Err    error
 }
 
 // Service describes methods for communication between
 // handlers and repositories.
 type Service interface {
-	Unload(ctx context.Context, featureID int, tagID int, lastRevision bool) (*Content, error)
+	Unload(ctx context.Context, featureID int, tagIDs []int, lastRevision bool, notModified func(*Banner) bool) (*Banner, error)
+	UnloadBatch(ctx context.Context, pairs []Pair, lastRevision bool) ([]*UserBanner, error)
+	Localize(ctx context.Context, banner *Banner, accepted []string) (*Content, string, error)
+	Render(ctx context.Context, banner *Banner, content *Content, tagIDs []int, locale string, params map[string]string) (*Content, map[string]string)
 	Create(ctx context.Context, banner *Banner) (int, error)
-	List(ctx context.Context, featureID int, tagID int, limit int, offset int) ([]*Banner, error)
-	Update(ctx context.Context, banner *Banner) error
-	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, featureID int, tagID int, limit int, offset int, deleted bool) ([]*Banner, error)
+	Update(ctx context.Context, id int, version int, patch *Patch) (*Banner, error)
+	Batch(ctx context.Context, items []*BatchItem) ([]*BatchResult, error)
+	Export(ctx context.Context, featureID int, tagID int, limit int, offset int, deleted bool, fn func(*Banner) error) error
+	Import(ctx context.Context, banners []*Banner, mode string, dryRun bool) ([]*ImportResult, error)
+	Get(ctx context.Context, id int) (*Banner, error)
+	Delete(ctx context.Context, id int, version int) error
+	Restore(ctx context.Context, id int) error
+	Subscribe(ctx context.Context, pairs []Pair, lastEventID int64) (*Subscription, []*Event, error)
 }
 
 // Repository describes methods related with banners
@@ -35,11 +159,43 @@ type Service interface {
 //
 //go:generate mockgen -destination=../../mocks/mock_Repository.go -package=mocks github.com/pavlegich/banners-service/internal/domains/banner Repository
 type Repository interface {
-	GetBannerByFilter(ctx context.Context, featureID int, tagID int) (*Banner, error)
+	GetBannerByFilter(ctx context.Context, featureID int, tagIDs []int) (*Banner, error)
+	GetBannerVariants(ctx context.Context, featureID int, tagIDs []int) ([]*Banner, error)
+	GetDefaultBanner(ctx context.Context, featureID int) (*Banner, error)
+	GetBannerByID(ctx context.Context, id int) (*Banner, error)
 	CreateBanner(ctx context.Context, banner *Banner) (*Banner, error)
-	GetBannersByFilter(ctx context.Context, featureID int, tagID int, limit int, offset int) ([]*Banner, error)
+	GetBannersByFilter(ctx context.Context, featureID int, tagID int, limit int, offset int, deleted bool) ([]*Banner, error)
 	UpdateBanner(ctx context.Context, banner *Banner) (*Banner, error)
-	DeleteBannerByID(ctx context.Context, id int) error
+	SaveBanners(ctx context.Context, banners []*Banner) ([]*Banner, error)
+	DeleteBannerByID(ctx context.Context, id int, version int) error
+	RestoreBannerByID(ctx context.Context, id int) (*Banner, error)
+	PurgeDeletedBanners(ctx context.Context, deletedBefore time.Time) (int, error)
+	Outbox
+}
+
+// Notifier describes methods for notifying the external systems about the banners changes.
+// The key identifies the change, so the change notified more than once might be skipped.
+type Notifier interface {
+	Notify(ctx context.Context, key string, event string, bannerID int, before any, after any) error
+}
+
+// Catalog describes methods for getting the names of the features and tags
+// referenced by the banners and the default locales of the features.
+// The unknown IDs are not presented in the result.
+type Catalog interface {
+	Names(ctx context.Context, featureIDs []int, tagIDs []int) (map[int]string, map[int]string, error)
+	DefaultLocale(ctx context.Context, featureID int) (string, error)
+}
+
+// Tracker describes methods for counting the banners shown to users.
+type Tracker interface {
+	TrackImpression(ctx context.Context, bannerID int, tagID int)
+}
+
+// Capper describes methods for limiting the banners impressions for users.
+// The impression is counted only if it is allowed by the cap.
+type Capper interface {
+	Allow(ctx context.Context, bannerID int, userID string, c Cap) (bool, error)
 }
 
 // Cache describes methods realted with banners stored in cache.
@@ -47,7 +203,13 @@ type Repository interface {
 //go:generate mockgen -destination=../../mocks/mock_Cache.go -package=mocks github.com/pavlegich/banners-service/internal/domains/banner Cache
 type Cache interface {
 	CreateBanner(ctx context.Context, banner *Banner) error
-	GetBannerByFilter(ctx context.Context, featureID int, tagID int) (*Banner, error)
+	CreateBanners(ctx context.Context, banners []*Banner) error
+	CreateBannerByFilter(ctx context.Context, featureID int, tagIDs []int, banner *Banner) error
+	GetBannerByFilter(ctx context.Context, featureID int, tagIDs []int) (*Banner, error)
+	CreateBannerVariants(ctx context.Context, featureID int, tagID int, variants []*Banner) error
+	GetBannerVariants(ctx context.Context, featureID int, tagID int) ([]*Banner, error)
+	CreateDefaultBanner(ctx context.Context, featureID int, banner *Banner) error
+	GetDefaultBanner(ctx context.Context, featureID int) (*Banner, error)
 	DeleteBanner(ctx context.Context, id int, featureID int, tagID int) error
 	GarbageCollect(ctx context.Context)
 }
@@ -69,3 +231,287 @@ func (c *Content) Scan(v interface{}) error {
 		return fmt.Errorf("cannot scan type %t into Map", v)
 	}
 }
+
+// Locales type contains the banner content per locale for implementing the Scanner interface.
+type Locales map[string]Content
+
+// Scan implements Scan method for scanning the localized banner content from the storage.
+func (l *Locales) Scan(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	switch data := v.(type) {
+	case string:
+		return json.Unmarshal([]byte(data), &l)
+	case []byte:
+		return json.Unmarshal(data, &l)
+	default:
+		return fmt.Errorf("cannot scan type %t into Locales", v)
+	}
+}
+
+// Value implements Value method for storing the localized banner content,
+// the banner without localized content is stored as the empty object.
+func (l Locales) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, fmt.Errorf("Value: marshal localized content failed %w", err)
+	}
+
+	return string(data), nil
+}
+
+// Limits of the banner data.
+const (
+	MaxTagIDs             = 100
+	MaxContentFields      = 50
+	MaxContentKeyLength   = 64
+	MaxContentValueLength = 4096
+	MaxLocales            = 20
+	MaxLocaleLength       = 35
+	MaxWeight             = 10000
+	MaxPriority           = 1000
+	MaxCapImpressions     = 1000000
+	MaxCapWindow          = 30 * 24 * 60 * 60
+)
+
+// Validate checks whether the banner data might be stored and returns
+// the validation error with all the failed field validations. The content
+// templates might use the built-in and the configured template variables.
+func (b *Banner) Validate(templateVars []string) error {
+	verr := &errs.ValidationError{}
+
+	switch {
+	case len(b.TagIDs) == 0:
+		verr.Add("tag_ids", errs.ValidationRequired, "must contain at least one tag")
+	case len(b.TagIDs) > MaxTagIDs:
+		verr.Add("tag_ids", errs.ValidationTooMany, fmt.Sprintf("must contain at most %d tags", MaxTagIDs))
+	}
+
+	seen := make(map[int]struct{}, len(b.TagIDs))
+	for i, tagID := range b.TagIDs {
+		field := fmt.Sprintf("tag_ids[%d]", i)
+		if tagID < 1 {
+			verr.Add(field, errs.ValidationPositive, "must be positive")
+			continue
+		}
+		if _, ok := seen[tagID]; ok {
+			verr.Add(field, errs.ValidationDuplicate, fmt.Sprintf("tag %d is duplicated", tagID))
+			continue
+		}
+		seen[tagID] = struct{}{}
+	}
+
+	switch {
+	case b.FeatureID == 0:
+		verr.Add("feature_id", errs.ValidationRequired, "is required")
+	case b.FeatureID < 0:
+		verr.Add("feature_id", errs.ValidationPositive, "must be positive")
+	}
+
+	if b.Weight < 0 || b.Weight > MaxWeight {
+		verr.Add("weight", errs.ValidationInvalid, fmt.Sprintf("must be from 0 to %d", MaxWeight))
+	}
+
+	if b.Priority < 0 || b.Priority > MaxPriority {
+		verr.Add("priority", errs.ValidationInvalid, fmt.Sprintf("must be from 0 to %d", MaxPriority))
+	}
+
+	if b.Cap.Impressions < 0 || b.Cap.Impressions > MaxCapImpressions {
+		verr.Add("frequency_cap.impressions", errs.ValidationInvalid, fmt.Sprintf("must be from 0 to %d", MaxCapImpressions))
+	}
+	switch {
+	case b.Cap.Window < 0 || b.Cap.Window > MaxCapWindow:
+		verr.Add("frequency_cap.window", errs.ValidationInvalid, fmt.Sprintf("must be from 0 to %d seconds", MaxCapWindow))
+	case b.Cap.Impressions > 0 && b.Cap.Window == 0:
+		verr.Add("frequency_cap.window", errs.ValidationRequired, "is required for the limited impressions")
+	case b.Cap.Impressions == 0 && b.Cap.Window > 0:
+		verr.Add("frequency_cap.impressions", errs.ValidationRequired, "is required for the window")
+	}
+
+	if b.Content == nil {
+		verr.Add("content", errs.ValidationRequired, "is required")
+	} else {
+		validateContent(verr, "content", *b.Content, templateVars)
+	}
+
+	if len(b.Locales) > MaxLocales {
+		verr.Add("localized_content", errs.ValidationTooMany, fmt.Sprintf("must contain at most %d locales", MaxLocales))
+	}
+	locales := make([]string, 0, len(b.Locales))
+	for locale := range b.Locales {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	seenLocales := make(map[string]struct{}, len(locales))
+	for _, locale := range locales {
+		field := "localized_content." + locale
+		if !ValidLocale(locale) {
+			verr.Add(field, errs.ValidationInvalid, "locale must be a language tag, e.g. en or en-US")
+			continue
+		}
+		if _, ok := seenLocales[strings.ToLower(locale)]; ok {
+			verr.Add(field, errs.ValidationDuplicate, fmt.Sprintf("locale %s is duplicated", locale))
+			continue
+		}
+		seenLocales[strings.ToLower(locale)] = struct{}{}
+
+		validateContent(verr, field, b.Locales[locale], templateVars)
+	}
+
+	return verr.Err()
+}
+
+// validateContent adds the failed validations of the content fields to the validation error.
+func validateContent(verr *errs.ValidationError, field string, content Content, templateVars []string) {
+	if len(content) > MaxContentFields {
+		verr.Add(field, errs.ValidationTooMany, fmt.Sprintf("must contain at most %d fields", MaxContentFields))
+	}
+	keys := make([]string, 0, len(content))
+	for k := range content {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := content[k]
+		if k == "" {
+			verr.Add(field, errs.ValidationRequired, "field names must not be empty")
+		}
+		if len(k) > MaxContentKeyLength {
+			verr.Add(field+"."+k, errs.ValidationTooLong, fmt.Sprintf("name must be at most %d bytes", MaxContentKeyLength))
+		}
+		if len(v) > MaxContentValueLength {
+			verr.Add(field+"."+k, errs.ValidationTooLong, fmt.Sprintf("must be at most %d bytes", MaxContentValueLength))
+		}
+		if isTemplate(v) {
+			if _, err := ParseTemplate(v, templateVars); err != nil {
+				verr.Add(field+"."+k, errs.ValidationInvalid, "template "+err.Error())
+			}
+		}
+	}
+}
+
+// Outranks reports whether the banner is preferred for users over the other one
+// matching the same feature and tags: the banner with the higher priority wins,
+// then the recently updated one, then the one with the greater ID.
+func (b *Banner) Outranks(other *Banner) bool {
+	if b.Priority != other.Priority {
+		return b.Priority > other.Priority
+	}
+	if !b.UpdatedAt.Equal(other.UpdatedAt) {
+		return b.UpdatedAt.After(other.UpdatedAt)
+	}
+	return b.ID > other.ID
+}
+
+// IsFallback reports whether the banner is the default banner of the feature
+// returned instead of the banner of the requested tags.
+func (b *Banner) IsFallback(tagIDs []int) bool {
+	if !b.IsDefault {
+		return false
+	}
+	for _, tagID := range tagIDs {
+		if slices.Contains(b.TagIDs, tagID) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the copy of the banner with the patch applied.
+func (p *Patch) Apply(b *Banner) (*Banner, error) {
+	patched := *b
+
+	if p.TagIDs != nil {
+		patched.TagIDs = append([]int(nil), *p.TagIDs...)
+	} else {
+		patched.TagIDs = append([]int(nil), b.TagIDs...)
+	}
+	if p.FeatureID != nil {
+		patched.FeatureID = *p.FeatureID
+	}
+	if p.IsActive != nil {
+		patched.IsActive = *p.IsActive
+	}
+	if p.Weight != nil {
+		patched.Weight = *p.Weight
+	}
+	if p.Priority != nil {
+		patched.Priority = *p.Priority
+	}
+	if p.IsDefault != nil {
+		patched.IsDefault = *p.IsDefault
+	}
+	if p.Cap != nil {
+		patched.Cap = *p.Cap
+	}
+
+	patched.Locales = make(Locales, len(b.Locales))
+	for locale, content := range b.Locales {
+		patched.Locales[locale] = content
+	}
+	for locale, content := range p.Locales {
+		for k := range patched.Locales {
+			if strings.EqualFold(k, locale) {
+				delete(patched.Locales, k)
+			}
+		}
+		if content != nil {
+			patched.Locales[locale] = *content
+		}
+	}
+
+	content, err := mergeContent(b.Content, p.Content)
+	if err != nil {
+		return nil, fmt.Errorf("Apply: merge content failed %w", err)
+	}
+	patched.Content = content
+
+	return &patched, nil
+}
+
+// mergeContent applies the JSON Merge Patch to the banner content. The null patch
+// removes the content, the null member of the patch object removes the content key.
+func mergeContent(target *Content, patch json.RawMessage) (*Content, error) {
+	merged := Content{}
+	if target != nil {
+		for k, v := range *target {
+			merged[k] = v
+		}
+	}
+
+	if len(patch) == 0 {
+		if target == nil {
+			return nil, nil
+		}
+		return &merged, nil
+	}
+
+	if bytes.Equal(bytes.TrimSpace(patch), []byte("null")) {
+		return nil, nil
+	}
+
+	var members map[string]*string
+	err := json.Unmarshal(patch, &members)
+	if err != nil {
+		verr := &errs.ValidationError{}
+		verr.Add("content", errs.ValidationInvalidType, "must be an object with string values")
+		return nil, fmt.Errorf("mergeContent: unmarshal patch failed %w", verr)
+	}
+
+	for k, v := range members {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = *v
+	}
+
+	return &merged, nil
+}
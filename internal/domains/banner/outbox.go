@@ -0,0 +1,200 @@
+package banner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// relayBatchSize is the number of the outbox events claimed at once.
+const relayBatchSize = 100
+
+// DefaultRelayLease is the time, after which the claimed events are published again,
+// if they have not been published, e.g. the sink failed or the server stopped.
+const DefaultRelayLease = 30 * time.Second
+
+// OutboxEvent contains the banner change stored into the outbox in the same transaction
+// as the change. The key identifies the change, so the sinks might skip the events
+// published more than once. Before and after are the banner states, nil if there is no state.
+type OutboxEvent struct {
+	ID        int64           `json:"id"`
+	Key       string          `json:"key"`
+	Action    string          `json:"action"`
+	BannerID  int             `json:"banner_id"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Outbox describes methods for reading the banner change events from the storage.
+type Outbox interface {
+	ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*OutboxEvent, error)
+	DeleteOutboxEvents(ctx context.Context, ids []int64) error
+}
+
+// Sink describes methods for publishing the banner change events from the outbox.
+type Sink interface {
+	Publish(ctx context.Context, e *OutboxEvent) error
+}
+
+// NewOutboxEvent returns the outbox event of the banner change. The key is built from
+// the banner ID and the banner version after the change, which is unique for every change.
+func NewOutboxEvent(action string, bannerID int, version int, before *Banner, after *Banner) (*OutboxEvent, error) {
+	e := &OutboxEvent{
+		Key:      fmt.Sprintf("banner-%d-%d", bannerID, version),
+		Action:   action,
+		BannerID: bannerID,
+	}
+
+	var err error
+	if before != nil {
+		e.Before, err = json.Marshal(before)
+		if err != nil {
+			return nil, fmt.Errorf("NewOutboxEvent: marshal state before failed %w", err)
+		}
+	}
+	if after != nil {
+		e.After, err = json.Marshal(after)
+		if err != nil {
+			return nil, fmt.Errorf("NewOutboxEvent: marshal state after failed %w", err)
+		}
+	}
+
+	return e, nil
+}
+
+// ChangeAction returns the action of the banner update.
+func ChangeAction(before *Banner, after *Banner) string {
+	if before.IsActive == after.IsActive {
+		return audit.ActionUpdated
+	}
+	if after.IsActive {
+		return audit.ActionActivated
+	}
+	return audit.ActionDeactivated
+}
+
+// notifierSink publishes the banner change events to the notifier.
+type notifierSink struct {
+	notifier Notifier
+}
+
+// NewNotifierSink returns the sink publishing the banner change events to the notifier.
+func NewNotifierSink(n Notifier) Sink {
+	return &notifierSink{notifier: n}
+}
+
+// Publish sends the event to the notifier.
+func (s *notifierSink) Publish(ctx context.Context, e *OutboxEvent) error {
+	var before, after any
+	if e.Before != nil {
+		before = e.Before
+	}
+	if e.After != nil {
+		after = e.After
+	}
+
+	err := s.notifier.Notify(ctx, e.Key, e.Action, e.BannerID, before, after)
+	if err != nil {
+		return fmt.Errorf("Publish: notify failed %w", err)
+	}
+
+	return nil
+}
+
+// Relay contains objects for publishing the banner change events from the outbox.
+type Relay struct {
+	outbox Outbox
+	lease  time.Duration
+	sinks  []Sink
+}
+
+// NewRelay returns new relay publishing the outbox events to the sinks. The events,
+// which have not been published, are claimed again after the lease.
+func NewRelay(ctx context.Context, outbox Outbox, lease time.Duration, sinks ...Sink) *Relay {
+	return &Relay{
+		outbox: outbox,
+		lease:  lease,
+		sinks:  sinks,
+	}
+}
+
+// Run publishes the outbox events to the sinks with requested interval. Every event
+// is published to all the sinks at least once: the event is removed from the outbox only
+// after all the sinks published it, otherwise it is published to all of them again.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// The full batch means that more events might be waiting
+		if r.relay(ctx) == relayBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relay claims the batch of the outbox events, publishes them in order until the first
+// failure and removes the published events from the outbox. It returns the number of
+// the claimed events.
+func (r *Relay) relay(ctx context.Context) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	events, err := r.outbox.ClaimOutboxEvents(ctx, time.Now(), r.lease, relayBatchSize)
+	if err != nil {
+		logger.Log.Error("relay: claim outbox events failed",
+			zap.Error(err))
+		return 0
+	}
+	if len(events) == 0 {
+		return 0
+	}
+
+	published := make([]int64, 0, len(events))
+	for _, e := range events {
+		err = r.publish(ctx, e)
+		if err != nil {
+			logger.Log.Error("relay: publish outbox event failed",
+				zap.Int64("event_id", e.ID),
+				zap.String("key", e.Key),
+				zap.Error(err))
+			break
+		}
+		published = append(published, e.ID)
+	}
+
+	if len(published) != 0 {
+		err = r.outbox.DeleteOutboxEvents(ctx, published)
+		if err != nil {
+			logger.Log.Error("relay: delete published outbox events failed",
+				zap.Error(err))
+		}
+	}
+
+	return len(events)
+}
+
+// publish publishes the event to all the sinks.
+func (r *Relay) publish(ctx context.Context, e *OutboxEvent) error {
+	for _, s := range r.sinks {
+		err := s.Publish(ctx, e)
+		if err != nil {
+			return fmt.Errorf("publish: publish to sink failed %w", err)
+		}
+	}
+
+	return nil
+}
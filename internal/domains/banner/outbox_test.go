@@ -0,0 +1,83 @@
+package banner_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sink stores the keys of the published events and fails
+// until the failures number is reached.
+type sink struct {
+	sync.Mutex
+	failures int
+	keys     []string
+}
+
+// Publish implements the banner.Sink interface.
+func (s *sink) Publish(ctx context.Context, e *banner.OutboxEvent) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink is unavailable")
+	}
+	s.keys = append(s.keys, e.Key)
+
+	return nil
+}
+
+// published returns the keys of the published events.
+func (s *sink) published() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]string(nil), s.keys...)
+}
+
+func TestRelay_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	repo := repository.NewBannerMemoryRepository(ctx, nil)
+
+	stored, err := repo.CreateBanner(ctx, &banner.Banner{TagIDs: []int{1}, FeatureID: 1, Content: &banner.Content{}, IsActive: true})
+	require.NoError(t, err)
+	err = repo.DeleteBannerByID(ctx, stored.ID, 0)
+	require.NoError(t, err)
+
+	first := &sink{}
+	second := &sink{failures: 1}
+	relay := banner.NewRelay(ctx, repo, 10*time.Millisecond, first, second)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		relay.Run(ctx, 5*time.Millisecond)
+		wg.Done()
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// The event failed in the second sink is published to all the sinks again
+	require.Eventually(t, func() bool {
+		return len(second.published()) == 2
+	}, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"banner-1-1", "banner-1-1", "banner-1-2"}, first.published())
+	assert.Equal(t, []string{"banner-1-1", "banner-1-2"}, second.published())
+
+	// Published events are removed from the outbox
+	require.Eventually(t, func() bool {
+		events, err := repo.ClaimOutboxEvents(ctx, time.Now().Add(time.Hour), 0, 10)
+		require.NoError(t, err)
+		return len(events) == 0
+	}, 2*time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/utils"
+)
+
+// systemRole is the role of the banner changes made without the user role in context.
+const systemRole = "system"
+
+// insertAuditEntryQuery is the query for storing the banner change into the audit log.
+const insertAuditEntryQuery = `INSERT INTO audit_log (banner_id, actor, role, action, request_id, before, after, diff)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+// newAuditEntry returns the audit log entry of the banner change from the outbox event
+// made by the actor with the role from context.
+func newAuditEntry(ctx context.Context, e *banner.OutboxEvent) (*audit.Entry, error) {
+	role, err := utils.GetUserRoleFromContext(ctx)
+	if err != nil {
+		role = systemRole
+	}
+
+	entry, err := audit.NewEntry(audit.Actor(ctx, role), role, middleware.GetReqID(ctx), e.Action, e.BannerID, e.Before, e.After)
+	if err != nil {
+		return nil, fmt.Errorf("newAuditEntry: build entry failed %w", err)
+	}
+
+	return entry, nil
+}
+
+// recordChange stores the banner change into the outbox and the audit log using the transaction,
+// so the change is never stored without its event and audit log entry.
+func recordChange(ctx context.Context, q querier, action string, id int, version int, before *banner.Banner, after *banner.Banner) error {
+	event, err := createOutboxEvent(ctx, q, action, id, version, before, after)
+	if err != nil {
+		return fmt.Errorf("recordChange: create outbox event failed %w", err)
+	}
+
+	e, err := newAuditEntry(ctx, event)
+	if err != nil {
+		return fmt.Errorf("recordChange: build audit log entry failed %w", err)
+	}
+	diff, err := json.Marshal(e.Diff)
+	if err != nil {
+		return fmt.Errorf("recordChange: marshal diff failed %w", err)
+	}
+
+	_, err = q.ExecContext(ctx, insertAuditEntryQuery, e.BannerID, e.Actor, e.Role, e.Action, e.RequestID,
+		jsonArgument(e.Before), jsonArgument(e.After), string(diff))
+	if err != nil {
+		return fmt.Errorf("recordChange: insert audit log entry failed %w", err)
+	}
+
+	return nil
+}
+
+// recordPoolChange stores the banner change into the outbox and the audit log using
+// the pgx transaction, so the change is never stored without its event and audit log entry.
+func recordPoolChange(ctx context.Context, q poolQuerier, action string, id int, version int, before *banner.Banner, after *banner.Banner) error {
+	event, err := createPoolOutboxEvent(ctx, q, action, id, version, before, after)
+	if err != nil {
+		return fmt.Errorf("recordPoolChange: create outbox event failed %w", err)
+	}
+
+	e, err := newAuditEntry(ctx, event)
+	if err != nil {
+		return fmt.Errorf("recordPoolChange: build audit log entry failed %w", err)
+	}
+	diff, err := json.Marshal(e.Diff)
+	if err != nil {
+		return fmt.Errorf("recordPoolChange: marshal diff failed %w", err)
+	}
+
+	_, err = q.Exec(ctx, insertAuditEntryQuery, e.BannerID, e.Actor, e.Role, e.Action, e.RequestID,
+		jsonArgument(e.Before), jsonArgument(e.After), string(diff))
+	if err != nil {
+		return fmt.Errorf("recordPoolChange: insert audit log entry failed %w", err)
+	}
+
+	return nil
+}
@@ -3,6 +3,9 @@ package repository
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +19,9 @@ type Cache struct {
 	defaultExpiration time.Duration
 	cleanupInterval   time.Duration
 	banners           map[bannerKey]cacheBanner
+	variants          map[bannerKey]cacheVariants
+	filters           map[filterKey]cacheBanner
+	defaults          map[int]cacheBanner
 }
 
 // cacheBanner contains data for store banner in cache.
@@ -24,23 +30,99 @@ type cacheBanner struct {
 	expires time.Time
 }
 
+// cacheVariants contains data for store weighted banner variants in cache.
+type cacheVariants struct {
+	variants []*banner.Banner
+	expires  time.Time
+}
+
 // bannerKey contains data for unique banner search.
 type bannerKey struct {
 	featureID int
 	tagID     int
 }
 
+// filterKey contains data for unique search of the banner by feature and several tags.
+type filterKey struct {
+	featureID int
+	tagIDs    string
+}
+
+// newFilterKey returns the key of the feature and tags, which does not depend on the tags order.
+func newFilterKey(featureID int, tagIDs []int) filterKey {
+	sorted := slices.Clone(tagIDs)
+	slices.Sort(sorted)
+
+	tags := make([]string, len(sorted))
+	for i, tagID := range sorted {
+		tags[i] = strconv.Itoa(tagID)
+	}
+
+	return filterKey{
+		featureID: featureID,
+		tagIDs:    strings.Join(tags, ","),
+	}
+}
+
 // NewBannerCache creates and returns new banner cache.
 func NewBannerCache(ctx context.Context, defaultExpiration time.Duration, cleanupInterval time.Duration) *Cache {
 	return &Cache{
 		defaultExpiration: defaultExpiration,
 		cleanupInterval:   cleanupInterval,
 		banners:           make(map[bannerKey]cacheBanner, 0),
+		variants:          make(map[bannerKey]cacheVariants, 0),
+		filters:           make(map[filterKey]cacheBanner, 0),
+		defaults:          make(map[int]cacheBanner, 0),
+	}
+}
+
+// GetBannerByFilter finds and returns requested banner content by filter. The banner
+// of the single tag is cached for the feature and tag pair, the best banner of several
+// tags is cached for the whole filter.
+func (c *Cache) GetBannerByFilter(ctx context.Context, featureID int, tagIDs []int) (*banner.Banner, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	var cb cacheBanner
+	var ok bool
+	if len(tagIDs) == 1 {
+		cb, ok = c.banners[bannerKey{featureID: featureID, tagID: tagIDs[0]}]
+	} else {
+		cb, ok = c.filters[newFilterKey(featureID, tagIDs)]
+	}
+	if !ok {
+		return nil, fmt.Errorf("GetBannerByFilter: banners with requested tag not found %w", errs.ErrBannerInCacheNotFound)
+	}
+
+	if time.Now().After(cb.expires) {
+		return nil, fmt.Errorf("GetBannerByFilter: banner content usage expired %w", errs.ErrBannerExpired)
+	}
+
+	return cb.banner, nil
+}
+
+// CreateBannerByFilter stores the banner read from the storage by filter in cache.
+func (c *Cache) CreateBannerByFilter(ctx context.Context, featureID int, tagIDs []int, banner *banner.Banner) error {
+	c.Lock()
+	defer c.Unlock()
+
+	expires := time.Now().Add(c.defaultExpiration)
+	cb := cacheBanner{
+		banner:  cachedCopy(banner, expires),
+		expires: expires,
 	}
+	if len(tagIDs) == 1 {
+		c.banners[bannerKey{featureID: featureID, tagID: tagIDs[0]}] = cb
+		return nil
+	}
+	c.filters[newFilterKey(featureID, tagIDs)] = cb
+
+	return nil
 }
 
-// GetBannerByFilter finds and returns requested banner content by filter.
-func (c *Cache) GetBannerByFilter(ctx context.Context, featureID int, tagID int) (*banner.Banner, error) {
+// GetBannerVariants finds and returns the weighted variants of the feature and tag pair.
+// The empty list means the pair has no variants.
+func (c *Cache) GetBannerVariants(ctx context.Context, featureID int, tagID int) ([]*banner.Banner, error) {
 	c.RLock()
 	defer c.RUnlock()
 
@@ -49,36 +131,147 @@ func (c *Cache) GetBannerByFilter(ctx context.Context, featureID int, tagID int)
 		tagID:     tagID,
 	}
 
-	cb, ok := c.banners[key]
+	cv, ok := c.variants[key]
 	if !ok {
-		return nil, fmt.Errorf("GetBannerByFilter: banners with requested tag not found %w", errs.ErrBannerInCacheNotFound)
+		return nil, fmt.Errorf("GetBannerVariants: variants with requested tag not found %w", errs.ErrBannerInCacheNotFound)
+	}
+
+	if time.Now().After(cv.expires) {
+		return nil, fmt.Errorf("GetBannerVariants: variants usage expired %w", errs.ErrBannerExpired)
+	}
+
+	return cv.variants, nil
+}
+
+// CreateBannerVariants stores the weighted variants of the feature and tag pair in cache.
+func (c *Cache) CreateBannerVariants(ctx context.Context, featureID int, tagID int, variants []*banner.Banner) error {
+	c.Lock()
+	defer c.Unlock()
+
+	key := bannerKey{
+		featureID: featureID,
+		tagID:     tagID,
+	}
+
+	expires := time.Now().Add(c.defaultExpiration)
+	cached := make([]*banner.Banner, 0, len(variants))
+	for _, v := range variants {
+		cached = append(cached, cachedCopy(v, expires))
+	}
+	c.variants[key] = cacheVariants{
+		variants: cached,
+		expires:  expires,
+	}
+
+	return nil
+}
+
+// GetDefaultBanner finds and returns the default banner of the feature.
+// The nil banner means the feature has no default banner.
+func (c *Cache) GetDefaultBanner(ctx context.Context, featureID int) (*banner.Banner, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	cb, ok := c.defaults[featureID]
+	if !ok {
+		return nil, fmt.Errorf("GetDefaultBanner: default banner of requested feature not found %w", errs.ErrBannerInCacheNotFound)
 	}
 
 	if time.Now().After(cb.expires) {
-		return nil, fmt.Errorf("GetBannerByFilter: banner content usage expired %w", errs.ErrBannerExpired)
+		return nil, fmt.Errorf("GetDefaultBanner: default banner usage expired %w", errs.ErrBannerExpired)
 	}
 
 	return cb.banner, nil
 }
 
+// CreateDefaultBanner stores the default banner of the feature read from the storage in cache.
+// The nil banner is stored for the feature without default banner.
+func (c *Cache) CreateDefaultBanner(ctx context.Context, featureID int, banner *banner.Banner) error {
+	c.Lock()
+	defer c.Unlock()
+
+	expires := time.Now().Add(c.defaultExpiration)
+	c.defaults[featureID] = cacheBanner{
+		banner:  cachedCopy(banner, expires),
+		expires: expires,
+	}
+
+	return nil
+}
+
 // CreateBanner creates new banner in cache.
 func (c *Cache) CreateBanner(ctx context.Context, banner *banner.Banner) error {
 	c.Lock()
 	defer c.Unlock()
 
-	for _, tagID := range banner.TagIDs {
+	c.storeBanner(banner)
+
+	return nil
+}
+
+// CreateBanners creates the banners in cache at once.
+func (c *Cache) CreateBanners(ctx context.Context, banners []*banner.Banner) error {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, b := range banners {
+		c.storeBanner(b)
+	}
+
+	return nil
+}
+
+// storeBanner replaces the cached banners of its tags, which the banner outranks,
+// and drops the variants, filters and defaults the banner might change, the lock must be held.
+// The pairs not cached yet are left for reading from the storage, since the stored
+// banner might outrank the new one. The pairs the banner left or does not win
+// anymore are dropped.
+func (c *Cache) storeBanner(b *banner.Banner) {
+	c.dropVariants(b.ID)
+	c.dropFilters(b.ID, b.FeatureID)
+	c.dropDefaults(b.ID)
+	if b.IsDefault {
+		delete(c.defaults, b.FeatureID)
+	}
+	for k, cb := range c.banners {
+		if cb.banner.ID != b.ID {
+			continue
+		}
+		if !b.IsActive || k.featureID != b.FeatureID || !slices.Contains(b.TagIDs, k.tagID) || cb.banner.Outranks(b) {
+			delete(c.banners, k)
+		}
+	}
+
+	expires := time.Now().Add(c.defaultExpiration)
+	for _, tagID := range b.TagIDs {
 		key := bannerKey{
-			featureID: banner.FeatureID,
+			featureID: b.FeatureID,
 			tagID:     tagID,
 		}
+		delete(c.variants, key)
+
+		cb, ok := c.banners[key]
+		if !ok || !b.IsActive || (cb.banner.ID != b.ID && cb.banner.Outranks(b)) {
+			continue
+		}
 
 		c.banners[key] = cacheBanner{
-			banner:  banner,
-			expires: banner.UpdatedAt.Add(c.defaultExpiration),
+			banner:  cachedCopy(b, expires),
+			expires: expires,
 		}
 	}
+}
 
-	return nil
+// cachedCopy returns the copy of the banner with the expiration of its cache entry,
+// so the clients might reuse the banner got from cache until the entry expires.
+func cachedCopy(b *banner.Banner, expires time.Time) *banner.Banner {
+	if b == nil {
+		return nil
+	}
+
+	cached := *b
+	cached.CachedUntil = expires
+	return &cached
 }
 
 // DeleteBanner deletes banner from cache.
@@ -92,6 +285,9 @@ func (c *Cache) DeleteBanner(ctx context.Context, id int, featureID int, tagID i
 				delete(c.banners, k)
 			}
 		}
+		c.dropVariants(id)
+		c.dropFilters(id, 0)
+		c.dropDefaults(id)
 
 		return nil
 	}
@@ -100,6 +296,7 @@ func (c *Cache) DeleteBanner(ctx context.Context, id int, featureID int, tagID i
 		featureID: featureID,
 		tagID:     tagID,
 	}
+	delete(c.variants, key)
 
 	_, ok := c.banners[key]
 	if !ok {
@@ -111,29 +308,54 @@ func (c *Cache) DeleteBanner(ctx context.Context, id int, featureID int, tagID i
 	return nil
 }
 
+// dropVariants deletes the variants containing the banner, the lock must be held.
+func (c *Cache) dropVariants(id int) {
+	for k, cv := range c.variants {
+		for _, v := range cv.variants {
+			if v.ID == id {
+				delete(c.variants, k)
+				break
+			}
+		}
+	}
+}
+
+// dropFilters deletes the filters containing the banner or the feature, the lock must be held.
+func (c *Cache) dropFilters(id int, featureID int) {
+	for k, cb := range c.filters {
+		if (id != 0 && cb.banner.ID == id) || (featureID != 0 && k.featureID == featureID) {
+			delete(c.filters, k)
+		}
+	}
+}
+
+// dropDefaults deletes the default banner entries of the banner, the lock must be held.
+func (c *Cache) dropDefaults(id int) {
+	for k, cb := range c.defaults {
+		if cb.banner != nil && cb.banner.ID == id {
+			delete(c.defaults, k)
+		}
+	}
+}
+
 // GarbageCollect cleans banners cache with requested interval.
 func (c *Cache) GarbageCollect(ctx context.Context) {
 	ticker := time.NewTicker(c.cleanupInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if c.banners == nil {
-				return
-			}
-
 			keys := c.expiredKeys()
 			if len(keys) != 0 {
 				c.clearBanners(keys)
 			}
-
-		default:
-			continue
+			c.clearVariants()
+			c.clearFilters()
 		}
 	}
-
 }
 
 // expiredKeys returns list of expired keys.
@@ -159,3 +381,32 @@ func (c *Cache) clearBanners(keys []bannerKey) {
 		delete(c.banners, k)
 	}
 }
+
+// clearVariants deletes expired variants.
+func (c *Cache) clearVariants() {
+	c.Lock()
+	defer c.Unlock()
+
+	for k, cv := range c.variants {
+		if time.Now().After(cv.expires) {
+			delete(c.variants, k)
+		}
+	}
+}
+
+// clearFilters deletes expired filters and default banners.
+func (c *Cache) clearFilters() {
+	c.Lock()
+	defer c.Unlock()
+
+	for k, cb := range c.filters {
+		if time.Now().After(cb.expires) {
+			delete(c.filters, k)
+		}
+	}
+	for k, cb := range c.defaults {
+		if time.Now().After(cb.expires) {
+			delete(c.defaults, k)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_GarbageCollect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := repository.NewBannerCache(ctx, 100*time.Millisecond, 10*time.Millisecond)
+	go c.GarbageCollect(ctx)
+
+	b := &banner.Banner{ID: 1, FeatureID: 1, TagIDs: []int{1}, IsActive: true}
+	require.NoError(t, c.CreateBannerByFilter(ctx, 1, []int{1}, b))
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, c.CreateBannerVariants(ctx, 1, 1, []*banner.Banner{b}))
+
+	// The banner of the pair is collected, the later cached variants of the pair are kept.
+	time.Sleep(60 * time.Millisecond)
+	_, err := c.GetBannerByFilter(ctx, 1, []int{1})
+	assert.ErrorIs(t, err, errs.ErrBannerInCacheNotFound)
+	variants, err := c.GetBannerVariants(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, variants, 1)
+	assert.Equal(t, b.ID, variants[0].ID)
+	assert.WithinDuration(t, time.Now().Add(40*time.Millisecond), variants[0].CachedUntil, 40*time.Millisecond)
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = c.GetBannerVariants(ctx, 1, 1)
+	assert.ErrorIs(t, err, errs.ErrBannerInCacheNotFound)
+}
+
+func TestCache_CreateBannerExpiration(t *testing.T) {
+	ctx := context.Background()
+	c := repository.NewBannerCache(ctx, time.Minute, time.Minute)
+
+	b := &banner.Banner{ID: 1, FeatureID: 1, TagIDs: []int{1}, IsActive: true, UpdatedAt: time.Now().Add(-time.Hour)}
+	require.NoError(t, c.CreateBannerByFilter(ctx, 1, []int{1}, b))
+
+	// The updated banner expires after the default expiration from now, whenever it was updated
+	updated := *b
+	updated.Version = 2
+	require.NoError(t, c.CreateBanner(ctx, &updated))
+
+	got, err := c.GetBannerByFilter(ctx, 1, []int{1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Version)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), got.CachedUntil, time.Second)
+}
@@ -0,0 +1,66 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository/repotest"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/infra/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) banner.Repository {
+		return repository.NewBannerMemoryRepository(context.Background(), nil)
+	})
+}
+
+// resetTables empties the banners tables and registers
+// the features and tags referenced by the tests.
+const resetTables = `TRUNCATE banners, banner_outbox, features, tags RESTART IDENTITY CASCADE;
+INSERT INTO features (id, name) SELECT i, 'feature ' || i FROM generate_series(1, 2000) AS i;
+INSERT INTO tags (id, name) SELECT i, 'tag ' || i FROM generate_series(1, 2000) AS i;
+INSERT INTO tags (id, name) VALUES (2147483647, 'tag 2147483647');`
+
+// testDSN returns the database DSN for the integration tests
+// and skips the test if the database is not available.
+func testDSN(t *testing.T) string {
+	t.Helper()
+
+	requireDatabase(t)
+
+	return testDatabaseDSN
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := database.Init(ctx, testDSN(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repotest.Run(t, func(t *testing.T) banner.Repository {
+		_, err := db.ExecContext(ctx, resetTables)
+		require.NoError(t, err)
+
+		return repository.NewBannerRepository(ctx, db)
+	})
+}
+
+func TestPoolRepository_Conformance(t *testing.T) {
+	ctx := context.Background()
+
+	pool, err := database.InitPool(ctx, &config.Config{DSN: testDSN(t)}, repository.PrepareStatements)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	repotest.Run(t, func(t *testing.T) banner.Repository {
+		_, err := pool.Exec(ctx, resetTables)
+		require.NoError(t, err)
+
+		return repository.NewBannerPoolRepository(ctx, pool)
+	})
+}
@@ -0,0 +1,88 @@
+package repository_test
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+)
+
+// testDatabaseDSN is the DSN of the database for the integration tests,
+// empty if the database is not available.
+var testDatabaseDSN string
+
+// testDatabaseErr explains why the database for the integration tests is not available.
+var testDatabaseErr error
+
+// requiredEnv is the environment variable, which makes the integration tests fail
+// instead of being skipped, if the database is not available, e.g. in CI.
+const requiredEnv = "TEST_DATABASE_REQUIRED"
+
+// embeddedPort is the port of the embedded database for the integration tests.
+const embeddedPort = 54329
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	os.Exit(run(m))
+}
+
+// run prepares the database for the integration tests and runs the tests.
+// The database from TEST_DATABASE_DSN is used if it is set, otherwise
+// the embedded PostgreSQL is started unless the tests are short.
+func run(m *testing.M) int {
+	testDatabaseDSN = os.Getenv("TEST_DATABASE_DSN")
+	if testDatabaseDSN != "" {
+		return m.Run()
+	}
+	if testing.Short() {
+		testDatabaseErr = fmt.Errorf("TEST_DATABASE_DSN is not set in short mode")
+		return m.Run()
+	}
+
+	tmp, err := os.MkdirTemp("", "banners-postgres")
+	if err != nil {
+		testDatabaseErr = fmt.Errorf("create temporary directory failed %w", err)
+		return m.Run()
+	}
+	defer os.RemoveAll(tmp)
+
+	cfg := embeddedpostgres.DefaultConfig().
+		Version(embeddedpostgres.V16).
+		Port(embeddedPort).
+		RuntimePath(filepath.Join(tmp, "runtime")).
+		DataPath(filepath.Join(tmp, "data")).
+		StartTimeout(time.Minute).
+		Logger(io.Discard)
+
+	db := embeddedpostgres.NewDatabase(cfg)
+	err = db.Start()
+	if err != nil {
+		testDatabaseErr = fmt.Errorf("start embedded postgres failed %w", err)
+		return m.Run()
+	}
+	defer db.Stop()
+
+	testDatabaseDSN = cfg.GetConnectionURL() + "?sslmode=disable"
+
+	return m.Run()
+}
+
+// requireDatabase skips the test, if the database for the integration tests
+// is not available, or fails it, if the database is required.
+func requireDatabase(tb testing.TB) {
+	tb.Helper()
+
+	if testDatabaseDSN != "" {
+		return
+	}
+	if required, _ := strconv.ParseBool(os.Getenv(requiredEnv)); required {
+		tb.Fatalf("database is required by %s, but not available: %s", requiredEnv, testDatabaseErr)
+	}
+	tb.Skipf("database is not available: %s", testDatabaseErr)
+}
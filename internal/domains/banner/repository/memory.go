@@ -0,0 +1,489 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+)
+
+// MemoryRepository contains banners stored in memory
+// for tests and local development.
+type MemoryRepository struct {
+	sync.RWMutex
+	lastID      int
+	banners     map[int]*banner.Banner
+	outbox      []outboxEntry
+	lastEventID int64
+	audit       audit.Repository
+}
+
+// outboxEntry contains the outbox event stored in memory with its claim time.
+type outboxEntry struct {
+	event       banner.OutboxEvent
+	lockedUntil time.Time
+}
+
+// NewBannerMemoryRepository returns new in-memory banners repository object. The banner
+// changes are recorded into the audit log repository under the banners lock, if it is set.
+func NewBannerMemoryRepository(ctx context.Context, auditRepo audit.Repository) *MemoryRepository {
+	return &MemoryRepository{
+		banners: make(map[int]*banner.Banner, 0),
+		outbox:  make([]outboxEntry, 0),
+		audit:   auditRepo,
+	}
+}
+
+// copyBanner returns the deep copy of the banner, so stored banners
+// couldn't be changed outside the repository.
+func copyBanner(b *banner.Banner) *banner.Banner {
+	c := *b
+	c.TagIDs = slices.Clone(b.TagIDs)
+	if b.DeletedAt != nil {
+		deletedAt := *b.DeletedAt
+		c.DeletedAt = &deletedAt
+	}
+	if b.Content != nil {
+		content := make(banner.Content, len(*b.Content))
+		for k, v := range *b.Content {
+			content[k] = v
+		}
+		c.Content = &content
+	}
+	if b.Locales != nil {
+		c.Locales = make(banner.Locales, len(b.Locales))
+		for locale, content := range b.Locales {
+			c.Locales[locale] = maps.Clone(content)
+		}
+	}
+
+	return &c
+}
+
+// now returns the current time rounded the same way as the database does.
+func now() time.Time {
+	return time.Now().Round(time.Microsecond)
+}
+
+// sortedBanners returns the stored banners matched by filter
+// sorted by update time from newest to oldest.
+func (r *MemoryRepository) sortedBanners(featureID int, tagID int, onlyActive bool, deleted bool) []*banner.Banner {
+	list := make([]*banner.Banner, 0)
+	for _, b := range r.banners {
+		if (b.DeletedAt != nil) != deleted {
+			continue
+		}
+		if featureID != 0 && b.FeatureID != featureID {
+			continue
+		}
+		if tagID != 0 && !slices.Contains(b.TagIDs, tagID) {
+			continue
+		}
+		if onlyActive && !b.IsActive {
+			continue
+		}
+		list = append(list, b)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].UpdatedAt.Equal(list[j].UpdatedAt) {
+			return list[i].ID > list[j].ID
+		}
+		return list[i].UpdatedAt.After(list[j].UpdatedAt)
+	})
+
+	return list
+}
+
+// GetBannerByFilter gets and returns banner content from the storage by the requested filters.
+// If the banners match several tags, the banner with the highest priority is returned.
+func (r *MemoryRepository) GetBannerByFilter(ctx context.Context, featureID int, tagIDs []int) (*banner.Banner, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	var best *banner.Banner
+	for _, tagID := range tagIDs {
+		list := r.bestBanners(featureID, tagID)
+		if len(list) != 0 && (best == nil || list[0].Outranks(best)) {
+			best = list[0]
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("GetBannerByFilter: banner not found in memory %w", errs.ErrBannerNotFound)
+	}
+
+	return copyBanner(best), nil
+}
+
+// bestBanners returns the active banners of the feature and tag pair
+// from the best one for users to the worst one.
+func (r *MemoryRepository) bestBanners(featureID int, tagID int) []*banner.Banner {
+	list := r.sortedBanners(featureID, tagID, true, false)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Outranks(list[j]) })
+
+	return list
+}
+
+// GetDefaultBanner gets and returns the default banner of the feature from the storage.
+// If the feature has several default banners, the banner with the highest priority is returned.
+func (r *MemoryRepository) GetDefaultBanner(ctx context.Context, featureID int) (*banner.Banner, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	var best *banner.Banner
+	for _, b := range r.sortedBanners(featureID, 0, true, false) {
+		if b.IsDefault && (best == nil || b.Outranks(best)) {
+			best = b
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("GetDefaultBanner: default banner not found in memory %w", errs.ErrBannerNotFound)
+	}
+
+	return copyBanner(best), nil
+}
+
+// GetBannerVariants gets and returns the active weighted variants of the feature and any of the tags
+// from the storage ordered by ID.
+func (r *MemoryRepository) GetBannerVariants(ctx context.Context, featureID int, tagIDs []int) ([]*banner.Banner, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	variants := make([]*banner.Banner, 0)
+	for _, b := range r.sortedBanners(featureID, 0, true, false) {
+		if b.Weight > 0 && slices.ContainsFunc(tagIDs, func(tagID int) bool { return slices.Contains(b.TagIDs, tagID) }) {
+			variants = append(variants, copyBanner(b))
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].ID < variants[j].ID })
+
+	return variants, nil
+}
+
+// GetBannerByID gets and returns the requested by ID banner from the storage,
+// including the deleted one.
+func (r *MemoryRepository) GetBannerByID(ctx context.Context, id int) (*banner.Banner, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	stored, ok := r.banners[id]
+	if !ok {
+		return nil, fmt.Errorf("GetBannerByID: banner not found in memory %w", errs.ErrBannerNotFound)
+	}
+
+	return copyBanner(stored), nil
+}
+
+// CreateBanner stores new banner into the storage.
+func (r *MemoryRepository) CreateBanner(ctx context.Context, b *banner.Banner) (*banner.Banner, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastID++
+	b.ID = r.lastID
+	b.CreatedAt = now()
+	b.UpdatedAt = b.CreatedAt
+	b.Version = 1
+	b.DeletedAt = nil
+
+	e, err := banner.NewOutboxEvent(audit.ActionCreated, b.ID, b.Version, nil, b)
+	if err != nil {
+		return nil, fmt.Errorf("CreateBanner: build outbox event failed %w", err)
+	}
+	err = r.addAuditEntries(ctx, e)
+	if err != nil {
+		r.lastID--
+		return nil, fmt.Errorf("CreateBanner: record change failed %w", err)
+	}
+
+	r.banners[b.ID] = copyBanner(b)
+	r.addOutboxEvents(e)
+
+	return b, nil
+}
+
+// GetBannersByFilter gets and returns the banners by filter from the storage.
+// Only deleted banners are returned if requested, otherwise only not deleted ones.
+func (r *MemoryRepository) GetBannersByFilter(ctx context.Context, featureID int, tagID int, limit int, offset int, deleted bool) ([]*banner.Banner, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	list := r.sortedBanners(featureID, tagID, false, deleted)
+	if offset > len(list) {
+		offset = len(list)
+	}
+	list = list[offset:]
+	if limit != 0 && limit < len(list) {
+		list = list[:limit]
+	}
+
+	bannersList := make([]*banner.Banner, 0, len(list))
+	for _, b := range list {
+		bannersList = append(bannersList, copyBanner(b))
+	}
+
+	return bannersList, nil
+}
+
+// UpdateBanner updates requested banner in the storage, if its stored version
+// equals to the banner version. Zero banner version means no version check.
+func (r *MemoryRepository) UpdateBanner(ctx context.Context, b *banner.Banner) (*banner.Banner, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	stored, ok := r.banners[b.ID]
+	if !ok || stored.DeletedAt != nil {
+		return nil, fmt.Errorf("UpdateBanner: nothing to update, %w", errs.ErrBannerNotFound)
+	}
+	if b.Version != 0 && b.Version != stored.Version {
+		return nil, fmt.Errorf("UpdateBanner: nothing to update, %w", errs.ErrBannerVersionConflict)
+	}
+
+	b.UpdatedAt = now()
+	b.Version = stored.Version + 1
+
+	e, err := banner.NewOutboxEvent(banner.ChangeAction(stored, b), b.ID, b.Version, stored, b)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateBanner: build outbox event failed %w", err)
+	}
+	err = r.addAuditEntries(ctx, e)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateBanner: record change failed %w", err)
+	}
+
+	updated := copyBanner(b)
+	updated.CreatedAt = stored.CreatedAt
+	updated.DeletedAt = nil
+	r.banners[b.ID] = updated
+	r.addOutboxEvents(e)
+
+	return b, nil
+}
+
+// SaveBanners creates the banners without ID and updates the banners with ID
+// at once. Nothing is stored, if any of the banners could not be saved.
+func (r *MemoryRepository) SaveBanners(ctx context.Context, banners []*banner.Banner) ([]*banner.Banner, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	lastID := r.lastID
+	staged := make(map[int]*banner.Banner, len(banners))
+	saved := make([]*banner.Banner, 0, len(banners))
+	events := make([]*banner.OutboxEvent, 0, len(banners))
+	for i, b := range banners {
+		var current *banner.Banner
+		stored := copyBanner(b)
+		stored.UpdatedAt = now()
+		stored.DeletedAt = nil
+
+		if b.ID == 0 {
+			lastID++
+			stored.ID = lastID
+			stored.CreatedAt = stored.UpdatedAt
+			stored.Version = 1
+		} else {
+			var ok bool
+			current, ok = staged[b.ID]
+			if !ok {
+				current, ok = r.banners[b.ID]
+			}
+			if !ok || current.DeletedAt != nil {
+				return nil, fmt.Errorf("SaveBanners: nothing to update, %w",
+					&errs.BatchItemError{Index: i, Err: errs.ErrBannerNotFound})
+			}
+			if b.Version != 0 && b.Version != current.Version {
+				return nil, fmt.Errorf("SaveBanners: nothing to update, %w",
+					&errs.BatchItemError{Index: i, Err: errs.ErrBannerVersionConflict})
+			}
+
+			stored.CreatedAt = current.CreatedAt
+			stored.Version = current.Version + 1
+		}
+
+		action := audit.ActionCreated
+		if current != nil {
+			action = banner.ChangeAction(current, stored)
+		}
+		e, err := banner.NewOutboxEvent(action, stored.ID, stored.Version, current, stored)
+		if err != nil {
+			return nil, fmt.Errorf("SaveBanners: build outbox event failed %w",
+				&errs.BatchItemError{Index: i, Err: err})
+		}
+
+		staged[stored.ID] = stored
+		saved = append(saved, copyBanner(stored))
+		events = append(events, e)
+	}
+
+	err := r.addAuditEntries(ctx, events...)
+	if err != nil {
+		return nil, fmt.Errorf("SaveBanners: record changes failed %w", err)
+	}
+
+	r.lastID = lastID
+	for id, b := range staged {
+		r.banners[id] = b
+	}
+	r.addOutboxEvents(events...)
+
+	return saved, nil
+}
+
+// DeleteBannerByID marks the requested by ID banner in the storage as deleted, if its
+// stored version equals to the requested version. Zero version means no version check.
+func (r *MemoryRepository) DeleteBannerByID(ctx context.Context, id int, version int) error {
+	r.Lock()
+	defer r.Unlock()
+
+	stored, ok := r.banners[id]
+	if !ok || stored.DeletedAt != nil {
+		return fmt.Errorf("DeleteBannerByID: nothing to delete, %w", errs.ErrBannerNotFound)
+	}
+	if version != 0 && version != stored.Version {
+		return fmt.Errorf("DeleteBannerByID: nothing to delete, %w", errs.ErrBannerVersionConflict)
+	}
+
+	e, err := banner.NewOutboxEvent(audit.ActionDeleted, id, stored.Version+1, stored, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteBannerByID: build outbox event failed %w", err)
+	}
+	err = r.addAuditEntries(ctx, e)
+	if err != nil {
+		return fmt.Errorf("DeleteBannerByID: record change failed %w", err)
+	}
+
+	deletedAt := now()
+	stored.DeletedAt = &deletedAt
+	stored.Version++
+	r.addOutboxEvents(e)
+
+	return nil
+}
+
+// RestoreBannerByID restores the requested by ID deleted banner in the storage and returns it.
+func (r *MemoryRepository) RestoreBannerByID(ctx context.Context, id int) (*banner.Banner, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	stored, ok := r.banners[id]
+	if !ok || stored.DeletedAt == nil {
+		return nil, fmt.Errorf("RestoreBannerByID: nothing to restore, %w", errs.ErrBannerNotFound)
+	}
+
+	restored := copyBanner(stored)
+	restored.DeletedAt = nil
+	restored.Version++
+
+	e, err := banner.NewOutboxEvent(audit.ActionRestored, id, restored.Version, nil, restored)
+	if err != nil {
+		return nil, fmt.Errorf("RestoreBannerByID: build outbox event failed %w", err)
+	}
+	err = r.addAuditEntries(ctx, e)
+	if err != nil {
+		return nil, fmt.Errorf("RestoreBannerByID: record change failed %w", err)
+	}
+
+	r.banners[id] = restored
+	r.addOutboxEvents(e)
+
+	return copyBanner(restored), nil
+}
+
+// PurgeDeletedBanners removes the banners deleted before the requested time
+// from the storage and returns their number.
+func (r *MemoryRepository) PurgeDeletedBanners(ctx context.Context, deletedBefore time.Time) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	count := 0
+	for id, b := range r.banners {
+		if b.DeletedAt != nil && b.DeletedAt.Before(deletedBefore) {
+			delete(r.banners, id)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// addAuditEntries records the banner changes of the outbox events into the audit log
+// before they are applied, the caller must hold the lock. Nothing is applied, if any
+// of the entries could not be recorded.
+func (r *MemoryRepository) addAuditEntries(ctx context.Context, events ...*banner.OutboxEvent) error {
+	if r.audit == nil {
+		return nil
+	}
+
+	entries := make([]*audit.Entry, 0, len(events))
+	for _, e := range events {
+		entry, err := newAuditEntry(ctx, e)
+		if err != nil {
+			return fmt.Errorf("addAuditEntries: build entry failed %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, entry := range entries {
+		_, err := r.audit.CreateEntry(ctx, entry)
+		if err != nil {
+			return fmt.Errorf("addAuditEntries: create entry failed %w", err)
+		}
+	}
+
+	return nil
+}
+
+// addOutboxEvents stores the banner change events into the outbox,
+// the caller must hold the lock.
+func (r *MemoryRepository) addOutboxEvents(events ...*banner.OutboxEvent) {
+	for _, e := range events {
+		r.lastEventID++
+		e.ID = r.lastEventID
+		e.CreatedAt = now()
+		r.outbox = append(r.outbox, outboxEntry{event: *e})
+	}
+}
+
+// ClaimOutboxEvents returns the outbox events from oldest to newest, which are not claimed
+// or which claim has expired, and claims them for the lease time.
+func (r *MemoryRepository) ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*banner.OutboxEvent, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	events := make([]*banner.OutboxEvent, 0)
+	for i := range r.outbox {
+		if len(events) == limit {
+			break
+		}
+
+		entry := &r.outbox[i]
+		if entry.lockedUntil.After(now) {
+			continue
+		}
+
+		entry.lockedUntil = now.Add(lease)
+		e := entry.event
+		events = append(events, &e)
+	}
+
+	return events, nil
+}
+
+// DeleteOutboxEvents removes the published events from the outbox.
+func (r *MemoryRepository) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.outbox = slices.DeleteFunc(r.outbox, func(entry outboxEntry) bool {
+		return slices.Contains(ids, entry.event.ID)
+	})
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+)
+
+// insertOutboxEventQuery is the query for storing the banner change event into the outbox.
+const insertOutboxEventQuery = `INSERT INTO banner_outbox (key, action, banner_id, before, after)
+	VALUES ($1, $2, $3, $4, $5)`
+
+// claimOutboxEventsQuery is the query for claiming the oldest outbox events, which are
+// not claimed or which claim has expired. The events claimed at the same time are skipped.
+const claimOutboxEventsQuery = `UPDATE banner_outbox SET locked_until = $2
+	WHERE id IN (SELECT id FROM banner_outbox WHERE locked_until <= $1
+	ORDER BY id LIMIT $3 FOR UPDATE SKIP LOCKED)
+	RETURNING id, key, action, banner_id, before, after, created_at`
+
+// jsonArgument returns the JSON query argument, which is NULL for the empty data.
+func jsonArgument(data json.RawMessage) any {
+	if data == nil {
+		return nil
+	}
+	return string(data)
+}
+
+// createOutboxEvent stores the banner change event into the outbox using the transaction
+// and returns it.
+func createOutboxEvent(ctx context.Context, q querier, action string, id int, version int, before *banner.Banner, after *banner.Banner) (*banner.OutboxEvent, error) {
+	e, err := banner.NewOutboxEvent(action, id, version, before, after)
+	if err != nil {
+		return nil, fmt.Errorf("createOutboxEvent: build event failed %w", err)
+	}
+
+	_, err = q.ExecContext(ctx, insertOutboxEventQuery, e.Key, e.Action, e.BannerID, jsonArgument(e.Before), jsonArgument(e.After))
+	if err != nil {
+		return nil, fmt.Errorf("createOutboxEvent: insert data failed %w", err)
+	}
+
+	return e, nil
+}
+
+// ClaimOutboxEvents returns the outbox events from oldest to newest, which are not claimed
+// or which claim has expired, and claims them for the lease time.
+func (r *Repository) ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*banner.OutboxEvent, error) {
+	rows, err := r.db.QueryContext(ctx, claimOutboxEventsQuery, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("ClaimOutboxEvents: update rows failed %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*banner.OutboxEvent, 0)
+	for rows.Next() {
+		var e banner.OutboxEvent
+		var before, after []byte
+		err = rows.Scan(&e.ID, &e.Key, &e.Action, &e.BannerID, &before, &after, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("ClaimOutboxEvents: scan row failed %w", err)
+		}
+
+		e.Before = before
+		e.After = after
+		events = append(events, &e)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("ClaimOutboxEvents: rows.Err %w", err)
+	}
+
+	sortOutboxEvents(events)
+
+	return events, nil
+}
+
+// DeleteOutboxEvents removes the published events from the outbox.
+func (r *Repository) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM banner_outbox WHERE id = ANY ($1)`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("DeleteOutboxEvents: delete data failed %w", err)
+	}
+
+	return nil
+}
+
+// createPoolOutboxEvent stores the banner change event into the outbox using the transaction.
+func createPoolOutboxEvent(ctx context.Context, q poolQuerier, action string, id int, version int, before *banner.Banner, after *banner.Banner) (*banner.OutboxEvent, error) {
+	e, err := banner.NewOutboxEvent(action, id, version, before, after)
+	if err != nil {
+		return nil, fmt.Errorf("createPoolOutboxEvent: build event failed %w", err)
+	}
+
+	_, err = q.Exec(ctx, insertOutboxEventQuery, e.Key, e.Action, e.BannerID, jsonArgument(e.Before), jsonArgument(e.After))
+	if err != nil {
+		return nil, fmt.Errorf("createPoolOutboxEvent: insert data failed %w", err)
+	}
+
+	return e, nil
+}
+
+// ClaimOutboxEvents returns the outbox events from oldest to newest, which are not claimed
+// or which claim has expired, and claims them for the lease time.
+func (r *PoolRepository) ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*banner.OutboxEvent, error) {
+	rows, err := r.pool.Query(ctx, claimOutboxEventsQuery, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("ClaimOutboxEvents: update rows failed %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*banner.OutboxEvent, 0)
+	for rows.Next() {
+		var e banner.OutboxEvent
+		err = rows.Scan(&e.ID, &e.Key, &e.Action, &e.BannerID, &e.Before, &e.After, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("ClaimOutboxEvents: scan row failed %w", err)
+		}
+		events = append(events, &e)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("ClaimOutboxEvents: rows.Err %w", err)
+	}
+
+	sortOutboxEvents(events)
+
+	return events, nil
+}
+
+// DeleteOutboxEvents removes the published events from the outbox.
+func (r *PoolRepository) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM banner_outbox WHERE id = ANY ($1)`, ids)
+	if err != nil {
+		return fmt.Errorf("DeleteOutboxEvents: delete data failed %w", err)
+	}
+
+	return nil
+}
+
+// sortOutboxEvents sorts the outbox events from oldest to newest,
+// because the returned rows of the update are not ordered.
+func sortOutboxEvents(events []*banner.OutboxEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+}
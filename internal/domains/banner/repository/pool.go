@@ -0,0 +1,424 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+)
+
+// getBannerByFilterStmt is the name of the prepared statement
+// for getting the best banner by feature and tags.
+const getBannerByFilterStmt = "get_banner_by_filter"
+
+// getBannerVariantsStmt is the name of the prepared statement
+// for getting the weighted variants by feature and tags.
+const getBannerVariantsStmt = "get_banner_variants"
+
+// getDefaultBannerStmt is the name of the prepared statement
+// for getting the default banner by feature.
+const getDefaultBannerStmt = "get_default_banner"
+
+// poolQuerier describes the query methods of the pool and the transaction.
+type poolQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// PoolRepository contains native pgx connection pool for storing the banners.
+type PoolRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewBannerPoolRepository returns new banners repository object
+// built on the native pgx connection pool.
+func NewBannerPoolRepository(ctx context.Context, pool *pgxpool.Pool) *PoolRepository {
+	return &PoolRepository{
+		pool: pool,
+	}
+}
+
+// PrepareStatements prepares the hot statements on the new pool connection.
+func PrepareStatements(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Prepare(ctx, getBannerByFilterStmt, getBannerByFilterQuery)
+	if err != nil {
+		return fmt.Errorf("PrepareStatements: prepare %s failed %w", getBannerByFilterStmt, err)
+	}
+
+	_, err = conn.Prepare(ctx, getBannerVariantsStmt, getBannerVariantsQuery)
+	if err != nil {
+		return fmt.Errorf("PrepareStatements: prepare %s failed %w", getBannerVariantsStmt, err)
+	}
+
+	_, err = conn.Prepare(ctx, getDefaultBannerStmt, getDefaultBannerQuery)
+	if err != nil {
+		return fmt.Errorf("PrepareStatements: prepare %s failed %w", getDefaultBannerStmt, err)
+	}
+
+	return nil
+}
+
+// GetBannerByFilter gets and returns banner content from the storage by the requested filters.
+// If the banners match several tags, the banner with the highest priority is returned.
+func (r *PoolRepository) GetBannerByFilter(ctx context.Context, featureID int, tagIDs []int) (*banner.Banner, error) {
+	row := r.pool.QueryRow(ctx, getBannerByFilterStmt, featureID, tagIDs)
+
+	var b banner.Banner
+	err := row.Scan(&b.ID, &b.TagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("GetBannerByFilter: banner not found in database %w", errs.ErrBannerNotFound)
+		}
+		return nil, fmt.Errorf("GetBannerByFilter: scan row failed %w", err)
+	}
+
+	return &b, nil
+}
+
+// GetDefaultBanner gets and returns the default banner of the feature from the storage.
+// If the feature has several default banners, the banner with the highest priority is returned.
+func (r *PoolRepository) GetDefaultBanner(ctx context.Context, featureID int) (*banner.Banner, error) {
+	row := r.pool.QueryRow(ctx, getDefaultBannerStmt, featureID)
+
+	var b banner.Banner
+	err := row.Scan(&b.ID, &b.TagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("GetDefaultBanner: default banner not found in database %w", errs.ErrBannerNotFound)
+		}
+		return nil, fmt.Errorf("GetDefaultBanner: scan row failed %w", err)
+	}
+
+	return &b, nil
+}
+
+// GetBannerVariants gets and returns the active weighted variants of the feature and any of the tags
+// from the storage ordered by ID with one query.
+func (r *PoolRepository) GetBannerVariants(ctx context.Context, featureID int, tagIDs []int) ([]*banner.Banner, error) {
+	rows, err := r.pool.Query(ctx, getBannerVariantsStmt, featureID, tagIDs)
+	if err != nil {
+		return nil, fmt.Errorf("GetBannerVariants: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	variants := make([]*banner.Banner, 0)
+	for rows.Next() {
+		var b banner.Banner
+		err = rows.Scan(&b.ID, &b.TagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version)
+		if err != nil {
+			return nil, fmt.Errorf("GetBannerVariants: scan row failed %w", err)
+		}
+		variants = append(variants, &b)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetBannerVariants: rows.Err %w", err)
+	}
+
+	return variants, nil
+}
+
+// GetBannerByID gets and returns the requested by ID banner from the storage,
+// including the deleted one.
+func (r *PoolRepository) GetBannerByID(ctx context.Context, id int) (*banner.Banner, error) {
+	row := r.pool.QueryRow(ctx, `SELECT id, tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window, created_at, updated_at, version, deleted_at 
+	FROM banners WHERE id = $1`, id)
+
+	var b banner.Banner
+	err := row.Scan(&b.ID, &b.TagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version, &b.DeletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("GetBannerByID: banner not found in database %w", errs.ErrBannerNotFound)
+		}
+		return nil, fmt.Errorf("GetBannerByID: scan row failed %w", err)
+	}
+
+	return &b, nil
+}
+
+// CreateBanner stores new banner with its outbox event and audit log entry into the storage in one transaction.
+func (r *PoolRepository) CreateBanner(ctx context.Context, b *banner.Banner) (*banner.Banner, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("CreateBanner: begin transaction failed %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	stored, err := createPoolBanner(ctx, tx, b)
+	if err != nil {
+		return nil, fmt.Errorf("CreateBanner: create banner failed %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("CreateBanner: commit transaction failed %w", err)
+	}
+
+	return stored, nil
+}
+
+// createPoolBanner stores new banner with its outbox event and audit log entry using the transaction.
+func createPoolBanner(ctx context.Context, q poolQuerier, b *banner.Banner) (*banner.Banner, error) {
+	row := q.QueryRow(ctx, `INSERT INTO banners (tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at, updated_at, version`, b.TagIDs, b.FeatureID, b.Content, b.Locales, b.IsActive, b.Weight, b.Priority, b.IsDefault, b.Cap.Impressions, b.Cap.Window)
+
+	err := row.Scan(&b.ID, &b.CreatedAt, &b.UpdatedAt, &b.Version)
+	if err != nil {
+		return nil, fmt.Errorf("createPoolBanner: scan row failed %w", err)
+	}
+	b.DeletedAt = nil
+
+	err = recordPoolChange(ctx, q, audit.ActionCreated, b.ID, b.Version, nil, b)
+	if err != nil {
+		return nil, fmt.Errorf("createPoolBanner: record change failed %w", err)
+	}
+
+	return b, nil
+}
+
+// GetBannersByFilter gets and returns the banners by filter from the storage.
+// Only deleted banners are returned if requested, otherwise only not deleted ones.
+func (r *PoolRepository) GetBannersByFilter(ctx context.Context, featureID int, tagID int, limit int, offset int, deleted bool) ([]*banner.Banner, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id, tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window, created_at, updated_at, version, deleted_at 
+	FROM banners WHERE ($1 = 0 OR feature_id = $1) AND ($2 = 0 OR $2 = ANY (tag_ids)) AND (deleted_at IS NOT NULL) = $5 
+	ORDER BY updated_at DESC LIMIT NULLIF($3, 0) OFFSET $4`, featureID, tagID, limit, offset, deleted)
+	if err != nil {
+		return nil, fmt.Errorf("GetBannersByFilter: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	bannersList := make([]*banner.Banner, 0)
+	for rows.Next() {
+		var b banner.Banner
+		err = rows.Scan(&b.ID, &b.TagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version, &b.DeletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("GetBannersByFilter: scan row failed %w", err)
+		}
+		bannersList = append(bannersList, &b)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetBannersByFilter: rows.Err %w", err)
+	}
+
+	return bannersList, nil
+}
+
+// UpdateBanner updates requested banner with its outbox event and audit log entry in the storage in one transaction,
+// if its stored version equals to the banner version. Zero banner version means no version check.
+func (r *PoolRepository) UpdateBanner(ctx context.Context, b *banner.Banner) (*banner.Banner, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateBanner: begin transaction failed %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	stored, err := updatePoolBanner(ctx, tx, b)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateBanner: update banner failed %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateBanner: commit transaction failed %w", err)
+	}
+
+	return stored, nil
+}
+
+// updatePoolBanner updates requested banner with its outbox event and audit log entry using the transaction.
+// The banner state before the update is locked until the end of the transaction.
+func updatePoolBanner(ctx context.Context, q poolQuerier, b *banner.Banner) (*banner.Banner, error) {
+	before, err := getPoolBannerForUpdate(ctx, q, b.ID)
+	if err != nil {
+		return nil, fmt.Errorf("updatePoolBanner: nothing to update, %w", err)
+	}
+
+	row := q.QueryRow(ctx, `UPDATE banners SET tag_ids = $1, feature_id = $2, content = $3, localized_content = $4, is_active = $5, weight = $6, priority = $7, is_default = $8, cap_impressions = $9, cap_window = $10,
+	updated_at = NOW(), version = version + 1 WHERE id = $11 AND deleted_at IS NULL AND ($12 = 0 OR version = $12) 
+	RETURNING updated_at, version`, b.TagIDs, b.FeatureID, b.Content, b.Locales, b.IsActive, b.Weight, b.Priority, b.IsDefault, b.Cap.Impressions, b.Cap.Window, b.ID, b.Version)
+
+	err = row.Scan(&b.UpdatedAt, &b.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("updatePoolBanner: nothing to update, %w", unchangedPoolError(ctx, q, b.ID))
+		}
+		return nil, fmt.Errorf("updatePoolBanner: scan row failed %w", err)
+	}
+
+	err = recordPoolChange(ctx, q, banner.ChangeAction(before, b), b.ID, b.Version, before, b)
+	if err != nil {
+		return nil, fmt.Errorf("updatePoolBanner: record change failed %w", err)
+	}
+
+	return b, nil
+}
+
+// SaveBanners creates the banners without ID and updates the banners with ID in one
+// transaction. Nothing is stored, if any of the banners could not be saved.
+func (r *PoolRepository) SaveBanners(ctx context.Context, banners []*banner.Banner) ([]*banner.Banner, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("SaveBanners: begin transaction failed %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	saved := make([]*banner.Banner, 0, len(banners))
+	for i, b := range banners {
+		var stored *banner.Banner
+		if b.ID == 0 {
+			stored, err = createPoolBanner(ctx, tx, b)
+		} else {
+			stored, err = updatePoolBanner(ctx, tx, b)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("SaveBanners: save banner failed %w", &errs.BatchItemError{Index: i, Err: err})
+		}
+		saved = append(saved, stored)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("SaveBanners: commit transaction failed %w", err)
+	}
+
+	return saved, nil
+}
+
+// DeleteBannerByID marks the requested by ID banner in the storage as deleted with its outbox
+// event and audit log entry in one transaction, if its stored version equals to the requested version.
+// Zero version means no version check.
+func (r *PoolRepository) DeleteBannerByID(ctx context.Context, id int, version int) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("DeleteBannerByID: begin transaction failed %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := getPoolBannerForUpdate(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("DeleteBannerByID: nothing to delete, %w", err)
+	}
+
+	var deletedVersion int
+	err = tx.QueryRow(ctx, `UPDATE banners SET deleted_at = NOW(), version = version + 1 
+	WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2) RETURNING version`, id, version).Scan(&deletedVersion)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("DeleteBannerByID: nothing to delete, %w", unchangedPoolError(ctx, tx, id))
+		}
+		return fmt.Errorf("DeleteBannerByID: delete data failed %w", err)
+	}
+
+	err = recordPoolChange(ctx, tx, audit.ActionDeleted, id, deletedVersion, before, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteBannerByID: record change failed %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("DeleteBannerByID: commit transaction failed %w", err)
+	}
+
+	return nil
+}
+
+// RestoreBannerByID restores the requested by ID deleted banner in the storage with its outbox
+// event and audit log entry in one transaction and returns it.
+func (r *PoolRepository) RestoreBannerByID(ctx context.Context, id int) (*banner.Banner, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("RestoreBannerByID: begin transaction failed %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	row := tx.QueryRow(ctx, `UPDATE banners SET deleted_at = NULL, version = version + 1 
+	WHERE id = $1 AND deleted_at IS NOT NULL 
+	RETURNING id, tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window, created_at, updated_at, version`, id)
+
+	var b banner.Banner
+	err = row.Scan(&b.ID, &b.TagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("RestoreBannerByID: nothing to restore, %w", errs.ErrBannerNotFound)
+		}
+		return nil, fmt.Errorf("RestoreBannerByID: scan row failed %w", err)
+	}
+
+	err = recordPoolChange(ctx, tx, audit.ActionRestored, b.ID, b.Version, nil, &b)
+	if err != nil {
+		return nil, fmt.Errorf("RestoreBannerByID: record change failed %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("RestoreBannerByID: commit transaction failed %w", err)
+	}
+
+	return &b, nil
+}
+
+// getPoolBannerForUpdate gets and returns the requested by ID not deleted banner
+// and locks it until the end of the transaction.
+func getPoolBannerForUpdate(ctx context.Context, q poolQuerier, id int) (*banner.Banner, error) {
+	row := q.QueryRow(ctx, `SELECT id, tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window, created_at, updated_at, version 
+	FROM banners WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id)
+
+	var b banner.Banner
+	err := row.Scan(&b.ID, &b.TagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errs.ErrBannerNotFound
+		}
+		return nil, fmt.Errorf("getPoolBannerForUpdate: scan row failed %w", err)
+	}
+
+	return &b, nil
+}
+
+// unchangedPoolError returns the reason why the banner was not changed: the version conflict,
+// if the not deleted banner exists, otherwise not found error.
+func unchangedPoolError(ctx context.Context, q poolQuerier, id int) error {
+	var version int
+	err := q.QueryRow(ctx, `SELECT version FROM banners WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errs.ErrBannerNotFound
+		}
+		return fmt.Errorf("unchangedError: scan row failed %w", err)
+	}
+
+	return errs.ErrBannerVersionConflict
+}
+
+// PurgeDeletedBanners removes the banners deleted before the requested time
+// from the storage and returns their number.
+func (r *PoolRepository) PurgeDeletedBanners(ctx context.Context, deletedBefore time.Time) (int, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM banners WHERE deleted_at < $1`, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeDeletedBanners: delete data failed %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// ReplicationLag returns the replication lag of the storage,
+// which is zero if the storage is not a replica.
+func (r *PoolRepository) ReplicationLag(ctx context.Context) (time.Duration, error) {
+	var seconds float64
+	err := r.pool.QueryRow(ctx, replicationLagQuery).Scan(&seconds)
+	if err != nil {
+		return 0, fmt.Errorf("ReplicationLag: scan row failed %w", err)
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,129 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postgresRepositories returns both PostgreSQL repository implementations
+// on the integration tests database with the empty banners table
+// and the registered features and tags.
+func postgresRepositories(t *testing.T) map[string]repository.ReplicaRepository {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := database.Init(ctx, testDSN(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	pool, err := database.InitPool(ctx, &config.Config{DSN: testDSN(t)}, repository.PrepareStatements)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	_, err = db.ExecContext(ctx, resetTables)
+	require.NoError(t, err)
+
+	return map[string]repository.ReplicaRepository{
+		"sql": repository.NewBannerRepository(ctx, db),
+		"pgx": repository.NewBannerPoolRepository(ctx, pool),
+	}
+}
+
+func TestInit_Migrations(t *testing.T) {
+	ctx := context.Background()
+
+	// Migrations must be applicable to the already migrated database
+	for i := 0; i < 2; i++ {
+		db, err := database.Init(ctx, testDSN(t))
+		require.NoError(t, err)
+
+		var count int
+		err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM banners`).Scan(&count)
+		assert.NoError(t, err)
+		db.Close()
+	}
+}
+
+func TestRepository_TagMatching(t *testing.T) {
+	ctx := context.Background()
+
+	for name, repo := range postgresRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			many, err := repo.CreateBanner(ctx, &banner.Banner{
+				TagIDs:    []int{3, 11, 111, 2147483647},
+				FeatureID: 7,
+				Content:   &banner.Content{"title": name},
+				IsActive:  true,
+			})
+			require.NoError(t, err)
+
+			for _, tagID := range []int{3, 11, 111, 2147483647} {
+				got, err := repo.GetBannerByFilter(ctx, 7, []int{tagID})
+				require.NoError(t, err)
+				assert.Equal(t, many.ID, got.ID)
+				assert.Equal(t, []int{3, 11, 111, 2147483647}, got.TagIDs)
+			}
+
+			// Tags are matched as array elements, not as text
+			for _, tagID := range []int{1, 2, 4, 211} {
+				_, err := repo.GetBannerByFilter(ctx, 7, []int{tagID})
+				assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+			}
+
+			list, err := repo.GetBannersByFilter(ctx, 0, 111, 0, 0, false)
+			require.NoError(t, err)
+			require.NotEmpty(t, list)
+			assert.Equal(t, many.ID, list[0].ID)
+
+			err = repo.DeleteBannerByID(ctx, many.ID, 0)
+			require.NoError(t, err)
+
+			deleted, err := repo.GetBannersByFilter(ctx, 0, 111, 0, 0, true)
+			require.NoError(t, err)
+			require.NotEmpty(t, deleted)
+			assert.Equal(t, many.ID, deleted[0].ID)
+		})
+	}
+}
+
+func TestRepository_NullContent(t *testing.T) {
+	ctx := context.Background()
+
+	for name, repo := range postgresRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			stored, err := repo.CreateBanner(ctx, &banner.Banner{
+				TagIDs:    []int{1},
+				FeatureID: 8,
+				IsActive:  true,
+			})
+			require.NoError(t, err)
+
+			got, err := repo.GetBannerByFilter(ctx, 8, []int{1})
+			require.NoError(t, err)
+			assert.Nil(t, got.Content)
+
+			err = repo.DeleteBannerByID(ctx, stored.ID, 0)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRepository_ReplicationLag(t *testing.T) {
+	ctx := context.Background()
+
+	for name, repo := range postgresRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			lag, err := repo.ReplicationLag(ctx)
+			require.NoError(t, err)
+			assert.Zero(t, lag)
+		})
+	}
+}
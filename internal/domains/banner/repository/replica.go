@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"go.uber.org/zap"
+)
+
+// ReplicaRepository describes the banners storage, which
+// might be used as a read-only replica.
+type ReplicaRepository interface {
+	banner.Repository
+	ReplicationLag(ctx context.Context) (time.Duration, error)
+}
+
+// replica contains the replica storage and its health state.
+type replica struct {
+	repo    ReplicaRepository
+	healthy atomic.Bool
+}
+
+// ReplicaRouter contains the primary and replica storages and routes
+// the reads allowed by context to the healthy replicas.
+type ReplicaRouter struct {
+	primary       banner.Repository
+	replicas      []*replica
+	maxLag        time.Duration
+	checkInterval time.Duration
+	next          atomic.Uint64
+}
+
+// NewReplicaRouter returns new banners repository object, which sends
+// writes and actual reads to the primary and other reads to the replicas.
+// Replicas are considered unhealthy until the first health check.
+func NewReplicaRouter(ctx context.Context, primary banner.Repository, replicas []ReplicaRepository,
+	maxLag time.Duration, checkInterval time.Duration) *ReplicaRouter {
+	r := &ReplicaRouter{
+		primary:       primary,
+		replicas:      make([]*replica, 0, len(replicas)),
+		maxLag:        maxLag,
+		checkInterval: checkInterval,
+	}
+	for _, repo := range replicas {
+		r.replicas = append(r.replicas, &replica{repo: repo})
+	}
+
+	return r
+}
+
+// HealthCheck checks the replicas availability and lag with requested interval.
+func (r *ReplicaRouter) HealthCheck(ctx context.Context) {
+	ticker := time.NewTicker(r.checkInterval)
+	defer ticker.Stop()
+
+	r.checkReplicas(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.checkReplicas(ctx)
+		}
+	}
+}
+
+// checkReplicas updates the health state of every replica.
+func (r *ReplicaRouter) checkReplicas(ctx context.Context) {
+	for i, rep := range r.replicas {
+		lag, err := rep.repo.ReplicationLag(ctx)
+		healthy := err == nil && lag <= r.maxLag
+
+		if rep.healthy.Swap(healthy) != healthy {
+			logger.Log.Info("replica health changed",
+				zap.Int("replica", i),
+				zap.Bool("healthy", healthy),
+				zap.Duration("lag", lag),
+				zap.Error(err))
+		}
+	}
+}
+
+// replica returns the next healthy replica or nil, if there is no one.
+func (r *ReplicaRouter) replica() *replica {
+	count := uint64(len(r.replicas))
+	if count == 0 {
+		return nil
+	}
+
+	start := r.next.Add(1)
+	for i := uint64(0); i < count; i++ {
+		rep := r.replicas[(start+i)%count]
+		if rep.healthy.Load() {
+			return rep
+		}
+	}
+
+	return nil
+}
+
+// GetBannerByFilter gets and returns banner from the replica, if it is allowed
+// by context and there is a healthy one, otherwise from the primary.
+func (r *ReplicaRouter) GetBannerByFilter(ctx context.Context, featureID int, tagIDs []int) (*banner.Banner, error) {
+	if utils.GetReplicaReadFromContext(ctx) {
+		if rep := r.replica(); rep != nil {
+			b, err := rep.repo.GetBannerByFilter(ctx, featureID, tagIDs)
+			if err == nil || errors.Is(err, errs.ErrBannerNotFound) {
+				return b, err
+			}
+
+			// Replica failed, so wait for the next health check and fall back to the primary
+			rep.healthy.Store(false)
+			logger.Log.Error("GetBannerByFilter: get banner from replica failed",
+				zap.Error(err))
+		}
+	}
+
+	b, err := r.primary.GetBannerByFilter(ctx, featureID, tagIDs)
+	if err != nil {
+		return nil, fmt.Errorf("GetBannerByFilter: get banner from primary failed %w", err)
+	}
+
+	return b, nil
+}
+
+// GetDefaultBanner gets and returns the default banner of the feature from the replica,
+// if it is allowed by context and there is a healthy one, otherwise from the primary.
+func (r *ReplicaRouter) GetDefaultBanner(ctx context.Context, featureID int) (*banner.Banner, error) {
+	if utils.GetReplicaReadFromContext(ctx) {
+		if rep := r.replica(); rep != nil {
+			b, err := rep.repo.GetDefaultBanner(ctx, featureID)
+			if err == nil || errors.Is(err, errs.ErrBannerNotFound) {
+				return b, err
+			}
+
+			// Replica failed, so wait for the next health check and fall back to the primary
+			rep.healthy.Store(false)
+			logger.Log.Error("GetDefaultBanner: get default banner from replica failed",
+				zap.Error(err))
+		}
+	}
+
+	b, err := r.primary.GetDefaultBanner(ctx, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("GetDefaultBanner: get default banner from primary failed %w", err)
+	}
+
+	return b, nil
+}
+
+// GetBannerVariants gets and returns the weighted variants of the feature and any of the tags from the replica,
+// if it is allowed by context and there is a healthy one, otherwise from the primary.
+func (r *ReplicaRouter) GetBannerVariants(ctx context.Context, featureID int, tagIDs []int) ([]*banner.Banner, error) {
+	if utils.GetReplicaReadFromContext(ctx) {
+		if rep := r.replica(); rep != nil {
+			variants, err := rep.repo.GetBannerVariants(ctx, featureID, tagIDs)
+			if err == nil {
+				return variants, nil
+			}
+
+			// Replica failed, so wait for the next health check and fall back to the primary
+			rep.healthy.Store(false)
+			logger.Log.Error("GetBannerVariants: get variants from replica failed",
+				zap.Error(err))
+		}
+	}
+
+	variants, err := r.primary.GetBannerVariants(ctx, featureID, tagIDs)
+	if err != nil {
+		return nil, fmt.Errorf("GetBannerVariants: get variants from primary failed %w", err)
+	}
+
+	return variants, nil
+}
+
+// GetBannerByID gets and returns the requested by ID banner from the primary.
+func (r *ReplicaRouter) GetBannerByID(ctx context.Context, id int) (*banner.Banner, error) {
+	return r.primary.GetBannerByID(ctx, id)
+}
+
+// CreateBanner stores new banner into the primary.
+func (r *ReplicaRouter) CreateBanner(ctx context.Context, b *banner.Banner) (*banner.Banner, error) {
+	return r.primary.CreateBanner(ctx, b)
+}
+
+// GetBannersByFilter gets and returns the banners by filter from the primary.
+func (r *ReplicaRouter) GetBannersByFilter(ctx context.Context, featureID int, tagID int, limit int, offset int, deleted bool) ([]*banner.Banner, error) {
+	return r.primary.GetBannersByFilter(ctx, featureID, tagID, limit, offset, deleted)
+}
+
+// UpdateBanner updates requested banner in the primary.
+func (r *ReplicaRouter) UpdateBanner(ctx context.Context, b *banner.Banner) (*banner.Banner, error) {
+	return r.primary.UpdateBanner(ctx, b)
+}
+
+// SaveBanners creates and updates the banners in one transaction in the primary.
+func (r *ReplicaRouter) SaveBanners(ctx context.Context, banners []*banner.Banner) ([]*banner.Banner, error) {
+	return r.primary.SaveBanners(ctx, banners)
+}
+
+// DeleteBannerByID deletes the requested by ID banner from the primary.
+func (r *ReplicaRouter) DeleteBannerByID(ctx context.Context, id int, version int) error {
+	return r.primary.DeleteBannerByID(ctx, id, version)
+}
+
+// RestoreBannerByID restores the requested by ID deleted banner in the primary.
+func (r *ReplicaRouter) RestoreBannerByID(ctx context.Context, id int) (*banner.Banner, error) {
+	return r.primary.RestoreBannerByID(ctx, id)
+}
+
+// PurgeDeletedBanners removes the banners deleted before the requested time from the primary.
+func (r *ReplicaRouter) PurgeDeletedBanners(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return r.primary.PurgeDeletedBanners(ctx, deletedBefore)
+}
+
+// ClaimOutboxEvents claims the banner change events in the outbox of the primary.
+func (r *ReplicaRouter) ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*banner.OutboxEvent, error) {
+	return r.primary.ClaimOutboxEvents(ctx, now, lease, limit)
+}
+
+// DeleteOutboxEvents removes the published events from the outbox of the primary.
+func (r *ReplicaRouter) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	return r.primary.DeleteOutboxEvents(ctx, ids)
+}
@@ -0,0 +1,122 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/mocks"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeReplica is the replica storage with the mocked repository methods.
+type fakeReplica struct {
+	*mocks.MockRepository
+	lag time.Duration
+	err error
+}
+
+// ReplicationLag returns the configured lag and error.
+func (f *fakeReplica) ReplicationLag(ctx context.Context) (time.Duration, error) {
+	return f.lag, f.err
+}
+
+func TestReplicaRouter_GetBannerByFilter(t *testing.T) {
+	primaryBanner := &banner.Banner{ID: 1}
+	replicaBanner := &banner.Banner{ID: 2}
+
+	tests := []struct {
+		name        string
+		replicaRead bool
+		lag         time.Duration
+		lagErr      error
+		replicaErr  error
+		wantID      int
+		wantErr     error
+	}{
+		{
+			name:        "replica read from healthy replica",
+			replicaRead: true,
+			wantID:      replicaBanner.ID,
+		},
+		{
+			name:        "actual read from primary",
+			replicaRead: false,
+			wantID:      primaryBanner.ID,
+		},
+		{
+			name:        "replica lag beyond threshold",
+			replicaRead: true,
+			lag:         time.Minute,
+			wantID:      primaryBanner.ID,
+		},
+		{
+			name:        "replica is down",
+			replicaRead: true,
+			lagErr:      errors.New("connection refused"),
+			wantID:      primaryBanner.ID,
+		},
+		{
+			name:        "replica query failed",
+			replicaRead: true,
+			replicaErr:  errors.New("connection reset"),
+			wantID:      primaryBanner.ID,
+		},
+		{
+			name:        "banner not found in replica",
+			replicaRead: true,
+			replicaErr:  errs.ErrBannerNotFound,
+			wantErr:     errs.ErrBannerNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			primary := mocks.NewMockRepository(mockCtrl)
+			primary.EXPECT().GetBannerByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(primaryBanner, nil).AnyTimes()
+
+			replica := &fakeReplica{
+				MockRepository: mocks.NewMockRepository(mockCtrl),
+				lag:            tt.lag,
+				err:            tt.lagErr,
+			}
+			if tt.replicaErr != nil {
+				replica.EXPECT().GetBannerByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, tt.replicaErr).AnyTimes()
+			} else {
+				replica.EXPECT().GetBannerByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(replicaBanner, nil).AnyTimes()
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			router := repository.NewReplicaRouter(ctx, primary, []repository.ReplicaRepository{replica},
+				time.Second, time.Minute)
+
+			// Run the single health check
+			cancel()
+			router.HealthCheck(ctx)
+
+			reqCtx := context.Background()
+			if tt.replicaRead {
+				reqCtx = utils.WithReplicaRead(reqCtx)
+			}
+
+			got, err := router.GetBannerByFilter(reqCtx, 1, []int{1})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantID, got.ID)
+		})
+	}
+}
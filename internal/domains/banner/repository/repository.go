@@ -10,10 +10,23 @@ import (
 	"time"
 
 	"github.com/lib/pq"
+	"github.com/pavlegich/banners-service/internal/domains/audit"
 	"github.com/pavlegich/banners-service/internal/domains/banner"
 	errs "github.com/pavlegich/banners-service/internal/errors"
 )
 
+// replicationLagQuery is the query for getting the replication lag in seconds,
+// which is zero for the primary and for the replica without pending WAL.
+const replicationLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 0) END::float8`
+
+// querier describes the query methods of the database and the transaction.
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 // Repository contains storage objects for storing the banners.
 type Repository struct {
 	db *sql.DB
@@ -26,22 +39,27 @@ func NewBannerRepository(ctx context.Context, db *sql.DB) *Repository {
 	}
 }
 
+// getBannerByFilterQuery is the query for getting the best banner of the feature
+// for any of the tags, the overlap of the tags is served by the GIN index.
+const getBannerByFilterQuery = `SELECT id, tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window, created_at, updated_at, version 
+	FROM banners WHERE feature_id = $1 AND tag_ids && $2::integer[] AND is_active = true AND deleted_at IS NULL 
+	ORDER BY priority DESC, updated_at DESC, id DESC LIMIT 1`
+
 // GetBannerByFilter: gets and returns banner content from the storage by the requested filters.
-func (r *Repository) GetBannerByFilter(ctx context.Context, featureID int, tagID int) (*banner.Banner, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT id, tag_ids, feature_id, content, is_active, created_at, updated_at 
-	FROM banners WHERE feature_id = $1 AND $2 = ANY (tag_ids) AND is_active = true 
-	ORDER BY updated_at DESC LIMIT 1`, featureID, tagID)
+// If the banners match several tags, the banner with the highest priority is returned.
+func (r *Repository) GetBannerByFilter(ctx context.Context, featureID int, tagIDs []int) (*banner.Banner, error) {
+	row := r.db.QueryRowContext(ctx, getBannerByFilterQuery, featureID, tagIDs)
 
 	var b banner.Banner
-	var tagIDs pq.Int64Array
-	err := row.Scan(&b.ID, &tagIDs, &b.FeatureID, &b.Content, &b.IsActive, &b.CreatedAt, &b.UpdatedAt)
+	var bannerTagIDs pq.Int64Array
+	err := row.Scan(&b.ID, &bannerTagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("GetBannerByFilter: banner not found in database %w", errs.ErrBannerNotFound)
 		}
 		return nil, fmt.Errorf("GetBannerByFilter: scan row failed %w", err)
 	}
-	for _, v := range tagIDs {
+	for _, v := range bannerTagIDs {
 		b.TagIDs = append(b.TagIDs, int(v))
 	}
 
@@ -53,42 +71,167 @@ func (r *Repository) GetBannerByFilter(ctx context.Context, featureID int, tagID
 	return &b, nil
 }
 
-// CreateBanner stores new banner into the storage.
+// getDefaultBannerQuery is the query for getting the best active default banner of the feature.
+const getDefaultBannerQuery = `SELECT id, tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window, created_at, updated_at, version 
+	FROM banners WHERE feature_id = $1 AND is_default = true AND is_active = true AND deleted_at IS NULL 
+	ORDER BY priority DESC, updated_at DESC, id DESC LIMIT 1`
+
+// GetDefaultBanner gets and returns the default banner of the feature from the storage.
+// If the feature has several default banners, the banner with the highest priority is returned.
+func (r *Repository) GetDefaultBanner(ctx context.Context, featureID int) (*banner.Banner, error) {
+	row := r.db.QueryRowContext(ctx, getDefaultBannerQuery, featureID)
+
+	var b banner.Banner
+	var tagIDs pq.Int64Array
+	err := row.Scan(&b.ID, &tagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("GetDefaultBanner: default banner not found in database %w", errs.ErrBannerNotFound)
+		}
+		return nil, fmt.Errorf("GetDefaultBanner: scan row failed %w", err)
+	}
+	for _, v := range tagIDs {
+		b.TagIDs = append(b.TagIDs, int(v))
+	}
+
+	err = row.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetDefaultBanner: row.Err %w", err)
+	}
+
+	return &b, nil
+}
+
+// getBannerVariantsQuery is the query for getting the active weighted variants
+// of the feature and any of the tags.
+const getBannerVariantsQuery = `SELECT id, tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window, created_at, updated_at, version 
+	FROM banners WHERE feature_id = $1 AND tag_ids && $2::integer[] AND is_active = true AND deleted_at IS NULL AND weight > 0 
+	ORDER BY id`
+
+// GetBannerVariants gets and returns the active weighted variants of the feature and any of the tags
+// from the storage ordered by ID with one query.
+func (r *Repository) GetBannerVariants(ctx context.Context, featureID int, tagIDs []int) ([]*banner.Banner, error) {
+	rows, err := r.db.QueryContext(ctx, getBannerVariantsQuery, featureID, tagIDs)
+	if err != nil {
+		return nil, fmt.Errorf("GetBannerVariants: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	variants := make([]*banner.Banner, 0)
+	for rows.Next() {
+		var b banner.Banner
+		var tagIDs pq.Int64Array
+		err = rows.Scan(&b.ID, &tagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version)
+		if err != nil {
+			return nil, fmt.Errorf("GetBannerVariants: scan row failed %w", err)
+		}
+		for _, v := range tagIDs {
+			b.TagIDs = append(b.TagIDs, int(v))
+		}
+		variants = append(variants, &b)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetBannerVariants: rows.Err %w", err)
+	}
+
+	return variants, nil
+}
+
+// GetBannerByID gets and returns the requested by ID banner from the storage,
+// including the deleted one.
+func (r *Repository) GetBannerByID(ctx context.Context, id int) (*banner.Banner, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT id, tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window, created_at, updated_at, version, deleted_at 
+	FROM banners WHERE id = $1`, id)
+
+	var b banner.Banner
+	var tagIDs pq.Int64Array
+	err := row.Scan(&b.ID, &tagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version, &b.DeletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("GetBannerByID: banner not found in database %w", errs.ErrBannerNotFound)
+		}
+		return nil, fmt.Errorf("GetBannerByID: scan row failed %w", err)
+	}
+	for _, v := range tagIDs {
+		b.TagIDs = append(b.TagIDs, int(v))
+	}
+
+	err = row.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetBannerByID: row.Err %w", err)
+	}
+
+	return &b, nil
+}
+
+// CreateBanner stores new banner with its outbox event and audit log entry into the storage in one transaction.
 func (r *Repository) CreateBanner(ctx context.Context, b *banner.Banner) (*banner.Banner, error) {
-	row := r.db.QueryRowContext(ctx, `INSERT INTO banners (tag_ids, feature_id, content, is_active) 
-	VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`, b.TagIDs, b.FeatureID, b.Content, b.IsActive)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("CreateBanner: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	stored, err := createBanner(ctx, tx, b)
+	if err != nil {
+		return nil, fmt.Errorf("CreateBanner: create banner failed %w", err)
+	}
 
-	var id int
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("CreateBanner: commit transaction failed %w", err)
+	}
+
+	return stored, nil
+}
+
+// createBanner stores new banner with its outbox event and audit log entry using the transaction.
+func createBanner(ctx context.Context, q querier, b *banner.Banner) (*banner.Banner, error) {
+	row := q.QueryRowContext(ctx, `INSERT INTO banners (tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at, updated_at, version`, b.TagIDs, b.FeatureID, b.Content, b.Locales, b.IsActive, b.Weight, b.Priority, b.IsDefault, b.Cap.Impressions, b.Cap.Window)
+
+	var id, version int
 	var createdAt, updatedAt time.Time
-	err := row.Scan(&id, &createdAt, &updatedAt)
+	err := row.Scan(&id, &createdAt, &updatedAt, &version)
 	if err != nil {
-		return nil, fmt.Errorf("CreateBanner: scan row failed %w", err)
+		return nil, fmt.Errorf("createBanner: scan row failed %w", err)
 	}
 
 	b.ID = id
 	b.CreatedAt = createdAt
 	b.UpdatedAt = updatedAt
+	b.Version = version
+	b.DeletedAt = nil
 
 	err = row.Err()
 	if err != nil {
-		return nil, fmt.Errorf("CreateBanner: row.Err %w", err)
+		return nil, fmt.Errorf("createBanner: row.Err %w", err)
+	}
+
+	err = recordChange(ctx, q, audit.ActionCreated, b.ID, b.Version, nil, b)
+	if err != nil {
+		return nil, fmt.Errorf("createBanner: record change failed %w", err)
 	}
 
 	return b, nil
 }
 
 // GetBannersByFilter gets and returns the banners by filter from the storage.
-func (r *Repository) GetBannersByFilter(ctx context.Context, featureID int, tagID int, limit int, offset int) ([]*banner.Banner, error) {
-	query := "SELECT id, tag_ids, feature_id, content, is_active, created_at, updated_at FROM banners"
-	if featureID != 0 || tagID != 0 {
-		query += " WHERE"
-		if featureID != 0 && tagID == 0 {
-			query += fmt.Sprintf(" feature_id = %d", featureID)
-		} else if featureID == 0 && tagID != 0 {
-			query += fmt.Sprintf(" %d = ANY (tag_ids)", tagID)
-		} else {
-			query += fmt.Sprintf(" feature_id = %d AND %d = ANY (tag_ids)", featureID, tagID)
-		}
+// Only deleted banners are returned if requested, otherwise only not deleted ones.
+func (r *Repository) GetBannersByFilter(ctx context.Context, featureID int, tagID int, limit int, offset int, deleted bool) ([]*banner.Banner, error) {
+	query := "SELECT id, tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window, created_at, updated_at, version, deleted_at FROM banners"
+	if deleted {
+		query += " WHERE deleted_at IS NOT NULL"
+	} else {
+		query += " WHERE deleted_at IS NULL"
+	}
+	if featureID != 0 {
+		query += fmt.Sprintf(" AND feature_id = %d", featureID)
+	}
+	if tagID != 0 {
+		query += fmt.Sprintf(" AND %d = ANY (tag_ids)", tagID)
 	}
 
 	query += " ORDER BY updated_at DESC"
@@ -108,7 +251,7 @@ func (r *Repository) GetBannersByFilter(ctx context.Context, featureID int, tagI
 	for rows.Next() {
 		var b banner.Banner
 		var tagIDs pq.Int64Array
-		err = rows.Scan(&b.ID, &tagIDs, &b.FeatureID, &b.Content, &b.IsActive, &b.CreatedAt, &b.UpdatedAt)
+		err = rows.Scan(&b.ID, &tagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version, &b.DeletedAt)
 		if err != nil {
 			return nil, fmt.Errorf("GetBannersByFilter: scan row failed %w", err)
 		}
@@ -126,45 +269,246 @@ func (r *Repository) GetBannersByFilter(ctx context.Context, featureID int, tagI
 	return bannersList, nil
 }
 
-// UpdateBanner updates requested banner in the storage.
+// UpdateBanner updates requested banner with its outbox event and audit log entry in the storage in one transaction,
+// if its stored version equals to the banner version. Zero banner version means no version check.
 func (r *Repository) UpdateBanner(ctx context.Context, b *banner.Banner) (*banner.Banner, error) {
-	row := r.db.QueryRowContext(ctx, `UPDATE banners SET tag_ids = $1, feature_id = $2, content = $3, is_active = $4,
-	updated_at = NOW() WHERE id = $5 RETURNING updated_at`, b.TagIDs, b.FeatureID, b.Content, b.IsActive, b.ID)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateBanner: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	stored, err := updateBanner(ctx, tx, b)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateBanner: update banner failed %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("UpdateBanner: commit transaction failed %w", err)
+	}
+
+	return stored, nil
+}
+
+// updateBanner updates requested banner with its outbox event and audit log entry using the transaction.
+// The banner state before the update is locked until the end of the transaction.
+func updateBanner(ctx context.Context, q querier, b *banner.Banner) (*banner.Banner, error) {
+	before, err := getBannerForUpdate(ctx, q, b.ID)
+	if err != nil {
+		return nil, fmt.Errorf("updateBanner: nothing to update, %w", err)
+	}
+
+	row := q.QueryRowContext(ctx, `UPDATE banners SET tag_ids = $1, feature_id = $2, content = $3, localized_content = $4, is_active = $5, weight = $6, priority = $7, is_default = $8, cap_impressions = $9, cap_window = $10,
+	updated_at = NOW(), version = version + 1 WHERE id = $11 AND deleted_at IS NULL AND ($12 = 0 OR version = $12) 
+	RETURNING updated_at, version`, b.TagIDs, b.FeatureID, b.Content, b.Locales, b.IsActive, b.Weight, b.Priority, b.IsDefault, b.Cap.Impressions, b.Cap.Window, b.ID, b.Version)
 
 	var updatedAt time.Time
-	err := row.Scan(&updatedAt)
+	var version int
+	err = row.Scan(&updatedAt, &version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("UpdateBanner: nothing to update, %w", errs.ErrBannerNotFound)
+			return nil, fmt.Errorf("updateBanner: nothing to update, %w", unchangedError(ctx, q, b.ID))
 		}
-		return nil, fmt.Errorf("UpdateBanner: scan row failed %w", err)
+		return nil, fmt.Errorf("updateBanner: scan row failed %w", err)
 	}
 
 	b.UpdatedAt = updatedAt
+	b.Version = version
 
 	err = row.Err()
 	if err != nil {
-		return nil, fmt.Errorf("UpdateBanner: row.Err %w", err)
+		return nil, fmt.Errorf("updateBanner: row.Err %w", err)
+	}
+
+	err = recordChange(ctx, q, banner.ChangeAction(before, b), b.ID, b.Version, before, b)
+	if err != nil {
+		return nil, fmt.Errorf("updateBanner: record change failed %w", err)
 	}
 
 	return b, nil
 }
 
-// DeleteBannerByID deletes the requested by ID banner from the storage.
-func (r *Repository) DeleteBannerByID(ctx context.Context, id int) error {
-	res, err := r.db.ExecContext(ctx, `DELETE FROM banners WHERE id = $1`, id)
+// SaveBanners creates the banners without ID and updates the banners with ID in one
+// transaction. Nothing is stored, if any of the banners could not be saved.
+func (r *Repository) SaveBanners(ctx context.Context, banners []*banner.Banner) ([]*banner.Banner, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("SaveBanners: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	saved := make([]*banner.Banner, 0, len(banners))
+	for i, b := range banners {
+		var stored *banner.Banner
+		if b.ID == 0 {
+			stored, err = createBanner(ctx, tx, b)
+		} else {
+			stored, err = updateBanner(ctx, tx, b)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("SaveBanners: save banner failed %w", &errs.BatchItemError{Index: i, Err: err})
+		}
+		saved = append(saved, stored)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("SaveBanners: commit transaction failed %w", err)
+	}
+
+	return saved, nil
+}
+
+// DeleteBannerByID marks the requested by ID banner in the storage as deleted with its outbox
+// event and audit log entry in one transaction, if its stored version equals to the requested version.
+// Zero version means no version check.
+func (r *Repository) DeleteBannerByID(ctx context.Context, id int, version int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteBannerByID: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
 
+	before, err := getBannerForUpdate(ctx, tx, id)
 	if err != nil {
+		return fmt.Errorf("DeleteBannerByID: nothing to delete, %w", err)
+	}
+
+	row := tx.QueryRowContext(ctx, `UPDATE banners SET deleted_at = NOW(), version = version + 1 
+	WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2) RETURNING version`, id, version)
+
+	var deletedVersion int
+	err = row.Scan(&deletedVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("DeleteBannerByID: nothing to delete, %w", unchangedError(ctx, tx, id))
+		}
 		return fmt.Errorf("DeleteBannerByID: delete data failed %w", err)
 	}
 
-	rowsCount, err := res.RowsAffected()
+	err = recordChange(ctx, tx, audit.ActionDeleted, id, deletedVersion, before, nil)
 	if err != nil {
-		return fmt.Errorf("DeleteBannerByID: couldn't get rows affected %w", err)
+		return fmt.Errorf("DeleteBannerByID: record change failed %w", err)
 	}
-	if rowsCount == 0 {
-		return fmt.Errorf("DeleteBannerByID: nothing to delete, %w", errs.ErrBannerNotFound)
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("DeleteBannerByID: commit transaction failed %w", err)
 	}
 
 	return nil
 }
+
+// RestoreBannerByID restores the requested by ID deleted banner in the storage with its outbox
+// event and audit log entry in one transaction and returns it.
+func (r *Repository) RestoreBannerByID(ctx context.Context, id int) (*banner.Banner, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("RestoreBannerByID: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, `UPDATE banners SET deleted_at = NULL, version = version + 1 
+	WHERE id = $1 AND deleted_at IS NOT NULL 
+	RETURNING id, tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window, created_at, updated_at, version`, id)
+
+	var b banner.Banner
+	var tagIDs pq.Int64Array
+	err = row.Scan(&b.ID, &tagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("RestoreBannerByID: nothing to restore, %w", errs.ErrBannerNotFound)
+		}
+		return nil, fmt.Errorf("RestoreBannerByID: scan row failed %w", err)
+	}
+	for _, v := range tagIDs {
+		b.TagIDs = append(b.TagIDs, int(v))
+	}
+
+	err = row.Err()
+	if err != nil {
+		return nil, fmt.Errorf("RestoreBannerByID: row.Err %w", err)
+	}
+
+	err = recordChange(ctx, tx, audit.ActionRestored, b.ID, b.Version, nil, &b)
+	if err != nil {
+		return nil, fmt.Errorf("RestoreBannerByID: record change failed %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("RestoreBannerByID: commit transaction failed %w", err)
+	}
+
+	return &b, nil
+}
+
+// getBannerForUpdate gets and returns the requested by ID not deleted banner
+// and locks it until the end of the transaction.
+func getBannerForUpdate(ctx context.Context, q querier, id int) (*banner.Banner, error) {
+	row := q.QueryRowContext(ctx, `SELECT id, tag_ids, feature_id, content, localized_content, is_active, weight, priority, is_default, cap_impressions, cap_window, created_at, updated_at, version 
+	FROM banners WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id)
+
+	var b banner.Banner
+	var tagIDs pq.Int64Array
+	err := row.Scan(&b.ID, &tagIDs, &b.FeatureID, &b.Content, &b.Locales, &b.IsActive, &b.Weight, &b.Priority, &b.IsDefault, &b.Cap.Impressions, &b.Cap.Window, &b.CreatedAt, &b.UpdatedAt, &b.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrBannerNotFound
+		}
+		return nil, fmt.Errorf("getBannerForUpdate: scan row failed %w", err)
+	}
+	for _, v := range tagIDs {
+		b.TagIDs = append(b.TagIDs, int(v))
+	}
+
+	return &b, nil
+}
+
+// unchangedError returns the reason why the banner was not changed: the version conflict,
+// if the not deleted banner exists, otherwise not found error.
+func unchangedError(ctx context.Context, q querier, id int) error {
+	row := q.QueryRowContext(ctx, `SELECT version FROM banners WHERE id = $1 AND deleted_at IS NULL`, id)
+
+	var version int
+	err := row.Scan(&version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.ErrBannerNotFound
+		}
+		return fmt.Errorf("unchangedError: scan row failed %w", err)
+	}
+
+	return errs.ErrBannerVersionConflict
+}
+
+// PurgeDeletedBanners removes the banners deleted before the requested time
+// from the storage and returns their number.
+func (r *Repository) PurgeDeletedBanners(ctx context.Context, deletedBefore time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM banners WHERE deleted_at < $1`, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeDeletedBanners: delete data failed %w", err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("PurgeDeletedBanners: couldn't get rows affected %w", err)
+	}
+
+	return int(rowsCount), nil
+}
+
+// ReplicationLag returns the replication lag of the storage,
+// which is zero if the storage is not a replica.
+func (r *Repository) ReplicationLag(ctx context.Context) (time.Duration, error) {
+	row := r.db.QueryRowContext(ctx, replicationLagQuery)
+
+	var seconds float64
+	err := row.Scan(&seconds)
+	if err != nil {
+		return 0, fmt.Errorf("ReplicationLag: scan row failed %w", err)
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
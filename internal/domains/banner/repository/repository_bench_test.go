@@ -0,0 +1,94 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/infra/database"
+)
+
+// benchBanner is stored before the benchmarks and requested by them.
+var benchBanner = &banner.Banner{
+	TagIDs:    []int{1001, 1002, 1003},
+	FeatureID: 1001,
+	Content: &banner.Content{
+		"title": "some_title",
+		"text":  "some_text",
+		"url":   "some_url",
+	},
+	IsActive: true,
+}
+
+// benchRepositories initializes both repository implementations against
+// the integration tests database and skips the benchmark if it is not available.
+func benchRepositories(b *testing.B) map[string]banner.Repository {
+	b.Helper()
+	ctx := context.Background()
+
+	requireDatabase(b)
+	dsn := testDatabaseDSN
+
+	db, err := database.Init(ctx, dsn)
+	if err != nil {
+		b.Fatalf("database initialization failed: %s", err)
+	}
+	b.Cleanup(func() { db.Close() })
+
+	_, err = db.ExecContext(ctx, resetTables)
+	if err != nil {
+		b.Fatalf("reset tables failed: %s", err)
+	}
+
+	pool, err := database.InitPool(ctx, &config.Config{DSN: dsn}, repository.PrepareStatements)
+	if err != nil {
+		b.Fatalf("database pool initialization failed: %s", err)
+	}
+	b.Cleanup(pool.Close)
+
+	repos := map[string]banner.Repository{
+		"sql": repository.NewBannerRepository(ctx, db),
+		"pgx": repository.NewBannerPoolRepository(ctx, pool),
+	}
+
+	stored, err := repos["sql"].CreateBanner(ctx, benchBanner)
+	if err != nil {
+		b.Fatalf("create banner failed: %s", err)
+	}
+	b.Cleanup(func() { repos["sql"].DeleteBannerByID(ctx, stored.ID, 0) })
+
+	return repos
+}
+
+func BenchmarkRepository_GetBannerByFilter(b *testing.B) {
+	ctx := context.Background()
+
+	for name, repo := range benchRepositories(b) {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := repo.GetBannerByFilter(ctx, benchBanner.FeatureID, benchBanner.TagIDs[1:2])
+				if err != nil {
+					b.Fatalf("get banner failed: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRepository_GetBannersByFilter(b *testing.B) {
+	ctx := context.Background()
+
+	for name, repo := range benchRepositories(b) {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := repo.GetBannersByFilter(ctx, benchBanner.FeatureID, 0, 10, 0, false)
+				if err != nil {
+					b.Fatalf("get banners failed: %s", err)
+				}
+			}
+		})
+	}
+}
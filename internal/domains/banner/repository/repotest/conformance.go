@@ -0,0 +1,716 @@
+// Package repotest contains the conformance test suite,
+// which every banners repository implementation must pass.
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns new empty repository for the single test.
+type Factory func(t *testing.T) banner.Repository
+
+// newBanner returns new banner with requested feature and tags.
+func newBanner(featureID int, tagIDs []int, isActive bool) *banner.Banner {
+	return &banner.Banner{
+		TagIDs:    tagIDs,
+		FeatureID: featureID,
+		Content: &banner.Content{
+			"title": "some_title",
+			"text":  "some_text",
+			"url":   "some_url",
+		},
+		IsActive: isActive,
+	}
+}
+
+// create stores the banners in the requested order and returns them.
+func create(t *testing.T, repo banner.Repository, banners ...*banner.Banner) []*banner.Banner {
+	t.Helper()
+
+	stored := make([]*banner.Banner, 0, len(banners))
+	for _, b := range banners {
+		got, err := repo.CreateBanner(context.Background(), b)
+		require.NoError(t, err)
+		stored = append(stored, got)
+	}
+
+	return stored
+}
+
+// ids returns IDs of the banners.
+func ids(banners []*banner.Banner) []int {
+	res := make([]int, 0, len(banners))
+	for _, b := range banners {
+		res = append(res, b.ID)
+	}
+
+	return res
+}
+
+// Run runs the conformance test suite against the repositories from factory.
+func Run(t *testing.T, factory Factory) {
+	t.Run("CreateBanner", func(t *testing.T) { testCreateBanner(t, factory(t)) })
+	t.Run("GetBannerByFilter", func(t *testing.T) { testGetBannerByFilter(t, factory(t)) })
+	t.Run("GetBannerVariants", func(t *testing.T) { testGetBannerVariants(t, factory(t)) })
+	t.Run("GetDefaultBanner", func(t *testing.T) { testGetDefaultBanner(t, factory(t)) })
+	t.Run("GetBannerByID", func(t *testing.T) { testGetBannerByID(t, factory(t)) })
+	t.Run("GetBannersByFilter", func(t *testing.T) { testGetBannersByFilter(t, factory(t)) })
+	t.Run("UpdateBanner", func(t *testing.T) { testUpdateBanner(t, factory(t)) })
+	t.Run("SaveBanners", func(t *testing.T) { testSaveBanners(t, factory(t)) })
+	t.Run("DeleteBannerByID", func(t *testing.T) { testDeleteBannerByID(t, factory(t)) })
+	t.Run("Version", func(t *testing.T) { testVersion(t, factory(t)) })
+	t.Run("RestoreBannerByID", func(t *testing.T) { testRestoreBannerByID(t, factory(t)) })
+	t.Run("PurgeDeletedBanners", func(t *testing.T) { testPurgeDeletedBanners(t, factory(t)) })
+	t.Run("Outbox", func(t *testing.T) { testOutbox(t, factory(t)) })
+}
+
+func testCreateBanner(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	stored := create(t, repo,
+		newBanner(1, []int{1, 2}, true),
+		newBanner(1, []int{3}, false),
+	)
+
+	assert.Positive(t, stored[0].ID)
+	assert.Greater(t, stored[1].ID, stored[0].ID)
+	for _, b := range stored {
+		assert.False(t, b.CreatedAt.IsZero())
+		assert.Equal(t, b.CreatedAt, b.UpdatedAt)
+	}
+
+	list, err := repo.GetBannersByFilter(ctx, 0, 0, 0, 0, false)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	got := list[1]
+	assert.Equal(t, stored[0].ID, got.ID)
+	assert.Equal(t, []int{1, 2}, got.TagIDs)
+	assert.Equal(t, 1, got.FeatureID)
+	assert.Equal(t, newBanner(0, nil, true).Content, got.Content)
+	assert.True(t, got.IsActive)
+	assert.True(t, stored[0].CreatedAt.Equal(got.CreatedAt))
+	assert.True(t, stored[0].UpdatedAt.Equal(got.UpdatedAt))
+
+	// The new banner is never deleted, even if the deleted one is passed
+	deletedAt := time.Now()
+	deleted := newBanner(1, []int{4}, true)
+	deleted.DeletedAt = &deletedAt
+	deleted.Version = 5
+	created, err := repo.CreateBanner(ctx, deleted)
+	require.NoError(t, err)
+	assert.Nil(t, created.DeletedAt)
+	assert.Equal(t, 1, created.Version)
+
+	got, err = repo.GetBannerByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.DeletedAt)
+
+	got, err = repo.GetBannerByFilter(ctx, 1, []int{4})
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+}
+
+func testGetBannerByFilter(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	prioritized := func(b *banner.Banner, priority int) *banner.Banner {
+		b.Priority = priority
+		return b
+	}
+	stored := create(t, repo,
+		newBanner(1, []int{1, 2, 3}, true),
+		newBanner(1, []int{2}, true),
+		newBanner(1, []int{3}, false),
+		newBanner(2, []int{1}, true),
+		prioritized(newBanner(1, []int{4, 5}, true), 10),
+		newBanner(1, []int{5}, true),
+	)
+
+	tests := []struct {
+		name      string
+		featureID int
+		tagIDs    []int
+		wantID    int
+		wantErr   error
+	}{
+		{
+			name:      "single match",
+			featureID: 1,
+			tagIDs:    []int{1},
+			wantID:    stored[0].ID,
+		},
+		{
+			name:      "latest updated match",
+			featureID: 1,
+			tagIDs:    []int{2},
+			wantID:    stored[1].ID,
+		},
+		{
+			name:      "inactive banner skipped",
+			featureID: 1,
+			tagIDs:    []int{3},
+			wantID:    stored[0].ID,
+		},
+		{
+			name:      "other feature",
+			featureID: 2,
+			tagIDs:    []int{1},
+			wantID:    stored[3].ID,
+		},
+		{
+			name:      "several tags",
+			featureID: 1,
+			tagIDs:    []int{2, 1},
+			wantID:    stored[1].ID,
+		},
+		{
+			name:      "priority over latest updated",
+			featureID: 1,
+			tagIDs:    []int{5},
+			wantID:    stored[4].ID,
+		},
+		{
+			name:      "priority over other tags",
+			featureID: 1,
+			tagIDs:    []int{1, 4},
+			wantID:    stored[4].ID,
+		},
+		{
+			name:      "unknown and known tags",
+			featureID: 2,
+			tagIDs:    []int{2, 1},
+			wantID:    stored[3].ID,
+		},
+		{
+			name:      "unknown tag",
+			featureID: 2,
+			tagIDs:    []int{2},
+			wantErr:   errs.ErrBannerNotFound,
+		},
+		{
+			name:      "unknown feature",
+			featureID: 3,
+			tagIDs:    []int{1},
+			wantErr:   errs.ErrBannerNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetBannerByFilter(ctx, tt.featureID, tt.tagIDs)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantID, got.ID)
+		})
+	}
+}
+
+func testGetBannerVariants(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	weighted := func(b *banner.Banner, weight int) *banner.Banner {
+		b.Weight = weight
+		return b
+	}
+	stored := create(t, repo,
+		weighted(newBanner(1, []int{1, 2}, true), 70),
+		weighted(newBanner(1, []int{1}, true), 0),
+		weighted(newBanner(1, []int{1}, false), 50),
+		weighted(newBanner(1, []int{1, 3}, true), 30),
+		weighted(newBanner(2, []int{1}, true), 100),
+	)
+
+	got, err := repo.GetBannerVariants(ctx, 1, []int{1})
+	require.NoError(t, err)
+	assert.Equal(t, []int{stored[0].ID, stored[3].ID}, ids(got))
+	assert.Equal(t, []int{70, 30}, []int{got[0].Weight, got[1].Weight})
+	assert.Equal(t, stored[0].TagIDs, got[0].TagIDs)
+
+	got, err = repo.GetBannerVariants(ctx, 1, []int{2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, []int{stored[0].ID, stored[3].ID}, ids(got))
+
+	err = repo.DeleteBannerByID(ctx, stored[0].ID, 0)
+	require.NoError(t, err)
+
+	got, err = repo.GetBannerVariants(ctx, 1, []int{1})
+	require.NoError(t, err)
+	assert.Equal(t, []int{stored[3].ID}, ids(got))
+
+	got, err = repo.GetBannerVariants(ctx, 1, []int{2})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	got, err = repo.GetBannerVariants(ctx, 1, []int{2, 3, 4})
+	require.NoError(t, err)
+	assert.Equal(t, []int{stored[3].ID}, ids(got))
+}
+
+func testGetDefaultBanner(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	byDefault := func(b *banner.Banner, priority int) *banner.Banner {
+		b.IsDefault = true
+		b.Priority = priority
+		return b
+	}
+	stored := create(t, repo,
+		byDefault(newBanner(1, []int{1}, true), 0),
+		byDefault(newBanner(1, []int{2}, true), 5),
+		byDefault(newBanner(1, []int{3}, false), 10),
+		byDefault(newBanner(2, []int{1}, true), 0),
+		newBanner(1, []int{1}, true),
+	)
+
+	got, err := repo.GetDefaultBanner(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, stored[1].ID, got.ID)
+	assert.True(t, got.IsDefault)
+	assert.Equal(t, []int{2}, got.TagIDs)
+
+	err = repo.DeleteBannerByID(ctx, stored[1].ID, 0)
+	require.NoError(t, err)
+
+	got, err = repo.GetDefaultBanner(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, stored[0].ID, got.ID)
+
+	_, err = repo.GetDefaultBanner(ctx, 3)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+}
+
+func testGetBannerByID(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	stored := create(t, repo,
+		newBanner(1, []int{1, 2}, false),
+		newBanner(2, []int{3}, true),
+	)
+
+	got, err := repo.GetBannerByID(ctx, stored[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, stored[0].ID, got.ID)
+	assert.Equal(t, []int{1, 2}, got.TagIDs)
+	assert.Equal(t, 1, got.FeatureID)
+	assert.Equal(t, newBanner(0, nil, true).Content, got.Content)
+	assert.False(t, got.IsActive)
+	assert.True(t, stored[0].CreatedAt.Equal(got.CreatedAt))
+	assert.Nil(t, got.DeletedAt)
+
+	// Deleted banners are returned with deletion time
+	err = repo.DeleteBannerByID(ctx, stored[1].ID, 0)
+	require.NoError(t, err)
+
+	got, err = repo.GetBannerByID(ctx, stored[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, stored[1].ID, got.ID)
+	assert.NotNil(t, got.DeletedAt)
+
+	_, err = repo.GetBannerByID(ctx, stored[1].ID+100)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+}
+
+func testGetBannersByFilter(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	stored := create(t, repo,
+		newBanner(1, []int{1, 2}, true),
+		newBanner(1, []int{2, 3}, false),
+		newBanner(2, []int{1}, true),
+		newBanner(2, []int{3}, true),
+	)
+
+	tests := []struct {
+		name      string
+		featureID int
+		tagID     int
+		limit     int
+		offset    int
+		want      []int
+	}{
+		{
+			name: "all banners from newest",
+			want: ids([]*banner.Banner{stored[3], stored[2], stored[1], stored[0]}),
+		},
+		{
+			name:      "by feature",
+			featureID: 1,
+			want:      ids([]*banner.Banner{stored[1], stored[0]}),
+		},
+		{
+			name:  "by tag",
+			tagID: 1,
+			want:  ids([]*banner.Banner{stored[2], stored[0]}),
+		},
+		{
+			name:      "by feature and tag",
+			featureID: 1,
+			tagID:     3,
+			want:      ids([]*banner.Banner{stored[1]}),
+		},
+		{
+			name:   "limit and offset",
+			limit:  2,
+			offset: 1,
+			want:   ids([]*banner.Banner{stored[2], stored[1]}),
+		},
+		{
+			name:   "offset beyond the end",
+			offset: 10,
+			want:   []int{},
+		},
+		{
+			name:      "nothing matched",
+			featureID: 3,
+			want:      []int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetBannersByFilter(ctx, tt.featureID, tt.tagID, tt.limit, tt.offset, false)
+			require.NoError(t, err)
+			require.NotNil(t, got)
+			assert.Equal(t, tt.want, ids(got))
+		})
+	}
+}
+
+func testUpdateBanner(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	stored := create(t, repo,
+		newBanner(1, []int{1}, true),
+		newBanner(1, []int{1}, true),
+	)
+	createdAt, updatedAt := stored[0].CreatedAt, stored[0].UpdatedAt
+
+	update := newBanner(2, []int{4, 5}, false)
+	update.ID = stored[0].ID
+	update.Cap = banner.Cap{Impressions: 3, Window: 3600}
+	update.IsDefault = true
+	update.Locales = banner.Locales{"ru": {"title": "новый заголовок"}}
+	(*update.Content)["title"] = "new_title"
+
+	got, err := repo.UpdateBanner(ctx, update)
+	require.NoError(t, err)
+	assert.True(t, got.UpdatedAt.After(updatedAt))
+
+	list, err := repo.GetBannersByFilter(ctx, 2, 0, 0, 0, false)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, stored[0].ID, list[0].ID)
+	assert.Equal(t, []int{4, 5}, list[0].TagIDs)
+	assert.Equal(t, "new_title", (*list[0].Content)["title"])
+	assert.False(t, list[0].IsActive)
+	assert.Equal(t, update.Cap, list[0].Cap)
+	assert.True(t, list[0].IsDefault)
+	assert.Equal(t, update.Locales, list[0].Locales)
+	assert.True(t, createdAt.Equal(list[0].CreatedAt))
+	assert.True(t, got.UpdatedAt.Equal(list[0].UpdatedAt))
+
+	// Updated banner becomes the newest one
+	reactivate := newBanner(1, []int{1}, true)
+	reactivate.ID = stored[0].ID
+	_, err = repo.UpdateBanner(ctx, reactivate)
+	require.NoError(t, err)
+
+	actual, err := repo.GetBannerByFilter(ctx, 1, []int{1})
+	require.NoError(t, err)
+	assert.Equal(t, stored[0].ID, actual.ID)
+
+	missing := newBanner(1, []int{1}, true)
+	missing.ID = stored[1].ID + 100
+	_, err = repo.UpdateBanner(ctx, missing)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+}
+
+func testSaveBanners(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	stored := create(t, repo, newBanner(1, []int{1}, true))
+
+	update := newBanner(1, []int{2}, false)
+	update.ID = stored[0].ID
+	update.Version = stored[0].Version
+
+	saved, err := repo.SaveBanners(ctx, []*banner.Banner{
+		newBanner(2, []int{1}, true),
+		update,
+		newBanner(3, []int{1}, true),
+	})
+	require.NoError(t, err)
+	require.Len(t, saved, 3)
+	assert.Greater(t, saved[0].ID, stored[0].ID)
+	assert.Equal(t, 1, saved[0].Version)
+	assert.Equal(t, stored[0].ID, saved[1].ID)
+	assert.Equal(t, stored[0].Version+1, saved[1].Version)
+	assert.Greater(t, saved[2].ID, saved[0].ID)
+
+	got, err := repo.GetBannerByID(ctx, stored[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, got.TagIDs)
+	assert.False(t, got.IsActive)
+	assert.True(t, stored[0].CreatedAt.Equal(got.CreatedAt))
+
+	// Nothing is stored, if any of the banners could not be saved
+	stale := newBanner(1, []int{3}, true)
+	stale.ID = stored[0].ID
+	stale.Version = stored[0].Version
+
+	_, err = repo.SaveBanners(ctx, []*banner.Banner{
+		newBanner(4, []int{1}, true),
+		stale,
+	})
+	require.ErrorIs(t, err, errs.ErrBannerVersionConflict)
+	var itemErr *errs.BatchItemError
+	require.ErrorAs(t, err, &itemErr)
+	assert.Equal(t, 1, itemErr.Index)
+
+	list, err := repo.GetBannersByFilter(ctx, 4, 0, 0, 0, false)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	missing := newBanner(1, []int{1}, true)
+	missing.ID = saved[2].ID + 100
+	_, err = repo.SaveBanners(ctx, []*banner.Banner{missing})
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+}
+
+func testDeleteBannerByID(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	stored := create(t, repo,
+		newBanner(1, []int{1}, true),
+		newBanner(1, []int{2}, true),
+	)
+
+	err := repo.DeleteBannerByID(ctx, stored[0].ID, 0)
+	require.NoError(t, err)
+
+	_, err = repo.GetBannerByFilter(ctx, 1, []int{1})
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+
+	list, err := repo.GetBannersByFilter(ctx, 0, 0, 0, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, []int{stored[1].ID}, ids(list))
+
+	err = repo.DeleteBannerByID(ctx, stored[0].ID, 0)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+
+	// Deleted banners are listed only on request
+	deleted, err := repo.GetBannersByFilter(ctx, 1, 0, 0, 0, true)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, stored[0].ID, deleted[0].ID)
+	assert.NotNil(t, deleted[0].DeletedAt)
+
+	update := newBanner(1, []int{1}, true)
+	update.ID = stored[0].ID
+	_, err = repo.UpdateBanner(ctx, update)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+
+	err = repo.DeleteBannerByID(ctx, stored[1].ID+100, 0)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+}
+
+func testVersion(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	stored := create(t, repo,
+		newBanner(1, []int{1}, true),
+	)
+	assert.Equal(t, 1, stored[0].Version)
+
+	// Update with the actual version
+	update := newBanner(1, []int{1, 2}, true)
+	update.ID = stored[0].ID
+	update.Version = 1
+	got, err := repo.UpdateBanner(ctx, update)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Version)
+
+	// Update with the stale version
+	stale := newBanner(1, []int{3}, true)
+	stale.ID = stored[0].ID
+	stale.Version = 1
+	_, err = repo.UpdateBanner(ctx, stale)
+	assert.ErrorIs(t, err, errs.ErrBannerVersionConflict)
+
+	actual, err := repo.GetBannerByID(ctx, stored[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, actual.Version)
+	assert.Equal(t, []int{1, 2}, actual.TagIDs)
+
+	// Update without version check
+	stale.Version = 0
+	got, err = repo.UpdateBanner(ctx, stale)
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Version)
+
+	err = repo.DeleteBannerByID(ctx, stored[0].ID, 2)
+	assert.ErrorIs(t, err, errs.ErrBannerVersionConflict)
+
+	err = repo.DeleteBannerByID(ctx, stored[0].ID, 3)
+	require.NoError(t, err)
+
+	// Deleted banner is not found for any version
+	err = repo.DeleteBannerByID(ctx, stored[0].ID, 4)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+
+	restored, err := repo.RestoreBannerByID(ctx, stored[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 5, restored.Version)
+}
+
+func testRestoreBannerByID(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	stored := create(t, repo,
+		newBanner(1, []int{1, 2}, true),
+	)
+
+	_, err := repo.RestoreBannerByID(ctx, stored[0].ID)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+
+	err = repo.DeleteBannerByID(ctx, stored[0].ID, 0)
+	require.NoError(t, err)
+
+	got, err := repo.RestoreBannerByID(ctx, stored[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, stored[0].ID, got.ID)
+	assert.Equal(t, []int{1, 2}, got.TagIDs)
+	assert.Equal(t, 1, got.FeatureID)
+	assert.True(t, got.IsActive)
+	assert.Nil(t, got.DeletedAt)
+	assert.True(t, stored[0].UpdatedAt.Equal(got.UpdatedAt))
+
+	actual, err := repo.GetBannerByFilter(ctx, 1, []int{2})
+	require.NoError(t, err)
+	assert.Equal(t, stored[0].ID, actual.ID)
+
+	deleted, err := repo.GetBannersByFilter(ctx, 0, 0, 0, 0, true)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	_, err = repo.RestoreBannerByID(ctx, stored[0].ID+100)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+}
+
+func testPurgeDeletedBanners(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	stored := create(t, repo,
+		newBanner(1, []int{1}, true),
+		newBanner(1, []int{2}, true),
+	)
+
+	err := repo.DeleteBannerByID(ctx, stored[0].ID, 0)
+	require.NoError(t, err)
+
+	// Recently deleted banners are kept
+	count, err := repo.PurgeDeletedBanners(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	count, err = repo.PurgeDeletedBanners(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, err = repo.RestoreBannerByID(ctx, stored[0].ID)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+
+	deleted, err := repo.GetBannersByFilter(ctx, 0, 0, 0, 0, true)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	list, err := repo.GetBannersByFilter(ctx, 0, 0, 0, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, []int{stored[1].ID}, ids(list))
+}
+
+func testOutbox(t *testing.T, repo banner.Repository) {
+	ctx := context.Background()
+
+	stored := create(t, repo,
+		newBanner(1, []int{1}, true),
+	)
+
+	update := newBanner(1, []int{1}, false)
+	update.ID = stored[0].ID
+	update.Version = 1
+	_, err := repo.UpdateBanner(ctx, update)
+	require.NoError(t, err)
+
+	// Failed change has no event
+	update.Version = 1
+	_, err = repo.UpdateBanner(ctx, update)
+	require.ErrorIs(t, err, errs.ErrBannerVersionConflict)
+
+	_, err = repo.SaveBanners(ctx, []*banner.Banner{newBanner(2, []int{1}, true)})
+	require.NoError(t, err)
+
+	err = repo.DeleteBannerByID(ctx, stored[0].ID, 0)
+	require.NoError(t, err)
+	_, err = repo.RestoreBannerByID(ctx, stored[0].ID)
+	require.NoError(t, err)
+
+	now := time.Now()
+	events, err := repo.ClaimOutboxEvents(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 5)
+
+	actions := make([]string, 0, len(events))
+	keys := make(map[string]struct{}, len(events))
+	for i, e := range events {
+		if i > 0 {
+			assert.Greater(t, e.ID, events[i-1].ID)
+		}
+		actions = append(actions, e.Action)
+		keys[e.Key] = struct{}{}
+	}
+	assert.Equal(t, []string{audit.ActionCreated, audit.ActionDeactivated, audit.ActionCreated,
+		audit.ActionDeleted, audit.ActionRestored}, actions)
+	assert.Len(t, keys, 5)
+
+	assert.Equal(t, stored[0].ID, events[1].BannerID)
+	assert.JSONEq(t, `true`, string(field(t, events[1].Before, "is_active")))
+	assert.JSONEq(t, `false`, string(field(t, events[1].After, "is_active")))
+	assert.Nil(t, events[0].Before)
+	assert.Nil(t, events[3].After)
+
+	// Claimed events are not claimed again until the lease expires
+	again, err := repo.ClaimOutboxEvents(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	err = repo.DeleteOutboxEvents(ctx, []int64{events[0].ID, events[1].ID})
+	require.NoError(t, err)
+
+	expired, err := repo.ClaimOutboxEvents(ctx, now.Add(2*time.Minute), time.Minute, 2)
+	require.NoError(t, err)
+	require.Len(t, expired, 2)
+	assert.Equal(t, events[2].ID, expired[0].ID)
+	assert.Equal(t, events[3].ID, expired[1].ID)
+}
+
+// field returns the JSON field of the banner state.
+func field(t *testing.T, state json.RawMessage, name string) json.RawMessage {
+	t.Helper()
+
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(state, &fields))
+
+	return fields[name]
+}
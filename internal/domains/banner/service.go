@@ -4,67 +4,451 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"time"
 
 	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
 	"github.com/pavlegich/banners-service/internal/utils"
+	"go.uber.org/zap"
 )
 
 // BannerService contains objects for banner service.
 type BannerService struct {
-	repo  Repository
-	cache Cache
+	repo    Repository
+	cache   Cache
+	catalog Catalog
+	broker  *Broker
+	tracker Tracker
+	capper  Capper
+
+	templateVars []string
+	templates    *templateCache
 }
 
-// NewBannerService returns new banner service.
-func NewBannerService(ctx context.Context, repo Repository, cache Cache) *BannerService {
+// NewBannerService returns new banner service. The referenced features and tags
+// are checked in the catalog, the banner changes are published into the broker
+// and the banners shown to users are counted by the tracker and limited by the capper,
+// if they are set. The banner content templates might use the configured template variables.
+// The changes are recorded into the audit log by the repository with the changes themselves.
+func NewBannerService(ctx context.Context, repo Repository, cache Cache, catalog Catalog, broker *Broker, tracker Tracker, capper Capper, templateVars []string) *BannerService {
 	return &BannerService{
-		repo:  repo,
-		cache: cache,
+		repo:    repo,
+		cache:   cache,
+		catalog: catalog,
+		broker:  broker,
+		tracker: tracker,
+		capper:  capper,
+
+		templateVars: templateVars,
+		templates:    newTemplateCache(templateCacheSize, templateVars),
 	}
 }
 
-// Unload gets banner by filter and returns it.
-func (s *BannerService) Unload(ctx context.Context, featureID int, tagID int, lastRevision bool) (*Content, error) {
+// Unload gets the best banner of the feature for any of the tags and returns it.
+// If the pairs have the weighted variants, one of them is chosen for the user
+// from the context. If no banner of the tags is found, active or allowed by the
+// frequency cap, the default banner of the feature is returned, if it is set.
+// The banner returned to the user is counted as the impression for the first
+// of the tags it matches. The banner the client already has, reported by
+// the optional notModified, is not shown again, so it is not counted.
+func (s *BannerService) Unload(ctx context.Context, featureID int, tagIDs []int, lastRevision bool, notModified func(*Banner) bool) (*Banner, error) {
 	userRole, err := utils.GetUserRoleFromContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Unload: get user role from context failed %w", err)
 	}
 
+	banner, err := s.userBanner(ctx, userRole, featureID, tagIDs, lastRevision, notModified)
+	if err != nil {
+		return nil, fmt.Errorf("Unload: get user banner failed %w", err)
+	}
+
+	return banner, nil
+}
+
+// userBanner resolves the banner of the feature for any of the tags shown to the user
+// with the role: the best banner or the chosen variant, the default banner otherwise.
+// The shown banner is limited by the frequency cap and counted as the impression.
+func (s *BannerService) userBanner(ctx context.Context, userRole string, featureID int, tagIDs []int, lastRevision bool, notModified func(*Banner) bool) (*Banner, error) {
+	shown := func(b *Banner) bool {
+		return userRole == "user" && (notModified == nil || !notModified(b))
+	}
+
+	banner, err := s.unload(ctx, userRole, featureID, tagIDs, lastRevision)
+	if err == nil && shown(banner) {
+		err = s.allow(ctx, banner)
+	}
+	if errors.Is(err, errs.ErrBannerNotFound) || errors.Is(err, errs.ErrBannerNotAllowed) || errors.Is(err, errs.ErrBannerCapReached) {
+		fallback, ferr := s.defaultBanner(ctx, featureID, lastRevision)
+		switch {
+		case ferr == nil && (banner == nil || fallback.ID != banner.ID):
+			logger.Log.Info("userBanner: default banner returned",
+				zap.Int("feature_id", featureID),
+				zap.Int("banner_id", fallback.ID),
+				zap.Error(err))
+
+			banner, err = fallback, nil
+			if shown(banner) {
+				err = s.allow(ctx, banner)
+			}
+		case ferr != nil && !errors.Is(ferr, errs.ErrBannerNotFound):
+			return nil, fmt.Errorf("userBanner: get default banner failed %w", ferr)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("userBanner: get banner failed %w", err)
+	}
+
+	if s.tracker != nil && shown(banner) {
+		s.tracker.TrackImpression(ctx, banner.ID, matchedTag(banner, tagIDs))
+	}
+
+	return banner, nil
+}
+
+// Localize returns the banner content for the most preferred of the accepted locales
+// and the resolved locale. The default locale of the feature is got from the catalog
+// only if none of the accepted locales is available.
+func (s *BannerService) Localize(ctx context.Context, banner *Banner, accepted []string) (*Content, string, error) {
+	content, locale := banner.Localize(accepted, "")
+	if locale != "" || len(banner.Locales) == 0 || s.catalog == nil {
+		return content, locale, nil
+	}
+
+	defaultLocale, err := s.catalog.DefaultLocale(ctx, banner.FeatureID)
+	if err != nil {
+		return nil, "", fmt.Errorf("Localize: get default locale of feature failed %w", err)
+	}
+
+	content, locale = banner.Localize(accepted, defaultLocale)
+	return content, locale, nil
+}
+
+// Render returns the content with the placeholders replaced by the request variables
+// and the values of the variables used by the placeholders. The params contain the values of the
+// configured variables, the other params are ignored. The content without placeholders
+// is returned as is. The parsed templates are cached, so every content value
+// is parsed once.
+func (s *BannerService) Render(ctx context.Context, banner *Banner, content *Content, tagIDs []int, locale string, params map[string]string) (*Content, map[string]string) {
+	used := make(map[string]string)
+	if content == nil {
+		return content, used
+	}
+
+	var vars map[string]string
+	rendered := make(Content, len(*content))
+	for k, v := range *content {
+		rendered[k] = v
+		if !isTemplate(v) {
+			continue
+		}
+
+		// The templates are validated on create, but the banners stored before might be broken
+		t, err := s.templates.get(v)
+		if err != nil {
+			logger.Log.Error("Render: content value is not a valid template",
+				zap.Int("banner_id", banner.ID),
+				zap.String("field", k),
+				zap.Error(err))
+
+			continue
+		}
+
+		if vars == nil {
+			vars = make(map[string]string, len(s.templateVars)+len(templateVars))
+			for _, name := range s.templateVars {
+				vars[name] = params[name]
+			}
+
+			// The built-in variables are not overridden by the configured ones
+			vars[VarUserID] = utils.GetUserIDFromContext(ctx)
+			vars[VarFeatureID] = strconv.Itoa(banner.FeatureID)
+			vars[VarLocale] = locale
+			vars[VarTagID] = ""
+			if len(tagIDs) != 0 {
+				vars[VarTagID] = strconv.Itoa(matchedTag(banner, tagIDs))
+			}
+		}
+
+		rendered[k] = t.Render(vars)
+		for _, name := range t.Variables() {
+			used[name] = vars[name]
+		}
+	}
+	if vars == nil {
+		return content, used
+	}
+
+	return &rendered, used
+}
+
+// defaultBanner gets the active default banner of the feature and returns it.
+// The banner read from the storage is put into cache for the feature, the feature
+// without default banner is cached as well.
+func (s *BannerService) defaultBanner(ctx context.Context, featureID int, lastRevision bool) (*Banner, error) {
+	if !lastRevision {
+		banner, err := s.cache.GetDefaultBanner(ctx, featureID)
+		switch {
+		case err == nil && banner == nil:
+			return nil, fmt.Errorf("defaultBanner: feature has no default banner %w", errs.ErrBannerNotFound)
+		case err == nil:
+			return banner, nil
+		case !errors.Is(err, errs.ErrBannerInCacheNotFound) && !errors.Is(err, errs.ErrBannerExpired):
+			return nil, fmt.Errorf("defaultBanner: get default banner from cache failed %w", err)
+		}
+	}
+
+	// Stale content is allowed without last revision, so the banner might be read from the replica
+	repoCtx := ctx
 	if !lastRevision {
-		banner, err := s.cache.GetBannerByFilter(ctx, featureID, tagID)
+		repoCtx = utils.WithReplicaRead(ctx)
+	}
+
+	banner, err := s.repo.GetDefaultBanner(repoCtx, featureID)
+	if err != nil && !errors.Is(err, errs.ErrBannerNotFound) {
+		return nil, fmt.Errorf("defaultBanner: get default banner failed %w", err)
+	}
+
+	cerr := s.cache.CreateDefaultBanner(ctx, featureID, banner)
+	if cerr != nil {
+		return nil, fmt.Errorf("defaultBanner: create default banner in cache failed %w", cerr)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("defaultBanner: feature has no default banner %w", err)
+	}
+	return banner, nil
+}
+
+// unload gets the best banner by filter for the user role and returns it. The variant
+// chosen for the tag competes with the best banners of the other tags by priority.
+func (s *BannerService) unload(ctx context.Context, userRole string, featureID int, tagIDs []int, lastRevision bool) (*Banner, error) {
+	tagVariants, err := s.variants(ctx, featureID, tagIDs, lastRevision)
+	if err != nil {
+		return nil, fmt.Errorf("unload: get banner variants failed %w", err)
+	}
+
+	var best *Banner
+	plain := make([]int, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		variants := tagVariants[tagID]
+		if len(variants) == 0 {
+			plain = append(plain, tagID)
+			continue
+		}
+
+		variant := chooseVariant(variants, featureID, tagID, utils.GetUserIDFromContext(ctx))
+		if best == nil || variant.Outranks(best) {
+			best = variant
+		}
+	}
+	if len(plain) == 0 {
+		return best, nil
+	}
+
+	banner, err := s.bestBanner(ctx, userRole, featureID, plain, lastRevision)
+	if err != nil {
+		// The variant of the other tag is returned, if the rest tags have no banner for the user
+		if best != nil && (errors.Is(err, errs.ErrBannerNotFound) || errors.Is(err, errs.ErrBannerNotAllowed)) {
+			return best, nil
+		}
+		return nil, fmt.Errorf("unload: get best banner failed %w", err)
+	}
+
+	if best != nil && best.Outranks(banner) {
+		return best, nil
+	}
+	return banner, nil
+}
 
+// bestBanner gets the best banner of the feature for any of the tags and returns it.
+// The banner read from the storage is put into cache for the filter.
+func (s *BannerService) bestBanner(ctx context.Context, userRole string, featureID int, tagIDs []int, lastRevision bool) (*Banner, error) {
+	if !lastRevision {
+		banner, err := s.cachedBanner(ctx, featureID, tagIDs)
 		if err != nil {
-			// If banner expired, delete banner from cache and get banner from the database
-			if errors.Is(err, errs.ErrBannerExpired) {
-				err := s.cache.DeleteBanner(ctx, 0, featureID, tagID)
-				if err != nil {
-					return nil, fmt.Errorf("Unload: delete banner from cache failed %w", err)
-				}
-				// If banner not found, get banner from the database
-			} else if !errors.Is(err, errs.ErrBannerInCacheNotFound) {
-				return nil, fmt.Errorf("Unload: get user banner content from cache failed %w", err)
-			}
-		} else { // If banner found, check whether the banner is active for user and return it
+			return nil, fmt.Errorf("bestBanner: get user banner content from cache failed %w", err)
+		}
+
+		// If banner found, check whether the banner is active for user and return it
+		if banner != nil {
 			if !banner.IsActive && userRole == "user" {
-				return nil, fmt.Errorf("Unload: banner currently not active for users %w", errs.ErrBannerNotAllowed)
+				return nil, fmt.Errorf("bestBanner: banner currently not active for users %w", errs.ErrBannerNotAllowed)
 			}
-			return banner.Content, nil
+			return banner, nil
 		}
 	}
 
-	banner, err := s.repo.GetBannerByFilter(ctx, featureID, tagID)
+	// Stale content is allowed without last revision, so the banner might be read from the replica
+	repoCtx := ctx
+	if !lastRevision {
+		repoCtx = utils.WithReplicaRead(ctx)
+	}
+
+	banner, err := s.repo.GetBannerByFilter(repoCtx, featureID, tagIDs)
+	if err != nil {
+		return nil, fmt.Errorf("bestBanner: get actual user banner content failed %w", err)
+	}
+
+	err = s.cache.CreateBannerByFilter(ctx, featureID, tagIDs, banner)
 	if err != nil {
-		return nil, fmt.Errorf("Unload: get actual user banner content failed %w", err)
+		return nil, fmt.Errorf("bestBanner: create banner in cache failed %w", err)
 	}
 
 	if !banner.IsActive && userRole == "user" {
-		return nil, fmt.Errorf("Unload: banner currently not active for users %w", errs.ErrBannerNotAllowed)
+		return nil, fmt.Errorf("bestBanner: banner currently not active for users %w", errs.ErrBannerNotAllowed)
+	}
+	return banner, nil
+}
+
+// matchedTag returns the first of the requested tags the banner matches.
+func matchedTag(banner *Banner, tagIDs []int) int {
+	for _, tagID := range tagIDs {
+		if slices.Contains(banner.TagIDs, tagID) {
+			return tagID
+		}
 	}
-	return banner.Content, nil
+	return tagIDs[0]
 }
 
-// Create creates new banner and puts it into the storage.
+// UnloadBatch gets banners for each of the feature and tag pairs the same way
+// as Unload and returns the results in the requested order. The banners returned
+// to the user are counted as the impressions for the tags and limited by the frequency caps.
+func (s *BannerService) UnloadBatch(ctx context.Context, pairs []Pair, lastRevision bool) ([]*UserBanner, error) {
+	userRole, err := utils.GetUserRoleFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("UnloadBatch: get user role from context failed %w", err)
+	}
+
+	results := make([]*UserBanner, len(pairs))
+	for i, p := range pairs {
+		res := &UserBanner{Pair: p}
+		results[i] = res
+
+		banner, err := s.userBanner(ctx, userRole, p.FeatureID, []int{p.TagID}, lastRevision, nil)
+		if err != nil {
+			res.Err = fmt.Errorf("UnloadBatch: get user banner failed %w", err)
+			continue
+		}
+		res.Banner = banner
+	}
+
+	return results, nil
+}
+
+// allow counts the banner impression for the user from the context and returns
+// the error, if the user reached the frequency cap of the banner. The anonymous
+// users are not limited, since their impressions can not be counted.
+func (s *BannerService) allow(ctx context.Context, banner *Banner) error {
+	userID := utils.GetUserIDFromContext(ctx)
+	if s.capper == nil || !banner.Cap.Enabled() || userID == "" {
+		return nil
+	}
+
+	ok, err := s.capper.Allow(ctx, banner.ID, userID, banner.Cap)
+	if err != nil {
+		return fmt.Errorf("allow: count banner impression failed %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("allow: banner %d shown to user too often %w", banner.ID, errs.ErrBannerCapReached)
+	}
+
+	return nil
+}
+
+// variants returns the active weighted variants of the feature for each of the tags. The variants
+// of the tags missed in cache are read from the storage with one query and put into cache by tag.
+func (s *BannerService) variants(ctx context.Context, featureID int, tagIDs []int, lastRevision bool) (map[int][]*Banner, error) {
+	variants := make(map[int][]*Banner, len(tagIDs))
+	missed := tagIDs
+	if !lastRevision {
+		missed = make([]int, 0, len(tagIDs))
+		for _, tagID := range tagIDs {
+			cached, err := s.cache.GetBannerVariants(ctx, featureID, tagID)
+			if err == nil {
+				variants[tagID] = cached
+				continue
+			}
+			if !errors.Is(err, errs.ErrBannerInCacheNotFound) && !errors.Is(err, errs.ErrBannerExpired) {
+				return nil, fmt.Errorf("variants: get banner variants from cache failed %w", err)
+			}
+			missed = append(missed, tagID)
+		}
+	}
+	if len(missed) == 0 {
+		return variants, nil
+	}
+
+	// Stale variants are allowed without last revision, so they might be read from the replica
+	repoCtx := ctx
+	if !lastRevision {
+		repoCtx = utils.WithReplicaRead(ctx)
+	}
+
+	stored, err := s.repo.GetBannerVariants(repoCtx, featureID, missed)
+	if err != nil {
+		return nil, fmt.Errorf("variants: get banner variants failed %w", err)
+	}
+
+	for _, tagID := range missed {
+		list := make([]*Banner, 0)
+		for _, v := range stored {
+			if slices.Contains(v.TagIDs, tagID) {
+				list = append(list, v)
+			}
+		}
+		variants[tagID] = list
+
+		err = s.cache.CreateBannerVariants(ctx, featureID, tagID, list)
+		if err != nil {
+			return nil, fmt.Errorf("variants: create banner variants in cache failed %w", err)
+		}
+	}
+
+	return variants, nil
+}
+
+// cachedBanner returns the banner by filter from cache or nil, if the banner
+// is not found in cache. The expired banner of the single tag is deleted from cache.
+func (s *BannerService) cachedBanner(ctx context.Context, featureID int, tagIDs []int) (*Banner, error) {
+	banner, err := s.cache.GetBannerByFilter(ctx, featureID, tagIDs)
+	if err == nil {
+		return banner, nil
+	}
+
+	// If banner expired, delete banner from cache and get banner from the database,
+	// the expired filters of several tags are replaced by the banner from the database
+	if errors.Is(err, errs.ErrBannerExpired) {
+		if len(tagIDs) == 1 {
+			err := s.cache.DeleteBanner(ctx, 0, featureID, tagIDs[0])
+			if err != nil {
+				return nil, fmt.Errorf("cachedBanner: delete banner from cache failed %w", err)
+			}
+		}
+		return nil, nil
+	}
+
+	// If banner not found, get banner from the database
+	if errors.Is(err, errs.ErrBannerInCacheNotFound) {
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("cachedBanner: get banner from cache failed %w", err)
+}
+
+// Create validates new banner and puts it into the storage.
 func (s *BannerService) Create(ctx context.Context, banner *Banner) (int, error) {
+	err := banner.Validate(s.templateVars)
+	if err != nil {
+		return -1, fmt.Errorf("Create: banner is invalid %w", err)
+	}
+
+	err = s.checkReferences(ctx, banner)
+	if err != nil {
+		return -1, fmt.Errorf("Create: check banner references failed %w", err)
+	}
+
 	storedBanner, err := s.repo.CreateBanner(ctx, banner)
 	if err != nil {
 		return -1, fmt.Errorf("Create: create banner failed %w", err)
@@ -75,37 +459,516 @@ func (s *BannerService) Create(ctx context.Context, banner *Banner) (int, error)
 		return -1, fmt.Errorf("Create: create banner in cache failed %w", err)
 	}
 
+	s.notify(ctx, nil, storedBanner)
+
 	return storedBanner.ID, nil
 }
 
 // List returns list of banners by filter stored in the storage.
-func (s *BannerService) List(ctx context.Context, featureID int, tagID int, limit int, offset int) ([]*Banner, error) {
-	bannersList, err := s.repo.GetBannersByFilter(ctx, featureID, tagID, limit, offset)
+// Only deleted banners are listed if requested.
+func (s *BannerService) List(ctx context.Context, featureID int, tagID int, limit int, offset int, deleted bool) ([]*Banner, error) {
+	bannersList, err := s.repo.GetBannersByFilter(ctx, featureID, tagID, limit, offset, deleted)
 	if err != nil {
 		return nil, fmt.Errorf("List: get banners list by filter failed %w", err)
 	}
 
+	err = s.describe(ctx, bannersList...)
+	if err != nil {
+		return nil, fmt.Errorf("List: describe banners failed %w", err)
+	}
+
 	return bannersList, nil
 }
 
-// Update updates the requested banner.
-func (s *BannerService) Update(ctx context.Context, banner *Banner) error {
+// Update applies the patch to the requested banner, if the stored banner version
+// equals to the requested version. Zero version means no version check.
+// The patched banner is validated and stored only if the banner
+// has not been changed since it has been read.
+func (s *BannerService) Update(ctx context.Context, id int, version int, patch *Patch) (*Banner, error) {
+	before, err := s.repo.GetBannerByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("Update: get banner before update failed %w", err)
+	}
+
+	if before.DeletedAt != nil {
+		return nil, fmt.Errorf("Update: banner is deleted %w", errs.ErrBannerNotFound)
+	}
+
+	if version != 0 && version != before.Version {
+		return nil, fmt.Errorf("Update: banner version is %d %w", before.Version, errs.ErrBannerVersionConflict)
+	}
+
+	banner, err := patch.Apply(before)
+	if err != nil {
+		return nil, fmt.Errorf("Update: apply patch failed %w", err)
+	}
+
+	err = banner.Validate(s.templateVars)
+	if err != nil {
+		return nil, fmt.Errorf("Update: patched banner is invalid %w", err)
+	}
+
+	err = s.checkReferences(ctx, banner)
+	if err != nil {
+		return nil, fmt.Errorf("Update: check banner references failed %w", err)
+	}
+
 	storedBanner, err := s.repo.UpdateBanner(ctx, banner)
 	if err != nil {
-		return fmt.Errorf("Update: update banner failed %w", err)
+		return nil, fmt.Errorf("Update: update banner failed %w", err)
 	}
+	storedBanner.CreatedAt = before.CreatedAt
 
 	err = s.cache.CreateBanner(ctx, storedBanner)
 	if err != nil {
-		return fmt.Errorf("Update: create banner in cache failed %w", err)
+		return nil, fmt.Errorf("Update: create banner in cache failed %w", err)
+	}
+
+	s.notify(ctx, before, storedBanner)
+
+	return storedBanner, nil
+}
+
+// Batch creates and updates the banners of the batch items in one transaction
+// and refreshes the cache once. Nothing is stored, if any of the items failed,
+// the results contain the error of the failed item then.
+func (s *BannerService) Batch(ctx context.Context, items []*BatchItem) ([]*BatchResult, error) {
+	results := make([]*BatchResult, len(items))
+	banners := make([]*Banner, len(items))
+	befores := make([]*Banner, len(items))
+	ids := make(map[int]struct{}, len(items))
+
+	failed := false
+	for i, item := range items {
+		results[i] = &BatchResult{
+			Index:  i,
+			Status: BatchSkipped,
+		}
+
+		banner, before, err := s.prepareItem(ctx, item, ids)
+		if err != nil {
+			results[i].Status = BatchFailed
+			results[i].Err = err
+			failed = true
+			continue
+		}
+
+		banners[i] = banner
+		befores[i] = before
+	}
+
+	if failed {
+		return results, fmt.Errorf("Batch: prepare batch items failed %w", errs.ErrBatchNotApplied)
+	}
+
+	storedBanners, err := s.repo.SaveBanners(ctx, banners)
+	if err != nil {
+		var itemErr *errs.BatchItemError
+		if errors.As(err, &itemErr) && itemErr.Index < len(results) {
+			results[itemErr.Index].Status = BatchFailed
+			results[itemErr.Index].Err = itemErr.Err
+			return results, fmt.Errorf("Batch: save banners failed %w", errs.ErrBatchNotApplied)
+		}
+		return nil, fmt.Errorf("Batch: save banners failed %w", err)
+	}
+
+	err = s.cache.CreateBanners(ctx, storedBanners)
+	if err != nil {
+		return nil, fmt.Errorf("Batch: create banners in cache failed %w", err)
+	}
+
+	for i, storedBanner := range storedBanners {
+		results[i].Banner = storedBanner
+		results[i].Status = BatchUpdated
+		if befores[i] == nil {
+			results[i].Status = BatchCreated
+		}
+		s.notify(ctx, befores[i], storedBanner)
+	}
+
+	return results, nil
+}
+
+// exportPageSize is the number of the banners read from the storage at once during export.
+const exportPageSize = 500
+
+// Export calls fn for each banner by filter stored in the storage, reading them
+// page by page, so the banners might be streamed. Zero limit means no limit.
+func (s *BannerService) Export(ctx context.Context, featureID int, tagID int, limit int, offset int, deleted bool, fn func(*Banner) error) error {
+	exported := 0
+	for {
+		size := exportPageSize
+		if limit != 0 && limit-exported < size {
+			size = limit - exported
+		}
+		if size == 0 {
+			return nil
+		}
+
+		page, err := s.repo.GetBannersByFilter(ctx, featureID, tagID, size, offset+exported, deleted)
+		if err != nil {
+			return fmt.Errorf("Export: get banners page failed %w", err)
+		}
+
+		for _, b := range page {
+			err = fn(b)
+			if err != nil {
+				return fmt.Errorf("Export: export banner failed %w", err)
+			}
+		}
+
+		exported += len(page)
+		if len(page) < size {
+			return nil
+		}
+	}
+}
+
+// Import validates the banners and stores them in one transaction: in create mode
+// the banners are created, in upsert mode the stored banners with the same ID are
+// updated and others are created. The banners, which make the user banner ambiguous
+// with the other stored or imported banners, are not imported and reported as conflicts.
+// Nothing is stored, if any of the banners is invalid or the dry run is requested.
+func (s *BannerService) Import(ctx context.Context, banners []*Banner, mode string, dryRun bool) ([]*ImportResult, error) {
+	if mode != ImportCreate && mode != ImportUpsert {
+		return nil, fmt.Errorf("Import: unknown import mode %s", mode)
+	}
+
+	results := make([]*ImportResult, len(banners))
+	befores := make(map[int]*Banner, len(banners))
+	stored := make(map[int][]*Banner)
+	ids := make(map[int]struct{}, len(banners))
+
+	// The stored banners updated by the import are checked in their imported state
+	replaced := make(map[int]struct{})
+	if mode == ImportUpsert {
+		for _, b := range banners {
+			if b.ID != 0 {
+				replaced[b.ID] = struct{}{}
+			}
+		}
+	}
+
+	toSave := make([]*Banner, 0, len(banners))
+	saveIndexes := make([]int, 0, len(banners))
+	failed := false
+	for i, b := range banners {
+		res := &ImportResult{BatchResult: BatchResult{Index: i}}
+		results[i] = res
+
+		item := &Banner{
+			TagIDs:    b.TagIDs,
+			FeatureID: b.FeatureID,
+			Content:   b.Content,
+			Locales:   b.Locales,
+			IsActive:  b.IsActive,
+			Weight:    b.Weight,
+			Priority:  b.Priority,
+			IsDefault: b.IsDefault,
+			Cap:       b.Cap,
+		}
+		if mode == ImportUpsert {
+			item.ID = b.ID
+		}
+
+		err := item.Validate(s.templateVars)
+		if err == nil && item.ID != 0 {
+			if _, ok := ids[item.ID]; ok {
+				verr := &errs.ValidationError{}
+				verr.Add("banner_id", errs.ValidationDuplicate, fmt.Sprintf("banner %d is duplicated in the import", item.ID))
+				err = verr
+			}
+			ids[item.ID] = struct{}{}
+		}
+		if err != nil {
+			res.Status = BatchFailed
+			res.Err = fmt.Errorf("Import: banner is invalid %w", err)
+			failed = true
+			continue
+		}
+
+		err = s.checkReferences(ctx, item)
+		if err != nil {
+			res.Status = BatchFailed
+			res.Err = fmt.Errorf("Import: check banner references failed %w", err)
+			failed = true
+			continue
+		}
+
+		if item.ID != 0 {
+			before, err := s.repo.GetBannerByID(ctx, item.ID)
+			switch {
+			case errors.Is(err, errs.ErrBannerNotFound):
+				item.ID = 0
+			case err != nil:
+				return nil, fmt.Errorf("Import: get banner before update failed %w", err)
+			case before.DeletedAt != nil:
+				item.ID = 0
+			default:
+				befores[i] = before
+			}
+		}
+
+		if _, ok := stored[item.FeatureID]; !ok {
+			list, err := s.repo.GetBannersByFilter(ctx, item.FeatureID, 0, 0, 0, false)
+			if err != nil {
+				return nil, fmt.Errorf("Import: get feature banners failed %w", err)
+			}
+			stored[item.FeatureID] = list
+		}
+
+		res.Conflicts = conflicts(item, stored[item.FeatureID], replaced, toSave, saveIndexes)
+		if len(res.Conflicts) != 0 {
+			res.Status = ImportConflict
+			continue
+		}
+
+		res.Status = BatchCreated
+		if item.ID != 0 {
+			res.Status = BatchUpdated
+		}
+		toSave = append(toSave, item)
+		saveIndexes = append(saveIndexes, i)
+	}
+
+	if failed {
+		for _, i := range saveIndexes {
+			results[i].Status = BatchSkipped
+		}
+		return results, fmt.Errorf("Import: validate banners failed %w", errs.ErrBatchNotApplied)
+	}
+
+	if dryRun || len(toSave) == 0 {
+		return results, nil
+	}
+
+	storedBanners, err := s.repo.SaveBanners(ctx, toSave)
+	if err != nil {
+		var itemErr *errs.BatchItemError
+		if errors.As(err, &itemErr) && itemErr.Index < len(saveIndexes) {
+			for _, i := range saveIndexes {
+				results[i].Status = BatchSkipped
+			}
+			res := results[saveIndexes[itemErr.Index]]
+			res.Status = BatchFailed
+			res.Err = itemErr.Err
+			return results, fmt.Errorf("Import: save banners failed %w", errs.ErrBatchNotApplied)
+		}
+		return nil, fmt.Errorf("Import: save banners failed %w", err)
+	}
+
+	err = s.cache.CreateBanners(ctx, storedBanners)
+	if err != nil {
+		return nil, fmt.Errorf("Import: create banners in cache failed %w", err)
+	}
+
+	for j, storedBanner := range storedBanners {
+		i := saveIndexes[j]
+		results[i].Banner = storedBanner
+
+		s.notify(ctx, befores[i], storedBanner)
+	}
+
+	return results, nil
+}
+
+// conflicts returns the conflicts of the banner with the stored banners of the feature,
+// which are not replaced by the import, and with the imported banners of the indexes.
+func conflicts(b *Banner, stored []*Banner, replaced map[int]struct{}, imported []*Banner, indexes []int) []Conflict {
+	res := make([]Conflict, 0)
+	for _, other := range stored {
+		if _, ok := replaced[other.ID]; ok || other.ID == b.ID {
+			continue
+		}
+		for _, tagID := range ambiguous(b, other) {
+			res = append(res, Conflict{
+				FeatureID: b.FeatureID,
+				TagID:     tagID,
+				BannerID:  other.ID,
+			})
+		}
+	}
+
+	for j, other := range imported {
+		for _, tagID := range ambiguous(b, other) {
+			res = append(res, Conflict{
+				FeatureID: b.FeatureID,
+				TagID:     tagID,
+				Index:     indexes[j],
+			})
+		}
+	}
+
+	return res
+}
+
+// ambiguous returns the tags, for which the user banner is ambiguous with both banners:
+// both are active unweighted banners of the same priority sharing the feature and tag pair.
+// Both active default banners of the feature make the default banner ambiguous,
+// which is reported as the zero tag. The weighted banners are the variants of the pair.
+func ambiguous(b *Banner, other *Banner) []int {
+	tagIDs := make([]int, 0)
+	if b.FeatureID != other.FeatureID || !b.IsActive || !other.IsActive {
+		return tagIDs
+	}
+
+	if b.IsDefault && other.IsDefault {
+		tagIDs = append(tagIDs, 0)
+	}
+
+	if b.Weight == 0 && other.Weight == 0 && b.Priority == other.Priority {
+		for _, tagID := range b.TagIDs {
+			if slices.Contains(other.TagIDs, tagID) {
+				tagIDs = append(tagIDs, tagID)
+			}
+		}
+	}
+
+	return tagIDs
+}
+
+// prepareItem returns the validated banner to store for the batch item and
+// the stored banner before the update, which is nil for the new banner.
+func (s *BannerService) prepareItem(ctx context.Context, item *BatchItem, ids map[int]struct{}) (*Banner, *Banner, error) {
+	if item.ID == 0 {
+		banner, err := item.Patch.Apply(&Banner{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("prepareItem: apply patch failed %w", err)
+		}
+
+		err = banner.Validate(s.templateVars)
+		if err != nil {
+			return nil, nil, fmt.Errorf("prepareItem: new banner is invalid %w", err)
+		}
+
+		err = s.checkReferences(ctx, banner)
+		if err != nil {
+			return nil, nil, fmt.Errorf("prepareItem: check banner references failed %w", err)
+		}
+
+		return banner, nil, nil
+	}
+
+	if _, ok := ids[item.ID]; ok {
+		verr := &errs.ValidationError{}
+		verr.Add("banner_id", errs.ValidationDuplicate, fmt.Sprintf("banner %d is duplicated in the batch", item.ID))
+		return nil, nil, fmt.Errorf("prepareItem: banner is duplicated %w", verr)
+	}
+	ids[item.ID] = struct{}{}
+
+	before, err := s.repo.GetBannerByID(ctx, item.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("prepareItem: get banner before update failed %w", err)
+	}
+
+	if before.DeletedAt != nil {
+		return nil, nil, fmt.Errorf("prepareItem: banner is deleted %w", errs.ErrBannerNotFound)
+	}
+
+	if item.Version != 0 && item.Version != before.Version {
+		return nil, nil, fmt.Errorf("prepareItem: banner version is %d %w", before.Version, errs.ErrBannerVersionConflict)
+	}
+
+	banner, err := item.Patch.Apply(before)
+	if err != nil {
+		return nil, nil, fmt.Errorf("prepareItem: apply patch failed %w", err)
+	}
+
+	err = banner.Validate(s.templateVars)
+	if err != nil {
+		return nil, nil, fmt.Errorf("prepareItem: patched banner is invalid %w", err)
+	}
+
+	err = s.checkReferences(ctx, banner)
+	if err != nil {
+		return nil, nil, fmt.Errorf("prepareItem: check banner references failed %w", err)
+	}
+
+	return banner, before, nil
+}
+
+// Get returns the requested banner by ID stored in the storage.
+func (s *BannerService) Get(ctx context.Context, id int) (*Banner, error) {
+	banner, err := s.repo.GetBannerByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("Get: get banner failed %w", err)
+	}
+
+	if banner.DeletedAt != nil {
+		return nil, fmt.Errorf("Get: banner is deleted %w", errs.ErrBannerNotFound)
+	}
+
+	err = s.describe(ctx, banner)
+	if err != nil {
+		return nil, fmt.Errorf("Get: describe banner failed %w", err)
+	}
+
+	return banner, nil
+}
+
+// checkReferences checks whether the feature and tags referenced by the banner
+// are presented in the catalog and returns the reference error with the unknown ones.
+func (s *BannerService) checkReferences(ctx context.Context, banner *Banner) error {
+	if s.catalog == nil {
+		return nil
+	}
+
+	features, tags, err := s.catalog.Names(ctx, []int{banner.FeatureID}, banner.TagIDs)
+	if err != nil {
+		return fmt.Errorf("checkReferences: get names failed %w", err)
+	}
+
+	rerr := &errs.ReferenceError{}
+	if _, ok := features[banner.FeatureID]; !ok {
+		rerr.Add("feature_id", fmt.Sprintf("unknown feature %d", banner.FeatureID))
+	}
+	for i, tagID := range banner.TagIDs {
+		if _, ok := tags[tagID]; !ok {
+			rerr.Add(fmt.Sprintf("tag_ids[%d]", i), fmt.Sprintf("unknown tag %d", tagID))
+		}
+	}
+
+	return rerr.Err()
+}
+
+// describe sets the names of the features and tags referenced by the banners.
+func (s *BannerService) describe(ctx context.Context, banners ...*Banner) error {
+	if s.catalog == nil || len(banners) == 0 {
+		return nil
+	}
+
+	featureIDs := make([]int, 0, len(banners))
+	tagIDs := make([]int, 0, len(banners))
+	for _, b := range banners {
+		featureIDs = append(featureIDs, b.FeatureID)
+		tagIDs = append(tagIDs, b.TagIDs...)
+	}
+
+	features, tags, err := s.catalog.Names(ctx, featureIDs, tagIDs)
+	if err != nil {
+		return fmt.Errorf("describe: get names failed %w", err)
+	}
+
+	for _, b := range banners {
+		b.FeatureName = features[b.FeatureID]
+		b.TagNames = make([]string, 0, len(b.TagIDs))
+		for _, tagID := range b.TagIDs {
+			b.TagNames = append(b.TagNames, tags[tagID])
+		}
 	}
 
 	return nil
 }
 
-// Delete deletes the requested banner by ID from the storage.
-func (s *BannerService) Delete(ctx context.Context, id int) error {
-	err := s.repo.DeleteBannerByID(ctx, id)
+// Delete marks the requested banner by ID in the storage as deleted, if the stored
+// banner version equals to the requested one. Zero version means no version check.
+func (s *BannerService) Delete(ctx context.Context, id int, version int) error {
+	before, err := s.repo.GetBannerByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("Delete: get banner before delete failed %w", err)
+	}
+
+	err = s.repo.DeleteBannerByID(ctx, id, version)
 	if err != nil {
 		return fmt.Errorf("Delete: delete banner failed %w", err)
 	}
@@ -115,5 +978,113 @@ func (s *BannerService) Delete(ctx context.Context, id int) error {
 		return fmt.Errorf("Delete: delete banner from cache failed %w", err)
 	}
 
+	s.notify(ctx, before, nil)
+
+	return nil
+}
+
+// Restore restores the requested deleted banner by ID in the storage.
+func (s *BannerService) Restore(ctx context.Context, id int) error {
+	storedBanner, err := s.repo.RestoreBannerByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("Restore: restore banner failed %w", err)
+	}
+
+	err = s.cache.CreateBanner(ctx, storedBanner)
+	if err != nil {
+		return fmt.Errorf("Restore: create banner in cache failed %w", err)
+	}
+
+	s.notify(ctx, nil, storedBanner)
+
 	return nil
 }
+
+// Purge removes the banners deleted earlier than the retention period ago
+// from the storage with requested interval.
+func (s *BannerService) Purge(ctx context.Context, retention time.Duration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.repo.PurgeDeletedBanners(ctx, time.Now().Add(-retention))
+			if err != nil {
+				logger.Log.Error("Purge: purge deleted banners failed",
+					zap.Error(err))
+				continue
+			}
+
+			if count != 0 {
+				logger.Log.Info("deleted banners purged",
+					zap.Int("count", count))
+			}
+		}
+	}
+}
+
+// Subscribe subscribes to the changes of the banners of the pairs. The events missed
+// since the last event ID or, if they are not kept anymore, the events for all the pairs
+// are returned to be sent before the subscription events.
+func (s *BannerService) Subscribe(ctx context.Context, pairs []Pair, lastEventID int64) (*Subscription, []*Event, error) {
+	if s.broker == nil {
+		return nil, nil, fmt.Errorf("Subscribe: banner changes are not published")
+	}
+
+	sub, missed, resumed, currentID := s.broker.Subscribe(pairs, lastEventID)
+	if resumed {
+		return sub, missed, nil
+	}
+
+	events := changes(pairs)
+	for _, e := range events {
+		e.ID = currentID
+	}
+
+	return sub, events, nil
+}
+
+// notify publishes the changes of the pairs of the changed banner, if the pairs are subscribed.
+func (s *BannerService) notify(ctx context.Context, before *Banner, after *Banner) {
+	if s.broker == nil {
+		return
+	}
+
+	pairs := make([]Pair, 0)
+	seen := make(map[Pair]struct{})
+	for _, b := range []*Banner{before, after} {
+		if b == nil {
+			continue
+		}
+		for _, tagID := range b.TagIDs {
+			p := Pair{FeatureID: b.FeatureID, TagID: tagID}
+			if _, ok := seen[p]; ok {
+				continue
+			}
+			seen[p] = struct{}{}
+			pairs = append(pairs, p)
+		}
+	}
+
+	pairs = s.broker.Watched(pairs)
+	if len(pairs) == 0 {
+		return
+	}
+
+	s.broker.Publish(changes(pairs)...)
+}
+
+// changes returns the change events of the pairs. The banners are not resolved for
+// the events, since the banner of the pair depends on the user, and the subscriber
+// resolves it the same way as the user banner.
+func changes(pairs []Pair) []*Event {
+	events := make([]*Event, 0, len(pairs))
+	for _, p := range pairs {
+		events = append(events, &Event{Pair: p})
+	}
+
+	return events
+}
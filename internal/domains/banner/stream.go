@@ -0,0 +1,281 @@
+package banner
+
+import (
+	"sync"
+	"time"
+)
+
+// Event contains the change of the banners of the feature and tag pair. The subscriber
+// resolves the banner of the pair and sets the banner ID and version of the content
+// sent to it, zero banner ID means there is no banner for the subscriber.
+type Event struct {
+	ID       int64
+	Pair     Pair
+	BannerID int
+	Version  int
+}
+
+// eventKey identifies the banner content sent to the subscriber.
+type eventKey struct {
+	bannerID int
+	version  int
+}
+
+// subscriptionBuffer is the number of events, which might be queued for
+// the subscriber. The slow subscriber is dropped and should resume the stream.
+const subscriptionBuffer = 64
+
+// resumeWindow is the time, during which the pair changes are still published
+// after the last subscriber has left, so the subscriber might resume the stream.
+const resumeWindow = time.Minute
+
+// watch contains the subscription state of the pair.
+type watch struct {
+	subscribers int
+	since       int64
+	until       time.Time
+}
+
+// Broker delivers the banner change events to the subscribers of the feature
+// and tag pairs and keeps the recent events for resuming the subscriptions.
+type Broker struct {
+	sync.Mutex
+	historySize int
+	lastID      int64
+	history     []*Event
+	subs        map[*Subscription]struct{}
+	watches     map[Pair]*watch
+	closed      bool
+}
+
+// NewBroker returns new banner change events broker, which keeps
+// the requested number of recent events. Event IDs start from the current
+// time in microseconds, so the IDs of the previous server run are not resumed.
+func NewBroker(historySize int) *Broker {
+	return &Broker{
+		historySize: historySize,
+		lastID:      time.Now().UnixMicro(),
+		history:     make([]*Event, 0, historySize),
+		subs:        make(map[*Subscription]struct{}),
+		watches:     make(map[Pair]*watch),
+	}
+}
+
+// Subscription contains the events of the subscribed feature and tag pairs.
+type Subscription struct {
+	broker *Broker
+	pairs  map[Pair]struct{}
+	sent   map[Pair]eventKey
+	events chan *Event
+	closed bool
+}
+
+// Subscribe subscribes to the events of the pairs. If all the pair events after the last
+// event ID are kept, they are returned to be sent first, otherwise resumed is false
+// and the current state of the pairs should be sent with the returned last event ID.
+func (b *Broker) Subscribe(pairs []Pair, lastEventID int64) (sub *Subscription, missed []*Event, resumed bool, currentID int64) {
+	b.Lock()
+	defer b.Unlock()
+
+	sub = &Subscription{
+		broker: b,
+		pairs:  make(map[Pair]struct{}, len(pairs)),
+		sent:   make(map[Pair]eventKey, len(pairs)),
+		events: make(chan *Event, subscriptionBuffer),
+	}
+	for _, p := range pairs {
+		sub.pairs[p] = struct{}{}
+	}
+
+	if b.closed {
+		sub.closed = true
+		close(sub.events)
+		return sub, nil, false, b.lastID
+	}
+
+	resumed = b.resumable(sub, lastEventID)
+
+	b.subs[sub] = struct{}{}
+	for p := range sub.pairs {
+		w := b.watch(p)
+		if w == nil {
+			w = &watch{since: b.lastID}
+			b.watches[p] = w
+		}
+		w.subscribers++
+	}
+
+	if !resumed {
+		return sub, nil, false, b.lastID
+	}
+
+	for _, e := range b.history {
+		if e.ID > lastEventID && sub.wants(e) {
+			missed = append(missed, e)
+		}
+	}
+
+	return sub, missed, true, b.lastID
+}
+
+// resumable reports whether all the subscription pair events after the last event ID
+// are kept, the lock must be held.
+func (b *Broker) resumable(sub *Subscription, lastEventID int64) bool {
+	oldestID := b.lastID + 1
+	if len(b.history) > 0 {
+		oldestID = b.history[0].ID
+	}
+	if lastEventID == 0 || lastEventID < oldestID-1 || lastEventID > b.lastID {
+		return false
+	}
+
+	// The changes of the pair are published only while it is watched
+	for p := range sub.pairs {
+		w := b.watch(p)
+		if w == nil || w.since > lastEventID {
+			return false
+		}
+	}
+
+	return true
+}
+
+// watch returns the state of the pair, if its changes are published,
+// the lock must be held.
+func (b *Broker) watch(p Pair) *watch {
+	w, ok := b.watches[p]
+	if !ok {
+		return nil
+	}
+	if w.subscribers == 0 && time.Now().After(w.until) {
+		delete(b.watches, p)
+		return nil
+	}
+
+	return w
+}
+
+// Watched returns the pairs, which changes should be published.
+func (b *Broker) Watched(pairs []Pair) []Pair {
+	b.Lock()
+	defer b.Unlock()
+
+	watched := make([]Pair, 0, len(pairs))
+	for _, p := range pairs {
+		if b.watch(p) != nil {
+			watched = append(watched, p)
+		}
+	}
+
+	return watched
+}
+
+// Publish assigns IDs to the events, keeps them for resuming and sends them to the
+// subscribers, which have not received the same banner content yet.
+func (b *Broker) Publish(events ...*Event) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	for _, e := range events {
+		b.lastID++
+		e.ID = b.lastID
+
+		b.history = append(b.history, e)
+		if len(b.history) > b.historySize {
+			b.history = b.history[len(b.history)-b.historySize:]
+		}
+
+		for sub := range b.subs {
+			if !sub.wants(e) {
+				continue
+			}
+
+			select {
+			case sub.events <- e:
+			default:
+				// The subscriber is too slow, so drop it until it resumes the stream
+				sub.close()
+			}
+		}
+	}
+}
+
+// Close closes all the subscriptions, so the streams are finished.
+func (b *Broker) Close() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		sub.close()
+	}
+}
+
+// Events returns the channel of the subscription events, which is closed,
+// when the subscription is closed or dropped.
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Close unsubscribes from the events.
+func (s *Subscription) Close() {
+	s.broker.Lock()
+	defer s.broker.Unlock()
+
+	s.close()
+}
+
+// Sent reports whether the event banner content is already sent to the subscriber.
+func (s *Subscription) Sent(e *Event) bool {
+	s.broker.Lock()
+	defer s.broker.Unlock()
+
+	prev, ok := s.sent[e.Pair]
+	return ok && prev == eventKey{bannerID: e.BannerID, version: e.Version}
+}
+
+// Changed reports whether the event changes the banner content sent to the subscriber
+// and remembers the event content, so the unchanged content is not sent again.
+func (s *Subscription) Changed(e *Event) bool {
+	s.broker.Lock()
+	defer s.broker.Unlock()
+
+	key := eventKey{bannerID: e.BannerID, version: e.Version}
+	if prev, ok := s.sent[e.Pair]; ok && prev == key {
+		return false
+	}
+	s.sent[e.Pair] = key
+
+	return true
+}
+
+// wants reports whether the event pair is subscribed.
+func (s *Subscription) wants(e *Event) bool {
+	_, ok := s.pairs[e.Pair]
+	return ok
+}
+
+// close removes the subscription from the broker, the lock must be held.
+func (s *Subscription) close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(s.broker.subs, s)
+	close(s.events)
+
+	for p := range s.pairs {
+		w, ok := s.broker.watches[p]
+		if !ok {
+			continue
+		}
+		w.subscribers--
+		if w.subscribers == 0 {
+			w.until = time.Now().Add(resumeWindow)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package banner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// List of the request variables available in the banner content placeholders.
+const (
+	VarUserID    = "user_id"
+	VarFeatureID = "feature_id"
+	VarTagID     = "tag_id"
+	VarLocale    = "locale"
+)
+
+// templateVars contains the built-in placeholder variables, the additional
+// variables are configured and got from the request parameters or headers.
+var templateVars = []string{VarUserID, VarFeatureID, VarTagID, VarLocale}
+
+// List of the request parameter and header prefixes of the configured template variables.
+const (
+	TemplateVarQueryPrefix  = "var."
+	TemplateVarHeaderPrefix = "X-Banner-Var-"
+)
+
+// TemplateVarHeader returns the request header of the configured template variable,
+// e.g. X-Banner-Var-Promo-Code for promo_code.
+func TemplateVarHeader(name string) string {
+	return http.CanonicalHeaderKey(TemplateVarHeaderPrefix + strings.ReplaceAll(name, "_", "-"))
+}
+
+// Limits of the banner content templates.
+const (
+	MaxTemplatePlaceholders = 20
+	MaxTemplateValueLength  = 256
+	templateCacheSize       = 10000
+)
+
+// List of the template delimiters.
+const (
+	templateOpen     = "{{"
+	templateClose    = "}}"
+	templateFallback = "|"
+)
+
+// Template contains the banner content value parsed into the text parts and the
+// placeholders of the request variables: {{user_id}} or {{ user_id | guest }}, where
+// the text after the bar is rendered, if the variable is empty.
+type Template struct {
+	parts []templatePart
+}
+
+// templatePart contains the text or the placeholder of the template.
+type templatePart struct {
+	text     string
+	variable string
+	fallback string
+}
+
+// ParseTemplate parses the banner content value and returns the error, if the placeholder
+// is not closed or refers to the variable, which is neither built-in nor configured.
+func ParseTemplate(s string, vars []string) (*Template, error) {
+	t := &Template{}
+	for s != "" {
+		i := strings.Index(s, templateOpen)
+		if i == -1 {
+			t.parts = append(t.parts, templatePart{text: s})
+			break
+		}
+		if i > 0 {
+			t.parts = append(t.parts, templatePart{text: s[:i]})
+		}
+		s = s[i+len(templateOpen):]
+
+		j := strings.Index(s, templateClose)
+		if j == -1 {
+			return nil, errors.New("placeholder is not closed")
+		}
+		placeholder := s[:j]
+		s = s[j+len(templateClose):]
+
+		variable, fallback, _ := strings.Cut(placeholder, templateFallback)
+		variable, fallback = strings.TrimSpace(variable), strings.TrimSpace(fallback)
+		if !slices.Contains(templateVars, variable) && !slices.Contains(vars, variable) {
+			available := append(slices.Clone(templateVars), vars...)
+			return nil, fmt.Errorf("unknown variable %q, available: %s", variable, strings.Join(available, ", "))
+		}
+		if strings.Contains(fallback, templateOpen) {
+			return nil, errors.New("placeholder must not be nested")
+		}
+
+		t.parts = append(t.parts, templatePart{variable: variable, fallback: fallback})
+		if t.placeholders() > MaxTemplatePlaceholders {
+			return nil, fmt.Errorf("must contain at most %d placeholders", MaxTemplatePlaceholders)
+		}
+	}
+
+	return t, nil
+}
+
+// placeholders returns the number of the placeholders in the template.
+func (t *Template) placeholders() int {
+	count := 0
+	for _, p := range t.parts {
+		if p.variable != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// Variables returns the variables used by the template placeholders.
+func (t *Template) Variables() []string {
+	vars := make([]string, 0)
+	for _, p := range t.parts {
+		if p.variable != "" && !slices.Contains(vars, p.variable) {
+			vars = append(vars, p.variable)
+		}
+	}
+	return vars
+}
+
+// Render returns the template with the placeholders replaced by the variables. The values
+// come from the request, so the control characters are dropped and the long values are cut.
+func (t *Template) Render(vars map[string]string) string {
+	var sb strings.Builder
+	for _, p := range t.parts {
+		if p.variable == "" {
+			sb.WriteString(p.text)
+			continue
+		}
+
+		value := sanitizeValue(vars[p.variable])
+		if value == "" {
+			value = p.fallback
+		}
+		sb.WriteString(value)
+	}
+
+	return sb.String()
+}
+
+// sanitizeValue returns the request variable value safe for rendering into the content.
+func sanitizeValue(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, value)
+
+	if len(value) > MaxTemplateValueLength {
+		value = strings.ToValidUTF8(value[:MaxTemplateValueLength], "")
+	}
+
+	return value
+}
+
+// isTemplate reports whether the content value contains placeholders.
+func isTemplate(s string) bool {
+	return strings.Contains(s, templateOpen)
+}
+
+// templateCache contains the parsed templates of the banner content values,
+// so every template is parsed once for all the requests.
+type templateCache struct {
+	sync.RWMutex
+	size      int
+	vars      []string
+	templates map[string]*Template
+}
+
+// newTemplateCache returns new cache of the parsed templates with the configured variables.
+func newTemplateCache(size int, vars []string) *templateCache {
+	return &templateCache{
+		size:      size,
+		vars:      vars,
+		templates: make(map[string]*Template),
+	}
+}
+
+// get returns the parsed template of the content value. The cache is cleared,
+// when it is full, since the templates of the changed banners are not used anymore.
+func (c *templateCache) get(s string) (*Template, error) {
+	c.RLock()
+	t, ok := c.templates[s]
+	c.RUnlock()
+	if ok {
+		return t, nil
+	}
+
+	t, err := ParseTemplate(s, c.vars)
+	if err != nil {
+		return nil, fmt.Errorf("get: parse template failed %w", err)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.templates) >= c.size {
+		c.templates = make(map[string]*Template)
+	}
+	c.templates[s] = t
+
+	return t, nil
+}
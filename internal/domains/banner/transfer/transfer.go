@@ -0,0 +1,359 @@
+// Package transfer contains encoders and decoders of the banners
+// for export and import in NDJSON and CSV formats.
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+)
+
+// List of the supported formats.
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// MaxRecords is the maximum number of the banners in one imported file.
+const MaxRecords = 10000
+
+// csvHeader contains the columns of the CSV file.
+var csvHeader = []string{"banner_id", "feature_id", "tag_ids", "content", "localized_content", "is_active", "weight", "priority", "is_default", "cap_impressions", "cap_window", "created_at", "updated_at", "version", "deleted_at"}
+
+// ContentType returns the MIME type of the format.
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+// CheckFormat returns the error, if the format is not supported.
+func CheckFormat(format string) error {
+	switch format {
+	case FormatNDJSON, FormatCSV:
+		return nil
+	default:
+		return fmt.Errorf("CheckFormat: unknown format %s", format)
+	}
+}
+
+// Encoder writes the banners in the requested format.
+type Encoder struct {
+	format string
+	json   *json.Encoder
+	csv    *csv.Writer
+}
+
+// NewEncoder returns new encoder of the banners, which writes the CSV header at once.
+func NewEncoder(w io.Writer, format string) (*Encoder, error) {
+	err := CheckFormat(format)
+	if err != nil {
+		return nil, fmt.Errorf("NewEncoder: check format failed %w", err)
+	}
+
+	e := &Encoder{format: format}
+	if format == FormatNDJSON {
+		e.json = json.NewEncoder(w)
+		return e, nil
+	}
+
+	e.csv = csv.NewWriter(w)
+	err = e.csv.Write(csvHeader)
+	if err != nil {
+		return nil, fmt.Errorf("NewEncoder: write header failed %w", err)
+	}
+
+	return e, nil
+}
+
+// Encode writes the banner.
+func (e *Encoder) Encode(b *banner.Banner) error {
+	if e.format == FormatNDJSON {
+		err := e.json.Encode(b)
+		if err != nil {
+			return fmt.Errorf("Encode: encode banner failed %w", err)
+		}
+		return nil
+	}
+
+	tagIDs := make([]string, 0, len(b.TagIDs))
+	for _, tagID := range b.TagIDs {
+		tagIDs = append(tagIDs, strconv.Itoa(tagID))
+	}
+
+	content, err := json.Marshal(b.Content)
+	if err != nil {
+		return fmt.Errorf("Encode: marshal content failed %w", err)
+	}
+
+	locales := ""
+	if len(b.Locales) != 0 {
+		data, err := json.Marshal(b.Locales)
+		if err != nil {
+			return fmt.Errorf("Encode: marshal localized content failed %w", err)
+		}
+		locales = string(data)
+	}
+
+	deletedAt := ""
+	if b.DeletedAt != nil {
+		deletedAt = b.DeletedAt.Format(time.RFC3339Nano)
+	}
+
+	err = e.csv.Write([]string{
+		strconv.Itoa(b.ID),
+		strconv.Itoa(b.FeatureID),
+		strings.Join(tagIDs, ";"),
+		string(content),
+		locales,
+		strconv.FormatBool(b.IsActive),
+		strconv.Itoa(b.Weight),
+		strconv.Itoa(b.Priority),
+		strconv.FormatBool(b.IsDefault),
+		strconv.Itoa(b.Cap.Impressions),
+		strconv.Itoa(b.Cap.Window),
+		b.CreatedAt.Format(time.RFC3339Nano),
+		b.UpdatedAt.Format(time.RFC3339Nano),
+		strconv.Itoa(b.Version),
+		deletedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("Encode: write record failed %w", err)
+	}
+
+	return nil
+}
+
+// Flush writes the buffered data.
+func (e *Encoder) Flush() error {
+	if e.csv == nil {
+		return nil
+	}
+
+	e.csv.Flush()
+	err := e.csv.Error()
+	if err != nil {
+		return fmt.Errorf("Flush: flush records failed %w", err)
+	}
+
+	return nil
+}
+
+// Decode reads the banners in the requested format. The records, which could not
+// be read, are returned as validation error with the failed line numbers.
+func Decode(r io.Reader, format string) ([]*banner.Banner, error) {
+	err := CheckFormat(format)
+	if err != nil {
+		return nil, fmt.Errorf("Decode: check format failed %w", err)
+	}
+
+	if format == FormatCSV {
+		return decodeCSV(r)
+	}
+	return decodeNDJSON(r)
+}
+
+// decodeNDJSON reads the banners from the lines of JSON objects, skipping the empty lines.
+func decodeNDJSON(r io.Reader) ([]*banner.Banner, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	verr := &errs.ValidationError{}
+	banners := make([]*banner.Banner, 0)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(banners) == MaxRecords {
+			verr.Add(fmt.Sprintf("line %d", line), errs.ValidationTooMany, fmt.Sprintf("file must contain at most %d banners", MaxRecords))
+			break
+		}
+
+		var b banner.Banner
+		err := json.Unmarshal(data, &b)
+		if err != nil {
+			verr.Add(fmt.Sprintf("line %d", line), errs.ValidationInvalidType, "must be a banner JSON object")
+			continue
+		}
+		banners = append(banners, &b)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("decodeNDJSON: read lines failed %w", err)
+	}
+	if verr.Err() != nil {
+		return nil, fmt.Errorf("decodeNDJSON: decode lines failed %w", verr)
+	}
+
+	return banners, nil
+}
+
+// decodeCSV reads the banners from the CSV records with the header. Only feature_id,
+// tag_ids, content and is_active columns are required, the columns order is not fixed.
+func decodeCSV(r io.Reader) ([]*banner.Banner, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	verr := &errs.ValidationError{}
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return []*banner.Banner{}, nil
+		}
+		return nil, fmt.Errorf("decodeCSV: read header failed %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"feature_id", "tag_ids", "content", "is_active"} {
+		if _, ok := columns[name]; !ok {
+			verr.Add("line 1", errs.ValidationRequired, fmt.Sprintf("column %s is required", name))
+		}
+	}
+	if verr.Err() != nil {
+		return nil, fmt.Errorf("decodeCSV: check header failed %w", verr)
+	}
+
+	banners := make([]*banner.Banner, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				verr.Add(fmt.Sprintf("line %d", parseErr.Line), errs.ValidationInvalidType, "must be a CSV record")
+				continue
+			}
+			return nil, fmt.Errorf("decodeCSV: read record failed %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		field := fmt.Sprintf("line %d", line)
+		if len(banners) == MaxRecords {
+			verr.Add(field, errs.ValidationTooMany, fmt.Sprintf("file must contain at most %d banners", MaxRecords))
+			break
+		}
+
+		b, err := parseRecord(record, columns)
+		if err != nil {
+			verr.Add(field, errs.ValidationInvalidType, err.Error())
+			continue
+		}
+		banners = append(banners, b)
+	}
+
+	if verr.Err() != nil {
+		return nil, fmt.Errorf("decodeCSV: decode records failed %w", verr)
+	}
+
+	return banners, nil
+}
+
+// parseRecord returns the banner from the CSV record.
+func parseRecord(record []string, columns map[string]int) (*banner.Banner, error) {
+	value := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var b banner.Banner
+	var err error
+
+	if id := value("banner_id"); id != "" {
+		b.ID, err = strconv.Atoi(id)
+		if err != nil {
+			return nil, errors.New("banner_id must be an integer")
+		}
+	}
+
+	b.FeatureID, err = strconv.Atoi(value("feature_id"))
+	if err != nil {
+		return nil, errors.New("feature_id must be an integer")
+	}
+
+	if tagIDs := value("tag_ids"); tagIDs != "" {
+		for _, tag := range strings.Split(tagIDs, ";") {
+			tagID, err := strconv.Atoi(strings.TrimSpace(tag))
+			if err != nil {
+				return nil, errors.New("tag_ids must be integers separated by semicolon")
+			}
+			b.TagIDs = append(b.TagIDs, tagID)
+		}
+	}
+
+	if content := value("content"); content != "" {
+		err = json.Unmarshal([]byte(content), &b.Content)
+		if err != nil {
+			return nil, errors.New("content must be a JSON object with string values")
+		}
+	}
+
+	if locales := value("localized_content"); locales != "" {
+		err = json.Unmarshal([]byte(locales), &b.Locales)
+		if err != nil {
+			return nil, errors.New("localized_content must be a JSON object of the content by locale")
+		}
+	}
+
+	b.IsActive, err = strconv.ParseBool(value("is_active"))
+	if err != nil {
+		return nil, errors.New("is_active must be a boolean")
+	}
+
+	if weight := value("weight"); weight != "" {
+		b.Weight, err = strconv.Atoi(weight)
+		if err != nil {
+			return nil, errors.New("weight must be an integer")
+		}
+	}
+
+	if priority := value("priority"); priority != "" {
+		b.Priority, err = strconv.Atoi(priority)
+		if err != nil {
+			return nil, errors.New("priority must be an integer")
+		}
+	}
+
+	if isDefault := value("is_default"); isDefault != "" {
+		b.IsDefault, err = strconv.ParseBool(isDefault)
+		if err != nil {
+			return nil, errors.New("is_default must be a boolean")
+		}
+	}
+
+	if impressions := value("cap_impressions"); impressions != "" {
+		b.Cap.Impressions, err = strconv.Atoi(impressions)
+		if err != nil {
+			return nil, errors.New("cap_impressions must be an integer")
+		}
+	}
+
+	if window := value("cap_window"); window != "" {
+		b.Cap.Window, err = strconv.Atoi(window)
+		if err != nil {
+			return nil, errors.New("cap_window must be an integer")
+		}
+	}
+
+	return &b, nil
+}
@@ -0,0 +1,133 @@
+package transfer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/banner/transfer"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	createdAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	banners := []*banner.Banner{
+		{
+			ID:        1,
+			TagIDs:    []int{1, 2},
+			FeatureID: 3,
+			Content:   &banner.Content{"title": "some, \"quoted\" title", "text": "some_text"},
+			IsActive:  true,
+			Weight:    30,
+			Priority:  5,
+			Cap:       banner.Cap{Impressions: 3, Window: 3600},
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+			Version:   2,
+		},
+		{
+			ID:        2,
+			TagIDs:    []int{4},
+			FeatureID: 5,
+			Content:   &banner.Content{},
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+			Version:   1,
+		},
+	}
+
+	for _, format := range []string{transfer.FormatNDJSON, transfer.FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := transfer.NewEncoder(&buf, format)
+			require.NoError(t, err)
+			for _, b := range banners {
+				require.NoError(t, enc.Encode(b))
+			}
+			require.NoError(t, enc.Flush())
+
+			got, err := transfer.Decode(&buf, format)
+			require.NoError(t, err)
+			require.Len(t, got, len(banners))
+			for i, b := range got {
+				assert.Equal(t, banners[i].ID, b.ID)
+				assert.Equal(t, banners[i].TagIDs, b.TagIDs)
+				assert.Equal(t, banners[i].FeatureID, b.FeatureID)
+				assert.Equal(t, banners[i].Content, b.Content)
+				assert.Equal(t, banners[i].IsActive, b.IsActive)
+				assert.Equal(t, banners[i].Weight, b.Weight)
+				assert.Equal(t, banners[i].Priority, b.Priority)
+				assert.Equal(t, banners[i].Cap, b.Cap)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		data       string
+		wantCount  int
+		wantFields []string
+	}{
+		{
+			name:      "ndjson with empty lines",
+			format:    transfer.FormatNDJSON,
+			data:      "{\"feature_id\": 1, \"tag_ids\": [1]}\n\n{\"feature_id\": 2, \"tag_ids\": [1]}\n",
+			wantCount: 2,
+		},
+		{
+			name:       "ndjson with incorrect lines",
+			format:     transfer.FormatNDJSON,
+			data:       "{\"feature_id\": 1}\n[1]\n{\"feature_id\": \"2\"}\n",
+			wantFields: []string{"line 2", "line 3"},
+		},
+		{
+			name:      "csv with reordered and missing optional columns",
+			format:    transfer.FormatCSV,
+			data:      "is_active,content,tag_ids,feature_id\ntrue,\"{\"\"title\"\":\"\"a\"\"}\",1;2,3\n",
+			wantCount: 1,
+		},
+		{
+			name:       "csv without required columns",
+			format:     transfer.FormatCSV,
+			data:       "banner_id,feature_id\n1,2\n",
+			wantFields: []string{"line 1", "line 1", "line 1"},
+		},
+		{
+			name:       "csv with incorrect records",
+			format:     transfer.FormatCSV,
+			data:       "feature_id,tag_ids,content,is_active,weight\n1,1,{},yes,\none,1,{},true,\n1,1,{},false,heavy\n1,1,{},false,10\n",
+			wantFields: []string{"line 2", "line 3", "line 4"},
+		},
+		{
+			name:      "empty csv",
+			format:    transfer.FormatCSV,
+			data:      "",
+			wantCount: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := transfer.Decode(strings.NewReader(tt.data), tt.format)
+			if tt.wantFields == nil {
+				require.NoError(t, err)
+				assert.Len(t, got, tt.wantCount)
+				return
+			}
+
+			var verr *errs.ValidationError
+			require.ErrorAs(t, err, &verr)
+			fields := make([]string, 0, len(verr.Fields))
+			for _, f := range verr.Fields {
+				fields = append(fields, f.Field)
+			}
+			assert.Equal(t, tt.wantFields, fields)
+		})
+	}
+}
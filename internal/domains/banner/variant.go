@@ -0,0 +1,41 @@
+package banner
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// chooseVariant returns one of the weighted variants of the feature and tag pair.
+// The user is assigned to the bucket by the hash of the pair and the user ID,
+// so the user sees the same variant until the variants or their weights change.
+// The variant for the anonymous user is chosen randomly by weight.
+func chooseVariant(variants []*Banner, featureID int, tagID int, userID string) *Banner {
+	sorted := make([]*Banner, len(variants))
+	copy(sorted, variants)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	total := 0
+	for _, v := range sorted {
+		total += v.Weight
+	}
+
+	var bucket int
+	if userID == "" {
+		bucket = rand.Intn(total)
+	} else {
+		h := fnv.New32a()
+		h.Write([]byte(strconv.Itoa(featureID) + ":" + strconv.Itoa(tagID) + ":" + userID))
+		bucket = int(h.Sum32() % uint32(total))
+	}
+
+	for _, v := range sorted {
+		if bucket < v.Weight {
+			return v
+		}
+		bucket -= v.Weight
+	}
+
+	return sorted[len(sorted)-1]
+}
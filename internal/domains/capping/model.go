@@ -0,0 +1,24 @@
+// Package capping contains object and methods
+// for limiting the banners impressions per user.
+package capping
+
+import (
+	"context"
+	"time"
+)
+
+// Counter contains the number of the banner impressions for the user
+// during the window, which expires at the time.
+type Counter struct {
+	BannerID    int
+	UserID      string
+	Impressions int
+	ExpiresAt   time.Time
+}
+
+// Repository describes methods related with impressions counters
+// for interaction with the storage.
+type Repository interface {
+	Hit(ctx context.Context, bannerID int, userID string, limit int, window time.Duration, now time.Time) (bool, error)
+	PurgeExpiredCounters(ctx context.Context, now time.Time) (int, error)
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/capping"
+)
+
+// MemoryRepository contains impressions counters stored in memory
+// for tests and local development.
+type MemoryRepository struct {
+	sync.Mutex
+	counters map[key]*capping.Counter
+}
+
+// key contains data for unique counter search.
+type key struct {
+	bannerID int
+	userID   string
+}
+
+// NewCapMemoryRepository returns new in-memory impressions counters repository object.
+func NewCapMemoryRepository(ctx context.Context) *MemoryRepository {
+	return &MemoryRepository{
+		counters: make(map[key]*capping.Counter),
+	}
+}
+
+// Hit counts the banner impression for the user, if the user has seen the banner
+// less than limit times during the window, and reports whether it is counted.
+// The expired window is started again.
+func (r *MemoryRepository) Hit(ctx context.Context, bannerID int, userID string, limit int, window time.Duration, now time.Time) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	k := key{
+		bannerID: bannerID,
+		userID:   userID,
+	}
+
+	c, ok := r.counters[k]
+	if !ok || !c.ExpiresAt.After(now) {
+		c = &capping.Counter{
+			BannerID:  bannerID,
+			UserID:    userID,
+			ExpiresAt: now.Add(window),
+		}
+		r.counters[k] = c
+	}
+
+	if c.Impressions >= limit {
+		return false, nil
+	}
+	c.Impressions++
+
+	return true, nil
+}
+
+// PurgeExpiredCounters deletes the counters with the windows expired
+// by the time and returns the number of the deleted counters.
+func (r *MemoryRepository) PurgeExpiredCounters(ctx context.Context, now time.Time) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	count := 0
+	for k, c := range r.counters {
+		if !c.ExpiresAt.After(now) {
+			delete(r.counters, k)
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,67 @@
+// Package repository contains repository objects
+// and methods for interaction with impressions counters storage.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// hitQuery is the query for counting the impression in one statement, so the concurrent
+// impressions of the user never exceed the limit. The expired window is started again,
+// the impression over the limit updates nothing and returns no rows.
+// The impressions of the purged banners are not counted.
+const hitQuery = `INSERT INTO banner_caps (banner_id, user_id, impressions, expires_at) 
+	SELECT id, $2, 1, $4 FROM banners WHERE id = $1 
+	ON CONFLICT (banner_id, user_id) DO UPDATE 
+	SET impressions = CASE WHEN banner_caps.expires_at <= $3 THEN 1 ELSE banner_caps.impressions + 1 END, 
+	expires_at = CASE WHEN banner_caps.expires_at <= $3 THEN EXCLUDED.expires_at ELSE banner_caps.expires_at END 
+	WHERE banner_caps.expires_at <= $3 OR banner_caps.impressions < $5 
+	RETURNING impressions`
+
+// Repository contains storage objects for storing the impressions counters.
+type Repository struct {
+	db *sql.DB
+}
+
+// NewCapRepository returns new impressions counters repository object.
+func NewCapRepository(ctx context.Context, db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Hit counts the banner impression for the user, if the user has seen the banner
+// less than limit times during the window, and reports whether it is counted.
+// The expired window is started again.
+func (r *Repository) Hit(ctx context.Context, bannerID int, userID string, limit int, window time.Duration, now time.Time) (bool, error) {
+	var impressions int
+	err := r.db.QueryRowContext(ctx, hitQuery, bannerID, userID, now, now.Add(window), limit).Scan(&impressions)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Hit: upsert counter failed %w", err)
+	}
+
+	return true, nil
+}
+
+// PurgeExpiredCounters deletes the counters with the windows expired
+// by the time and returns the number of the deleted counters.
+func (r *Repository) PurgeExpiredCounters(ctx context.Context, now time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM banner_caps WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeExpiredCounters: delete rows failed %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("PurgeExpiredCounters: get affected rows failed %w", err)
+	}
+
+	return int(count), nil
+}
@@ -0,0 +1,64 @@
+package capping
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// CapService contains objects for frequency capping service.
+type CapService struct {
+	repo Repository
+}
+
+// NewCapService returns new frequency capping service.
+func NewCapService(ctx context.Context, repo Repository) *CapService {
+	return &CapService{
+		repo: repo,
+	}
+}
+
+// Allow counts the banner impression for the user and reports whether
+// the impression is allowed by the cap. The window starts with the first
+// impression, the impression over the cap is not counted.
+func (s *CapService) Allow(ctx context.Context, bannerID int, userID string, c banner.Cap) (bool, error) {
+	if !c.Enabled() {
+		return true, nil
+	}
+
+	ok, err := s.repo.Hit(ctx, bannerID, userID, c.Impressions, time.Duration(c.Window)*time.Second, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("Allow: count impression failed %w", err)
+	}
+
+	return ok, nil
+}
+
+// Purge deletes the counters with the expired windows with requested interval.
+func (s *CapService) Purge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.repo.PurgeExpiredCounters(ctx, time.Now())
+			if err != nil {
+				logger.Log.Error("Purge: purge expired counters failed",
+					zap.Error(err))
+				continue
+			}
+
+			if count != 0 {
+				logger.Log.Info("expired impressions counters purged",
+					zap.Int("count", count))
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package capping_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	"github.com/pavlegich/banners-service/internal/domains/capping"
+	"github.com/pavlegich/banners-service/internal/domains/capping/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapService_Allow(t *testing.T) {
+	ctx := context.Background()
+	s := capping.NewCapService(ctx, repository.NewCapMemoryRepository(ctx))
+	c := banner.Cap{Impressions: 2, Window: 3600}
+
+	for _, want := range []bool{true, true, false, false} {
+		ok, err := s.Allow(ctx, 1, "user-1", c)
+		require.NoError(t, err)
+		assert.Equal(t, want, ok)
+	}
+
+	// The impressions are counted per banner and user
+	ok, err := s.Allow(ctx, 1, "user-2", c)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = s.Allow(ctx, 2, "user-1", c)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// The banner without cap is not limited
+	for i := 0; i < 5; i++ {
+		ok, err = s.Allow(ctx, 3, "user-1", banner.Cap{})
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+}
+
+func TestMemoryRepository_Hit(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewCapMemoryRepository(ctx)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	ok, err := repo.Hit(ctx, 1, "user-1", 1, time.Hour, now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// The window starts with the first impression
+	ok, err = repo.Hit(ctx, 1, "user-1", 1, time.Hour, now.Add(59*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = repo.Hit(ctx, 1, "user-1", 1, time.Hour, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	count, err := repo.PurgeExpiredCounters(ctx, now.Add(90*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = repo.PurgeExpiredCounters(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
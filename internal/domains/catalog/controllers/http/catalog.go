@@ -0,0 +1,275 @@
+// Package http contains catalog object functions
+// for activating the handler in controller, and handlers.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/banners-service/internal/domains/catalog"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"go.uber.org/zap"
+)
+
+// maxBodySize is the maximum size of the request body in bytes.
+const maxBodySize = 1 << 16
+
+// CatalogHandler contains objects for work with tag and feature handlers.
+type CatalogHandler struct {
+	Config  *config.Config
+	Service catalog.Service
+}
+
+// Activate activates handlers for tag and feature objects.
+func Activate(ctx context.Context, r *chi.Mux, cfg *config.Config, s catalog.Service) {
+	h := &CatalogHandler{
+		Config:  cfg,
+		Service: s,
+	}
+
+	for _, kind := range []string{catalog.KindTag, catalog.KindFeature} {
+		r.Get("/"+kind, h.HandleGetEntities(kind))
+		r.Post("/"+kind, h.HandleCreateEntity(kind))
+		r.Get("/"+kind+"/{id}", h.HandleGetEntity(kind))
+		r.Patch("/"+kind+"/{id}", h.HandleUpdateEntity(kind))
+		r.Delete("/"+kind+"/{id}", h.HandleDeleteEntity(kind))
+	}
+}
+
+// HandleCreateEntity returns handler of admin's request to create new entity of the kind.
+func (h *CatalogHandler) HandleCreateEntity(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var req catalog.Entity
+
+		w.Header().Set("Content-Type", "application/json")
+
+		defer r.Body.Close()
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req)
+		if err != nil {
+			logger.Log.Error("HandleCreateEntity: decode request body failed",
+				zap.String("kind", kind),
+				zap.Error(err))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "request body must be a JSON object")
+			return
+		}
+
+		stored, err := h.Service.Create(ctx, kind, &catalog.Entity{
+			Name:        req.Name,
+			Description: req.Description,
+			Owner:       req.Owner,
+			Locale:      req.Locale,
+		})
+		if err != nil {
+			logger.Log.Error("HandleCreateEntity: create entity failed",
+				zap.String("kind", kind),
+				zap.Error(err))
+
+			writeError(w, r, kind, err)
+			return
+		}
+
+		writeJSON(w, r, http.StatusCreated, stored)
+	}
+}
+
+// HandleGetEntities returns handler of admin's request to get list of the entities of the kind.
+func (h *CatalogHandler) HandleGetEntities(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		limit, offset := 0, 0
+
+		w.Header().Set("Content-Type", "application/json")
+
+		queries := r.URL.Query()
+		for val := range queries {
+			if val != "limit" && val != "offset" {
+				logger.Log.Error("HandleGetEntities: incorrect query",
+					zap.String("query", val))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query in request url")
+				return
+			}
+
+			if len(queries[val]) != 1 {
+				logger.Log.Error("HandleGetEntities: incorrect queries number",
+					zap.String("query_name", val),
+					zap.Int("query_number", len(queries[val])))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
+				return
+			}
+
+			current, err := strconv.Atoi(queries[val][0])
+			if err != nil || current < 0 {
+				logger.Log.Error("HandleGetEntities: convert query to integer failed",
+					zap.String("query_name", val),
+					zap.String("query_value", queries[val][0]))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "query must be a non-negative integer")
+				return
+			}
+
+			if val == "limit" {
+				limit = current
+			} else {
+				offset = current
+			}
+		}
+
+		list, err := h.Service.List(ctx, kind, limit, offset)
+		if err != nil {
+			logger.Log.Error("HandleGetEntities: get entities failed",
+				zap.String("kind", kind),
+				zap.Error(err))
+
+			writeError(w, r, kind, err)
+			return
+		}
+
+		writeJSON(w, r, http.StatusOK, list)
+	}
+}
+
+// HandleGetEntity returns handler of admin's request to get the entity of the kind by ID.
+func (h *CatalogHandler) HandleGetEntity(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		w.Header().Set("Content-Type", "application/json")
+		id, ok := entityID(w, r)
+		if !ok {
+			return
+		}
+
+		e, err := h.Service.Get(ctx, kind, id)
+		if err != nil {
+			logger.Log.Error("HandleGetEntity: get entity failed",
+				zap.String("kind", kind),
+				zap.Error(err))
+
+			writeError(w, r, kind, err)
+			return
+		}
+
+		writeJSON(w, r, http.StatusOK, e)
+	}
+}
+
+// HandleUpdateEntity returns handler of admin's request to update the entity of the kind,
+// only the requested fields are changed.
+func (h *CatalogHandler) HandleUpdateEntity(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var req catalog.Patch
+
+		w.Header().Set("Content-Type", "application/json")
+		id, ok := entityID(w, r)
+		if !ok {
+			return
+		}
+
+		defer r.Body.Close()
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req)
+		if err != nil {
+			logger.Log.Error("HandleUpdateEntity: decode request body failed",
+				zap.String("kind", kind),
+				zap.Error(err))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "request body must be a JSON object")
+			return
+		}
+
+		stored, err := h.Service.Update(ctx, kind, id, &req)
+		if err != nil {
+			logger.Log.Error("HandleUpdateEntity: update entity failed",
+				zap.String("kind", kind),
+				zap.Error(err))
+
+			writeError(w, r, kind, err)
+			return
+		}
+
+		writeJSON(w, r, http.StatusOK, stored)
+	}
+}
+
+// HandleDeleteEntity returns handler of admin's request to delete the entity of the kind,
+// which is not referenced by any banner.
+func (h *CatalogHandler) HandleDeleteEntity(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		w.Header().Set("Content-Type", "application/json")
+		id, ok := entityID(w, r)
+		if !ok {
+			return
+		}
+
+		err := h.Service.Delete(ctx, kind, id)
+		if err != nil {
+			logger.Log.Error("HandleDeleteEntity: delete entity failed",
+				zap.String("kind", kind),
+				zap.Error(err))
+
+			writeError(w, r, kind, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// entityID returns the entity ID from the request path or writes the error response.
+func entityID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	idString := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idString)
+	if err != nil || id < 1 {
+		logger.Log.Error("entityID: incorrect id parameter",
+			zap.String("id", idString),
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter must be a positive integer")
+		return 0, false
+	}
+
+	return id, true
+}
+
+// writeError writes the error response for the failed request to the entity of the kind.
+func writeError(w http.ResponseWriter, r *http.Request, kind string, err error) {
+	var verr *errs.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		utils.WriteValidationError(w, r, verr)
+	case errors.Is(err, errs.ErrTagNotFound), errors.Is(err, errs.ErrFeatureNotFound):
+		utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, kind+" not found")
+	case errors.Is(err, errs.ErrEntityInUse):
+		utils.WriteError(w, r, http.StatusConflict, utils.CodeInUse, kind+" is referenced by banners")
+	default:
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+	}
+}
+
+// writeJSON writes the response with the requested status code and v marshaled to JSON.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		logger.Log.Error("writeJSON: marshal response failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(status)
+	w.Write(out)
+}
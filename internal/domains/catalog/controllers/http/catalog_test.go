@@ -0,0 +1,199 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/controllers/handlers"
+	auditrepo "github.com/pavlegich/banners-service/internal/domains/audit/repository"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	caprepo "github.com/pavlegich/banners-service/internal/domains/capping/repository"
+	"github.com/pavlegich/banners-service/internal/domains/catalog"
+	catalogrepo "github.com/pavlegich/banners-service/internal/domains/catalog/repository"
+	statsrepo "github.com/pavlegich/banners-service/internal/domains/stats/repository"
+	webhookrepo "github.com/pavlegich/banners-service/internal/domains/webhook/repository"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRoute returns the server route on the in-memory storage.
+func newRoute(t *testing.T) http.Handler {
+	t.Helper()
+	ctx := context.Background()
+
+	ctrl := handlers.NewController(ctx, repository.NewBannerMemoryRepository(ctx, nil), repository.NewBannerCache(ctx, time.Minute, time.Minute),
+		catalogrepo.NewCatalogMemoryRepository(ctx), auditrepo.NewAuditMemoryRepository(ctx), webhookrepo.NewWebhookMemoryRepository(ctx), statsrepo.NewStatsMemoryRepository(ctx), caprepo.NewCapMemoryRepository(ctx), &config.Config{})
+	mh, err := ctrl.BuildRoute(ctx)
+	require.NoError(t, err)
+
+	return mh
+}
+
+// serve sends the request with the token to the route and returns the response.
+func serve(t *testing.T, h http.Handler, method string, url string, body string, token string) (*http.Response, string) {
+	t.Helper()
+
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r.Header.Set("token", token)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	gotBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(gotBody)
+}
+
+func TestCatalogHandler_Entities(t *testing.T) {
+	mh := newRoute(t)
+
+	resp, body := serve(t, mh, http.MethodPost, "/feature", `{"name": "onboarding", "owner": "growth", "default_locale": "en"}`, "admin_token")
+	require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+
+	var feature catalog.Entity
+	require.NoError(t, json.Unmarshal([]byte(body), &feature))
+	assert.Equal(t, 1, feature.ID)
+	assert.Equal(t, "onboarding", feature.Name)
+	assert.Equal(t, "growth", feature.Owner)
+	assert.Equal(t, "en", feature.Locale)
+
+	resp, body = serve(t, mh, http.MethodPatch, "/feature/1", `{"default_locale": "pt-BR"}`, "admin_token")
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Contains(t, body, `"default_locale":"pt-BR"`)
+
+	resp, body = serve(t, mh, http.MethodPost, "/tag", `{"name": "newcomers"}`, "admin_token")
+	require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+
+	resp, body = serve(t, mh, http.MethodPatch, "/tag/1", `{"description": "users registered this week"}`, "admin_token")
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Contains(t, body, `"name":"newcomers","description":"users registered this week"`)
+
+	resp, body = serve(t, mh, http.MethodGet, "/tag?limit=10", "", "admin_token")
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	var tags []catalog.Entity
+	require.NoError(t, json.Unmarshal([]byte(body), &tags))
+	require.Len(t, tags, 1)
+	assert.Equal(t, "users registered this week", tags[0].Description)
+
+	// The banner references the created entities by ID
+	resp, body = serve(t, mh, http.MethodPost, "/banner", `{"tag_ids": [1], "feature_id": 1, "content": {}}`, "admin_token")
+	require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+
+	resp, _ = serve(t, mh, http.MethodDelete, "/feature/1", "", "admin_token")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, _ = serve(t, mh, http.MethodGet, "/feature/1", "", "admin_token")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCatalogHandler_Errors(t *testing.T) {
+	mh := newRoute(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		body        string
+		token       string
+		wantCode    int
+		wantErrCode string
+	}{
+		{
+			name:        "empty name",
+			method:      http.MethodPost,
+			url:         "/tag",
+			body:        `{"owner": "ads"}`,
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeValidationFailed,
+		},
+		{
+			name:        "default locale of tag",
+			method:      http.MethodPost,
+			url:         "/tag",
+			body:        `{"name": "newcomers", "default_locale": "en"}`,
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeValidationFailed,
+		},
+		{
+			name:        "incorrect default locale",
+			method:      http.MethodPost,
+			url:         "/feature",
+			body:        `{"name": "onboarding", "default_locale": "english please"}`,
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeValidationFailed,
+		},
+		{
+			name:        "incorrect body",
+			method:      http.MethodPost,
+			url:         "/feature",
+			body:        `{"name": 1}`,
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeInvalidBody,
+		},
+		{
+			name:        "incorrect id",
+			method:      http.MethodGet,
+			url:         "/tag/0",
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeInvalidPath,
+		},
+		{
+			name:        "incorrect query",
+			method:      http.MethodGet,
+			url:         "/feature?feature_id=1",
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeInvalidQuery,
+		},
+		{
+			name:        "tag not found",
+			method:      http.MethodPatch,
+			url:         "/tag/1",
+			body:        `{"name": "newcomers"}`,
+			wantCode:    http.StatusNotFound,
+			wantErrCode: utils.CodeNotFound,
+		},
+		{
+			name:        "feature not found",
+			method:      http.MethodDelete,
+			url:         "/feature/1",
+			wantCode:    http.StatusNotFound,
+			wantErrCode: utils.CodeNotFound,
+		},
+		{
+			name:        "forbidden",
+			method:      http.MethodGet,
+			url:         "/tag",
+			token:       "user_token",
+			wantCode:    http.StatusForbidden,
+			wantErrCode: utils.CodeForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.token
+			if token == "" {
+				token = "admin_token"
+			}
+
+			resp, body := serve(t, mh, tt.method, tt.url, tt.body, token)
+			require.Equal(t, tt.wantCode, resp.StatusCode, body)
+
+			var got utils.ErrorResponse
+			require.NoError(t, json.Unmarshal([]byte(body), &got))
+			assert.Equal(t, tt.wantErrCode, got.Code)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Package catalog contains object and methods
+// for managing the tags and features referenced by the banners.
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+)
+
+// List of the kinds of the catalog entities.
+const (
+	KindTag     = "tag"
+	KindFeature = "feature"
+)
+
+// Limits of the catalog entity data.
+const (
+	MaxNameLength        = 128
+	MaxDescriptionLength = 1024
+	MaxOwnerLength       = 128
+)
+
+// Entity contains data of the tag or feature. The default locale is set for the features only,
+// the banners of the feature are shown in it, if none of the user locales is available.
+type Entity struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Owner       string    `json:"owner"`
+	Locale      string    `json:"default_locale,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Patch contains the changed fields of the entity, nil fields are not changed.
+type Patch struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Owner       *string `json:"owner"`
+	Locale      *string `json:"default_locale"`
+}
+
+// Service describes methods for communication between
+// handlers, other services and repositories.
+type Service interface {
+	Create(ctx context.Context, kind string, e *Entity) (*Entity, error)
+	List(ctx context.Context, kind string, limit int, offset int) ([]*Entity, error)
+	Get(ctx context.Context, kind string, id int) (*Entity, error)
+	Update(ctx context.Context, kind string, id int, patch *Patch) (*Entity, error)
+	Delete(ctx context.Context, kind string, id int) error
+	Names(ctx context.Context, featureIDs []int, tagIDs []int) (map[int]string, map[int]string, error)
+	DefaultLocale(ctx context.Context, featureID int) (string, error)
+}
+
+// Repository describes methods related with tags and features
+// for interaction with the storage.
+type Repository interface {
+	CreateEntity(ctx context.Context, kind string, e *Entity) (*Entity, error)
+	GetEntities(ctx context.Context, kind string, limit int, offset int) ([]*Entity, error)
+	GetEntitiesByIDs(ctx context.Context, kind string, ids []int) ([]*Entity, error)
+	GetEntityByID(ctx context.Context, kind string, id int) (*Entity, error)
+	UpdateEntity(ctx context.Context, kind string, e *Entity) (*Entity, error)
+	DeleteEntityByID(ctx context.Context, kind string, id int) error
+}
+
+// Banners describes methods for finding the banners referencing the entity.
+type Banners interface {
+	GetBannersByFilter(ctx context.Context, featureID int, tagID int, limit int, offset int, deleted bool) ([]*banner.Banner, error)
+}
+
+// NotFound returns the not found error of the entity kind.
+func NotFound(kind string) error {
+	if kind == KindFeature {
+		return errs.ErrFeatureNotFound
+	}
+	return errs.ErrTagNotFound
+}
+
+// Validate checks the entity fields of the kind and returns the validation error
+// with all the failed fields, if any.
+func (e *Entity) Validate(kind string) error {
+	verr := &errs.ValidationError{}
+
+	switch {
+	case e.Name == "":
+		verr.Add("name", errs.ValidationRequired, "is required")
+	case utf8.RuneCountInString(e.Name) > MaxNameLength:
+		verr.Add("name", errs.ValidationTooLong, fmt.Sprintf("must be at most %d characters", MaxNameLength))
+	}
+
+	if utf8.RuneCountInString(e.Description) > MaxDescriptionLength {
+		verr.Add("description", errs.ValidationTooLong, fmt.Sprintf("must be at most %d characters", MaxDescriptionLength))
+	}
+
+	if utf8.RuneCountInString(e.Owner) > MaxOwnerLength {
+		verr.Add("owner", errs.ValidationTooLong, fmt.Sprintf("must be at most %d characters", MaxOwnerLength))
+	}
+
+	switch {
+	case e.Locale == "":
+	case kind != KindFeature:
+		verr.Add("default_locale", errs.ValidationInvalid, "is supported by features only")
+	case !banner.ValidLocale(e.Locale):
+		verr.Add("default_locale", errs.ValidationInvalid, "must be a language tag, e.g. en or en-US")
+	}
+
+	return verr.Err()
+}
+
+// Apply returns the copy of the entity with the patch applied.
+func (p *Patch) Apply(e *Entity) *Entity {
+	patched := *e
+	if p.Name != nil {
+		patched.Name = *p.Name
+	}
+	if p.Description != nil {
+		patched.Description = *p.Description
+	}
+	if p.Owner != nil {
+		patched.Owner = *p.Owner
+	}
+	if p.Locale != nil {
+		patched.Locale = *p.Locale
+	}
+
+	return &patched
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/catalog"
+)
+
+// MemoryRepository contains tags and features stored in memory
+// for tests and local development.
+type MemoryRepository struct {
+	sync.RWMutex
+	entities map[string][]catalog.Entity
+	lastIDs  map[string]int
+}
+
+// NewCatalogMemoryRepository returns new in-memory tags and features repository object.
+func NewCatalogMemoryRepository(ctx context.Context) *MemoryRepository {
+	return &MemoryRepository{
+		entities: make(map[string][]catalog.Entity, len(tables)),
+		lastIDs:  make(map[string]int, len(tables)),
+	}
+}
+
+// index returns the index of the stored entity by ID or -1, if it is not found.
+func (r *MemoryRepository) index(kind string, id int) int {
+	return slices.IndexFunc(r.entities[kind], func(e catalog.Entity) bool { return e.ID == id })
+}
+
+// CreateEntity stores new entity into the storage.
+func (r *MemoryRepository) CreateEntity(ctx context.Context, kind string, e *catalog.Entity) (*catalog.Entity, error) {
+	_, err := table(kind)
+	if err != nil {
+		return nil, fmt.Errorf("CreateEntity: get table failed %w", err)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastIDs[kind]++
+	e.ID = r.lastIDs[kind]
+	e.CreatedAt = time.Now().Round(time.Microsecond)
+	e.UpdatedAt = e.CreatedAt
+	r.entities[kind] = append(r.entities[kind], *e)
+
+	return e, nil
+}
+
+// GetEntities gets and returns the entities sorted by ID from the storage.
+// Zero limit means no limit.
+func (r *MemoryRepository) GetEntities(ctx context.Context, kind string, limit int, offset int) ([]*catalog.Entity, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	stored := r.entities[kind]
+	if offset > len(stored) {
+		offset = len(stored)
+	}
+	stored = stored[offset:]
+	if limit != 0 && limit < len(stored) {
+		stored = stored[:limit]
+	}
+
+	list := make([]*catalog.Entity, 0, len(stored))
+	for i := range stored {
+		e := stored[i]
+		list = append(list, &e)
+	}
+
+	return list, nil
+}
+
+// GetEntitiesByIDs gets and returns the requested entities by IDs from the storage.
+// The unknown IDs are not presented in the result.
+func (r *MemoryRepository) GetEntitiesByIDs(ctx context.Context, kind string, ids []int) ([]*catalog.Entity, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	list := make([]*catalog.Entity, 0, len(ids))
+	for _, stored := range r.entities[kind] {
+		if slices.Contains(ids, stored.ID) {
+			e := stored
+			list = append(list, &e)
+		}
+	}
+
+	return list, nil
+}
+
+// GetEntityByID gets and returns the requested entity by ID from the storage.
+func (r *MemoryRepository) GetEntityByID(ctx context.Context, kind string, id int) (*catalog.Entity, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	i := r.index(kind, id)
+	if i == -1 {
+		return nil, fmt.Errorf("GetEntityByID: %s %d %w", kind, id, catalog.NotFound(kind))
+	}
+
+	e := r.entities[kind][i]
+	return &e, nil
+}
+
+// UpdateEntity updates the requested entity in the storage.
+func (r *MemoryRepository) UpdateEntity(ctx context.Context, kind string, e *catalog.Entity) (*catalog.Entity, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	i := r.index(kind, e.ID)
+	if i == -1 {
+		return nil, fmt.Errorf("UpdateEntity: %s %d %w", kind, e.ID, catalog.NotFound(kind))
+	}
+
+	e.CreatedAt = r.entities[kind][i].CreatedAt
+	e.UpdatedAt = time.Now().Round(time.Microsecond)
+	r.entities[kind][i] = *e
+
+	return e, nil
+}
+
+// DeleteEntityByID removes the requested entity from the storage.
+func (r *MemoryRepository) DeleteEntityByID(ctx context.Context, kind string, id int) error {
+	r.Lock()
+	defer r.Unlock()
+
+	i := r.index(kind, id)
+	if i == -1 {
+		return fmt.Errorf("DeleteEntityByID: %s %d %w", kind, id, catalog.NotFound(kind))
+	}
+
+	r.entities[kind] = slices.Delete(r.entities[kind], i, i+1)
+
+	return nil
+}
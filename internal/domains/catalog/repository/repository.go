@@ -0,0 +1,232 @@
+// Package repository contains repository objects
+// and methods for interaction with tags and features storage.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/pavlegich/banners-service/internal/domains/catalog"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+)
+
+// foreignKeyViolation is the PostgreSQL error code of the foreign key violation.
+const foreignKeyViolation = "23503"
+
+// entityColumns returns the selected columns of the entity kind,
+// only the features have the default locale.
+func entityColumns(kind string) string {
+	locale := "''"
+	if kind == catalog.KindFeature {
+		locale = "default_locale"
+	}
+	return "id, name, description, owner, " + locale + ", created_at, updated_at"
+}
+
+// tables are the storage tables of the entity kinds.
+var tables = map[string]string{
+	catalog.KindTag:     "tags",
+	catalog.KindFeature: "features",
+}
+
+// Repository contains storage objects for storing the tags and features.
+type Repository struct {
+	db *sql.DB
+}
+
+// NewCatalogRepository returns new tags and features repository object.
+func NewCatalogRepository(ctx context.Context, db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// table returns the storage table of the entity kind.
+func table(kind string) (string, error) {
+	t, ok := tables[kind]
+	if !ok {
+		return "", fmt.Errorf("table: unknown entity kind %s", kind)
+	}
+	return t, nil
+}
+
+// CreateEntity stores new entity into the storage.
+func (r *Repository) CreateEntity(ctx context.Context, kind string, e *catalog.Entity) (*catalog.Entity, error) {
+	t, err := table(kind)
+	if err != nil {
+		return nil, fmt.Errorf("CreateEntity: get table failed %w", err)
+	}
+
+	query := `INSERT INTO ` + t + ` (name, description, owner)
+	VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`
+	args := []any{e.Name, e.Description, e.Owner}
+	if kind == catalog.KindFeature {
+		query = `INSERT INTO features (name, description, owner, default_locale)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
+		args = append(args, e.Locale)
+	}
+
+	row := r.db.QueryRowContext(ctx, query, args...)
+
+	err = row.Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("CreateEntity: scan row failed %w", err)
+	}
+
+	return e, nil
+}
+
+// GetEntities gets and returns the entities sorted by ID from the storage.
+// Zero limit means no limit.
+func (r *Repository) GetEntities(ctx context.Context, kind string, limit int, offset int) ([]*catalog.Entity, error) {
+	t, err := table(kind)
+	if err != nil {
+		return nil, fmt.Errorf("GetEntities: get table failed %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+entityColumns(kind)+` FROM `+t+`
+	ORDER BY id LIMIT NULLIF($1, 0) OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("GetEntities: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	list, err := scanEntities(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetEntities: scan entities failed %w", err)
+	}
+
+	return list, nil
+}
+
+// GetEntitiesByIDs gets and returns the requested entities by IDs from the storage.
+// The unknown IDs are not presented in the result.
+func (r *Repository) GetEntitiesByIDs(ctx context.Context, kind string, ids []int) ([]*catalog.Entity, error) {
+	t, err := table(kind)
+	if err != nil {
+		return nil, fmt.Errorf("GetEntitiesByIDs: get table failed %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+entityColumns(kind)+` FROM `+t+`
+	WHERE id = ANY ($1) ORDER BY id`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("GetEntitiesByIDs: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	list, err := scanEntities(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetEntitiesByIDs: scan entities failed %w", err)
+	}
+
+	return list, nil
+}
+
+// GetEntityByID gets and returns the requested entity by ID from the storage.
+func (r *Repository) GetEntityByID(ctx context.Context, kind string, id int) (*catalog.Entity, error) {
+	list, err := r.GetEntitiesByIDs(ctx, kind, []int{id})
+	if err != nil {
+		return nil, fmt.Errorf("GetEntityByID: get entities failed %w", err)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("GetEntityByID: %s %d %w", kind, id, catalog.NotFound(kind))
+	}
+
+	return list[0], nil
+}
+
+// UpdateEntity updates the requested entity in the storage.
+func (r *Repository) UpdateEntity(ctx context.Context, kind string, e *catalog.Entity) (*catalog.Entity, error) {
+	t, err := table(kind)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateEntity: get table failed %w", err)
+	}
+
+	query := `UPDATE ` + t + ` SET name = $1, description = $2, owner = $3, updated_at = NOW()
+	WHERE id = $4 RETURNING updated_at`
+	args := []any{e.Name, e.Description, e.Owner, e.ID}
+	if kind == catalog.KindFeature {
+		query = `UPDATE features SET name = $1, description = $2, owner = $3, default_locale = $5, updated_at = NOW()
+		WHERE id = $4 RETURNING updated_at`
+		args = append(args, e.Locale)
+	}
+
+	row := r.db.QueryRowContext(ctx, query, args...)
+
+	err = row.Scan(&e.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("UpdateEntity: %s %d %w", kind, e.ID, catalog.NotFound(kind))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("UpdateEntity: scan row failed %w", err)
+	}
+
+	return e, nil
+}
+
+// DeleteEntityByID removes the requested entity from the storage,
+// the entity referenced by the banners is not removed.
+func (r *Repository) DeleteEntityByID(ctx context.Context, kind string, id int) error {
+	t, err := table(kind)
+	if err != nil {
+		return fmt.Errorf("DeleteEntityByID: get table failed %w", err)
+	}
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM `+t+` WHERE id = $1`, id)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("DeleteEntityByID: %s %d is %w", kind, id, errs.ErrEntityInUse)
+		}
+		return fmt.Errorf("DeleteEntityByID: delete data failed %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteEntityByID: get affected rows failed %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("DeleteEntityByID: %s %d %w", kind, id, catalog.NotFound(kind))
+	}
+
+	return nil
+}
+
+// isForeignKeyViolation checks whether the error is the foreign key violation
+// returned by either of the database drivers.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == foreignKeyViolation
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == foreignKeyViolation
+	}
+
+	return false
+}
+
+// scanEntities reads the entities from the rows.
+func scanEntities(rows *sql.Rows) ([]*catalog.Entity, error) {
+	list := make([]*catalog.Entity, 0)
+	for rows.Next() {
+		var e catalog.Entity
+		err := rows.Scan(&e.ID, &e.Name, &e.Description, &e.Owner, &e.Locale, &e.CreatedAt, &e.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scanEntities: scan row failed %w", err)
+		}
+		list = append(list, &e)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("scanEntities: rows.Err %w", err)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,158 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+
+	errs "github.com/pavlegich/banners-service/internal/errors"
+)
+
+// CatalogService contains objects for catalog service.
+type CatalogService struct {
+	repo    Repository
+	banners Banners
+}
+
+// NewCatalogService returns new catalog service. The banners are used
+// for checking whether the deleted entity is still referenced.
+func NewCatalogService(ctx context.Context, repo Repository, banners Banners) *CatalogService {
+	return &CatalogService{
+		repo:    repo,
+		banners: banners,
+	}
+}
+
+// Create validates new entity and puts it into the storage.
+func (s *CatalogService) Create(ctx context.Context, kind string, e *Entity) (*Entity, error) {
+	err := e.Validate(kind)
+	if err != nil {
+		return nil, fmt.Errorf("Create: %s is invalid %w", kind, err)
+	}
+
+	stored, err := s.repo.CreateEntity(ctx, kind, e)
+	if err != nil {
+		return nil, fmt.Errorf("Create: create %s failed %w", kind, err)
+	}
+
+	return stored, nil
+}
+
+// List returns list of the entities sorted by ID.
+func (s *CatalogService) List(ctx context.Context, kind string, limit int, offset int) ([]*Entity, error) {
+	list, err := s.repo.GetEntities(ctx, kind, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("List: get %s list failed %w", kind, err)
+	}
+
+	return list, nil
+}
+
+// Get returns the requested entity by ID.
+func (s *CatalogService) Get(ctx context.Context, kind string, id int) (*Entity, error) {
+	e, err := s.repo.GetEntityByID(ctx, kind, id)
+	if err != nil {
+		return nil, fmt.Errorf("Get: get %s failed %w", kind, err)
+	}
+
+	return e, nil
+}
+
+// Update applies the patch to the requested entity, validates and stores it.
+func (s *CatalogService) Update(ctx context.Context, kind string, id int, patch *Patch) (*Entity, error) {
+	before, err := s.repo.GetEntityByID(ctx, kind, id)
+	if err != nil {
+		return nil, fmt.Errorf("Update: get %s before update failed %w", kind, err)
+	}
+
+	e := patch.Apply(before)
+	err = e.Validate(kind)
+	if err != nil {
+		return nil, fmt.Errorf("Update: patched %s is invalid %w", kind, err)
+	}
+
+	stored, err := s.repo.UpdateEntity(ctx, kind, e)
+	if err != nil {
+		return nil, fmt.Errorf("Update: update %s failed %w", kind, err)
+	}
+
+	return stored, nil
+}
+
+// Delete removes the requested entity by ID from the storage, if it is not
+// referenced by any banner, including the deleted ones.
+func (s *CatalogService) Delete(ctx context.Context, kind string, id int) error {
+	_, err := s.repo.GetEntityByID(ctx, kind, id)
+	if err != nil {
+		return fmt.Errorf("Delete: get %s failed %w", kind, err)
+	}
+
+	featureID, tagID := 0, id
+	if kind == KindFeature {
+		featureID, tagID = id, 0
+	}
+	for _, deleted := range []bool{false, true} {
+		banners, err := s.banners.GetBannersByFilter(ctx, featureID, tagID, 1, 0, deleted)
+		if err != nil {
+			return fmt.Errorf("Delete: get %s banners failed %w", kind, err)
+		}
+		if len(banners) != 0 {
+			return fmt.Errorf("Delete: %s %d is %w", kind, id, errs.ErrEntityInUse)
+		}
+	}
+
+	err = s.repo.DeleteEntityByID(ctx, kind, id)
+	if err != nil {
+		return fmt.Errorf("Delete: delete %s failed %w", kind, err)
+	}
+
+	return nil
+}
+
+// Names returns the names of the requested features and tags by ID.
+// The unknown IDs are not presented in the result.
+func (s *CatalogService) Names(ctx context.Context, featureIDs []int, tagIDs []int) (map[int]string, map[int]string, error) {
+	features, err := s.names(ctx, KindFeature, featureIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Names: get feature names failed %w", err)
+	}
+
+	tags, err := s.names(ctx, KindTag, tagIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Names: get tag names failed %w", err)
+	}
+
+	return features, tags, nil
+}
+
+// names returns the names of the requested entities by ID.
+func (s *CatalogService) names(ctx context.Context, kind string, ids []int) (map[int]string, error) {
+	names := make(map[int]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	list, err := s.repo.GetEntitiesByIDs(ctx, kind, ids)
+	if err != nil {
+		return nil, fmt.Errorf("names: get entities failed %w", err)
+	}
+
+	for _, e := range list {
+		names[e.ID] = e.Name
+	}
+
+	return names, nil
+}
+
+// DefaultLocale returns the default locale of the requested feature by ID.
+// The unknown feature has no default locale.
+func (s *CatalogService) DefaultLocale(ctx context.Context, featureID int) (string, error) {
+	list, err := s.repo.GetEntitiesByIDs(ctx, KindFeature, []int{featureID})
+	if err != nil {
+		return "", fmt.Errorf("DefaultLocale: get feature failed %w", err)
+	}
+	if len(list) == 0 {
+		return "", nil
+	}
+
+	return list[0].Locale, nil
+}
@@ -0,0 +1,85 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	bannerrepo "github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	"github.com/pavlegich/banners-service/internal/domains/catalog"
+	"github.com/pavlegich/banners-service/internal/domains/catalog/repository"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogService_CRUD(t *testing.T) {
+	ctx := context.Background()
+	s := catalog.NewCatalogService(ctx, repository.NewCatalogMemoryRepository(ctx), bannerrepo.NewBannerMemoryRepository(ctx, nil))
+
+	_, err := s.Create(ctx, catalog.KindTag, &catalog.Entity{Owner: "ads"})
+	var verr *errs.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []errs.FieldError{{Field: "name", Code: errs.ValidationRequired, Message: "is required"}}, verr.Fields)
+
+	tag, err := s.Create(ctx, catalog.KindTag, &catalog.Entity{Name: "newcomers", Owner: "ads"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, tag.ID)
+
+	// The kinds are numbered separately
+	feature, err := s.Create(ctx, catalog.KindFeature, &catalog.Entity{Name: "onboarding"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, feature.ID)
+
+	description := "users registered this week"
+	updated, err := s.Update(ctx, catalog.KindTag, tag.ID, &catalog.Patch{Description: &description})
+	require.NoError(t, err)
+	assert.Equal(t, "newcomers", updated.Name)
+	assert.Equal(t, description, updated.Description)
+	assert.Equal(t, "ads", updated.Owner)
+
+	list, err := s.List(ctx, catalog.KindTag, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, description, list[0].Description)
+
+	features, tags, err := s.Names(ctx, []int{1, 2}, []int{1})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]string{1: "onboarding"}, features)
+	assert.Equal(t, map[int]string{1: "newcomers"}, tags)
+
+	err = s.Delete(ctx, catalog.KindFeature, feature.ID)
+	require.NoError(t, err)
+
+	_, err = s.Get(ctx, catalog.KindFeature, feature.ID)
+	assert.ErrorIs(t, err, errs.ErrFeatureNotFound)
+
+	err = s.Delete(ctx, catalog.KindTag, 2)
+	assert.ErrorIs(t, err, errs.ErrTagNotFound)
+}
+
+func TestCatalogService_DeleteReferenced(t *testing.T) {
+	ctx := context.Background()
+	banners := bannerrepo.NewBannerMemoryRepository(ctx, nil)
+	s := catalog.NewCatalogService(ctx, repository.NewCatalogMemoryRepository(ctx), banners)
+
+	for _, kind := range []string{catalog.KindFeature, catalog.KindTag} {
+		_, err := s.Create(ctx, kind, &catalog.Entity{Name: kind})
+		require.NoError(t, err)
+	}
+
+	stored, err := banners.CreateBanner(ctx, &banner.Banner{TagIDs: []int{1}, FeatureID: 1, Content: &banner.Content{}})
+	require.NoError(t, err)
+
+	for _, kind := range []string{catalog.KindFeature, catalog.KindTag} {
+		err = s.Delete(ctx, kind, 1)
+		assert.ErrorIs(t, err, errs.ErrEntityInUse)
+	}
+
+	// The deleted banner might be restored, so it still references the entities
+	err = banners.DeleteBannerByID(ctx, stored.ID, 0)
+	require.NoError(t, err)
+
+	err = s.Delete(ctx, catalog.KindTag, 1)
+	assert.ErrorIs(t, err, errs.ErrEntityInUse)
+}
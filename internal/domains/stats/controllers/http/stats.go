@@ -0,0 +1,190 @@
+// Package http contains stats object functions
+// for activating the handler in controller, and handlers.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/banners-service/internal/domains/stats"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"go.uber.org/zap"
+)
+
+// maxBodySize is the maximum size of the request body in bytes.
+const maxBodySize = 1 << 10
+
+// defaultDays is the number of days in the stats period, if it is not requested.
+const defaultDays = 30
+
+// clickRequest contains data of the banner click report.
+type clickRequest struct {
+	BannerID int `json:"banner_id"`
+	TagID    int `json:"tag_id"`
+}
+
+// StatsHandler contains objects for work with stats handlers.
+type StatsHandler struct {
+	Config  *config.Config
+	Service stats.Service
+}
+
+// Activate activates handlers for stats object.
+func Activate(ctx context.Context, r *chi.Mux, cfg *config.Config, s stats.Service) {
+	h := &StatsHandler{
+		Config:  cfg,
+		Service: s,
+	}
+
+	r.Post("/user_banner/click", h.HandleClick)
+	r.Get("/banner/{id}/stats", h.HandleGetStats)
+}
+
+// HandleClick handles user's report of the click on the banner shown for the tag.
+func (h *StatsHandler) HandleClick(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req clickRequest
+
+	w.Header().Set("Content-Type", "application/json")
+
+	defer r.Body.Close()
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req)
+	if err != nil {
+		logger.Log.Error("HandleClick: decode request body failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "request body must be a JSON object")
+		return
+	}
+
+	verr := &errs.ValidationError{}
+	if req.BannerID < 1 {
+		verr.Add("banner_id", errs.ValidationPositive, "must be positive")
+	}
+	if req.TagID < 1 {
+		verr.Add("tag_id", errs.ValidationPositive, "must be positive")
+	}
+	if verr.Err() != nil {
+		logger.Log.Error("HandleClick: validate request failed",
+			zap.Int("banner_id", req.BannerID),
+			zap.Int("tag_id", req.TagID))
+
+		utils.WriteValidationError(w, r, verr)
+		return
+	}
+
+	err = h.Service.TrackClick(ctx, req.BannerID, req.TagID)
+	if err != nil {
+		logger.Log.Error("HandleClick: track click failed",
+			zap.Error(err))
+
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Del("Content-Type")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetStats handles admin's request to get the banner impressions and clicks
+// by days. The last 30 days are returned, if the period is not requested.
+func (h *StatsHandler) HandleGetStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	idString := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idString)
+	if err != nil || id < 1 {
+		logger.Log.Error("HandleGetStats: incorrect id parameter",
+			zap.String("id", idString),
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter must be a positive integer")
+		return
+	}
+
+	to := time.Now()
+	var from time.Time
+	queries := r.URL.Query()
+	for val := range queries {
+		if val != "from" && val != "to" {
+			logger.Log.Error("HandleGetStats: incorrect query",
+				zap.String("query", val))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query in request url")
+			return
+		}
+
+		if len(queries[val]) != 1 {
+			logger.Log.Error("HandleGetStats: incorrect queries number",
+				zap.String("query_name", val),
+				zap.Int("query_number", len(queries[val])))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
+			return
+		}
+
+		day, err := time.Parse(stats.DateLayout, queries[val][0])
+		if err != nil {
+			logger.Log.Error("HandleGetStats: convert query to date failed",
+				zap.String("query_name", val),
+				zap.String("query_value", queries[val][0]))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "dates must be in YYYY-MM-DD format")
+			return
+		}
+
+		if val == "from" {
+			from = day
+		} else {
+			to = day
+		}
+	}
+	if from.IsZero() {
+		from = stats.DayOf(to).AddDate(0, 0, 1-defaultDays)
+	}
+
+	st, err := h.Service.Stats(ctx, id, from, to)
+	if err != nil {
+		logger.Log.Error("HandleGetStats: get banner stats failed",
+			zap.Int("banner_id", id),
+			zap.Error(err))
+
+		writeError(w, r, err)
+		return
+	}
+
+	out, err := json.Marshal(st)
+	if err != nil {
+		logger.Log.Error("HandleGetStats: marshal banner stats failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+// writeError writes the error response for the failed stats request.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var verr *errs.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		utils.WriteValidationError(w, r, verr)
+	case errors.Is(err, errs.ErrBannerNotFound):
+		utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "banner not found")
+	default:
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+	}
+}
@@ -0,0 +1,186 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/controllers/handlers"
+	auditrepo "github.com/pavlegich/banners-service/internal/domains/audit/repository"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	caprepo "github.com/pavlegich/banners-service/internal/domains/capping/repository"
+	catalogrepo "github.com/pavlegich/banners-service/internal/domains/catalog/repository"
+	"github.com/pavlegich/banners-service/internal/domains/stats"
+	statsrepo "github.com/pavlegich/banners-service/internal/domains/stats/repository"
+	webhookrepo "github.com/pavlegich/banners-service/internal/domains/webhook/repository"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRoute returns the server route on the in-memory storage with the stored banner.
+func newRoute(t *testing.T) http.Handler {
+	t.Helper()
+	ctx := context.Background()
+
+	ctrl := handlers.NewController(ctx, repository.NewBannerMemoryRepository(ctx, nil), repository.NewBannerCache(ctx, time.Minute, time.Minute),
+		catalogrepo.NewCatalogMemoryRepository(ctx), auditrepo.NewAuditMemoryRepository(ctx), webhookrepo.NewWebhookMemoryRepository(ctx),
+		statsrepo.NewStatsMemoryRepository(ctx), caprepo.NewCapMemoryRepository(ctx), &config.Config{})
+	mh, err := ctrl.BuildRoute(ctx)
+	require.NoError(t, err)
+
+	for _, req := range [][2]string{
+		{"/feature", `{"name": "onboarding"}`},
+		{"/tag", `{"name": "newcomers"}`},
+		{"/banner", `{"tag_ids": [1], "feature_id": 1, "content": {"title": "some_title"}, "is_active": true}`},
+	} {
+		resp, body := serve(t, mh, http.MethodPost, req[0], req[1], "admin_token")
+		require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+	}
+
+	return mh
+}
+
+// serve sends the request with the token to the route and returns the response.
+func serve(t *testing.T, h http.Handler, method string, url string, body string, token string) (*http.Response, string) {
+	t.Helper()
+
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r.Header.Set("token", token)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	gotBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(gotBody)
+}
+
+func TestStatsHandler_Stats(t *testing.T) {
+	mh := newRoute(t)
+
+	for i := 0; i < 4; i++ {
+		resp, body := serve(t, mh, http.MethodGet, "/user_banner?feature_id=1&tag_id=1", "", "user_token")
+		require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	}
+
+	// Admin requests are not counted as impressions
+	resp, body := serve(t, mh, http.MethodGet, "/user_banner?feature_id=1&tag_id=1", "", "admin_token")
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	resp, body = serve(t, mh, http.MethodPost, "/user_banner/click", `{"banner_id": 1, "tag_id": 1}`, "user_token")
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, body)
+
+	resp, body = serve(t, mh, http.MethodGet, "/banner/1/stats", "", "admin_token")
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	var got stats.Stats
+	require.NoError(t, json.Unmarshal([]byte(body), &got))
+	assert.Equal(t, stats.Totals{Impressions: 4, Clicks: 1, CTR: 0.25}, got.Totals)
+	assert.Equal(t, []stats.Tag{{TagID: 1, Totals: got.Totals}}, got.Tags)
+	require.Len(t, got.Days, 30)
+	assert.Equal(t, time.Now().UTC().Format(stats.DateLayout), got.To)
+	assert.Equal(t, got.Totals, got.Days[29].Totals)
+	assert.Equal(t, stats.Totals{}, got.Days[0].Totals)
+
+	resp, body = serve(t, mh, http.MethodGet, "/banner/1/stats?from=2026-01-01&to=2026-01-03", "", "admin_token")
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	require.NoError(t, json.Unmarshal([]byte(body), &got))
+	assert.Len(t, got.Days, 3)
+	assert.Equal(t, "2026-01-01", got.From)
+}
+
+func TestStatsHandler_Errors(t *testing.T) {
+	mh := newRoute(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		body        string
+		token       string
+		wantCode    int
+		wantErrCode string
+	}{
+		{
+			name:        "click on unknown banner",
+			method:      http.MethodPost,
+			url:         "/user_banner/click",
+			body:        `{"banner_id": 2, "tag_id": 1}`,
+			wantCode:    http.StatusNotFound,
+			wantErrCode: utils.CodeNotFound,
+		},
+		{
+			name:        "click without tag",
+			method:      http.MethodPost,
+			url:         "/user_banner/click",
+			body:        `{"banner_id": 1}`,
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeValidationFailed,
+		},
+		{
+			name:        "incorrect click body",
+			method:      http.MethodPost,
+			url:         "/user_banner/click",
+			body:        `[1, 1]`,
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeInvalidBody,
+		},
+		{
+			name:        "stats of unknown banner",
+			method:      http.MethodGet,
+			url:         "/banner/2/stats",
+			token:       "admin_token",
+			wantCode:    http.StatusNotFound,
+			wantErrCode: utils.CodeNotFound,
+		},
+		{
+			name:        "incorrect date",
+			method:      http.MethodGet,
+			url:         "/banner/1/stats?from=yesterday",
+			token:       "admin_token",
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeInvalidQuery,
+		},
+		{
+			name:        "reversed period",
+			method:      http.MethodGet,
+			url:         "/banner/1/stats?from=2026-01-03&to=2026-01-01",
+			token:       "admin_token",
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: utils.CodeValidationFailed,
+		},
+		{
+			name:        "stats for user",
+			method:      http.MethodGet,
+			url:         "/banner/1/stats",
+			wantCode:    http.StatusForbidden,
+			wantErrCode: utils.CodeForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.token
+			if token == "" {
+				token = "user_token"
+			}
+
+			resp, body := serve(t, mh, tt.method, tt.url, tt.body, token)
+			require.Equal(t, tt.wantCode, resp.StatusCode, body)
+
+			var got utils.ErrorResponse
+			require.NoError(t, json.Unmarshal([]byte(body), &got))
+			assert.Equal(t, tt.wantErrCode, got.Code)
+		})
+	}
+}
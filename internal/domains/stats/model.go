@@ -0,0 +1,118 @@
+// Package stats contains object and methods
+// for counting the banners impressions and clicks.
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+)
+
+// DateLayout is the layout of the day in the stats.
+const DateLayout = "2006-01-02"
+
+// MaxDays is the maximum number of days in the requested stats period.
+const MaxDays = 366
+
+// Counter contains the number of the banner impressions and clicks
+// for the tag during the day.
+type Counter struct {
+	BannerID    int
+	TagID       int
+	Day         time.Time
+	Impressions int64
+	Clicks      int64
+}
+
+// Totals contains the number of impressions and clicks and the click-through rate.
+type Totals struct {
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	CTR         float64 `json:"ctr"`
+}
+
+// Day contains the banner impressions and clicks during the day.
+type Day struct {
+	Date string `json:"date"`
+	Totals
+}
+
+// Tag contains the banner impressions and clicks for the tag during the period.
+type Tag struct {
+	TagID int `json:"tag_id"`
+	Totals
+}
+
+// Stats contains the banner impressions and clicks during the period
+// with the series of days from the oldest to the newest.
+type Stats struct {
+	BannerID int    `json:"banner_id"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Totals
+	Tags []Tag `json:"tags"`
+	Days []Day `json:"days"`
+}
+
+// Service describes methods for communication between
+// handlers, other services and repositories.
+type Service interface {
+	TrackImpression(ctx context.Context, bannerID int, tagID int)
+	TrackClick(ctx context.Context, bannerID int, tagID int) error
+	Stats(ctx context.Context, bannerID int, from time.Time, to time.Time) (*Stats, error)
+}
+
+// Repository describes methods related with banners stats
+// for interaction with the storage.
+type Repository interface {
+	AddCounters(ctx context.Context, counters []*Counter) error
+	GetCounters(ctx context.Context, bannerID int, from time.Time, to time.Time) ([]*Counter, error)
+}
+
+// Banners describes the banners storage methods for checking the tracked banners.
+type Banners interface {
+	GetBannerByID(ctx context.Context, id int) (*banner.Banner, error)
+}
+
+// DayOf returns the beginning of the day of the time in UTC.
+func DayOf(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// add adds the counter values to the totals.
+func (t *Totals) add(c *Counter) {
+	t.Impressions += c.Impressions
+	t.Clicks += c.Clicks
+}
+
+// rate calculates the click-through rate of the totals.
+func (t *Totals) rate() {
+	t.CTR = 0
+	if t.Impressions > 0 {
+		t.CTR = float64(t.Clicks) / float64(t.Impressions)
+	}
+}
+
+// ValidatePeriod checks whether the stats might be requested for the period
+// and returns the validation error with all the invalid fields.
+func ValidatePeriod(from time.Time, to time.Time) error {
+	verr := &errs.ValidationError{}
+
+	if to.Before(from) {
+		verr.Add("to", errs.ValidationInvalid, "must not be before from")
+	} else if int(to.Sub(from).Hours()/24) >= MaxDays {
+		verr.Add("to", errs.ValidationTooMany, fmt.Sprintf("period must contain at most %d days", MaxDays))
+	}
+
+	return verr.Err()
+}
+
+// key contains data for unique counter search.
+type key struct {
+	bannerID int
+	tagID    int
+	day      time.Time
+}
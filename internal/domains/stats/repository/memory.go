@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/stats"
+)
+
+// MemoryRepository contains banners stats stored in memory
+// for tests and local development.
+type MemoryRepository struct {
+	sync.RWMutex
+	counters []stats.Counter
+}
+
+// NewStatsMemoryRepository returns new in-memory banners stats repository object.
+func NewStatsMemoryRepository(ctx context.Context) *MemoryRepository {
+	return &MemoryRepository{
+		counters: make([]stats.Counter, 0),
+	}
+}
+
+// AddCounters adds the counters values to the stored counters
+// of the same banner, tag and day.
+func (r *MemoryRepository) AddCounters(ctx context.Context, counters []*stats.Counter) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, c := range counters {
+		found := false
+		for i := range r.counters {
+			stored := &r.counters[i]
+			if stored.BannerID == c.BannerID && stored.TagID == c.TagID && stored.Day.Equal(c.Day) {
+				stored.Impressions += c.Impressions
+				stored.Clicks += c.Clicks
+				found = true
+				break
+			}
+		}
+		if !found {
+			r.counters = append(r.counters, *c)
+		}
+	}
+
+	return nil
+}
+
+// GetCounters gets and returns the banner counters from the day of from
+// till the day of to from the storage.
+func (r *MemoryRepository) GetCounters(ctx context.Context, bannerID int, from time.Time, to time.Time) ([]*stats.Counter, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	counters := make([]*stats.Counter, 0)
+	for _, c := range r.counters {
+		if c.BannerID != bannerID || c.Day.Before(from) || c.Day.After(to) {
+			continue
+		}
+		stored := c
+		counters = append(counters, &stored)
+	}
+
+	return counters, nil
+}
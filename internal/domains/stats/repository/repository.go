@@ -0,0 +1,87 @@
+// Package repository contains repository objects
+// and methods for interaction with banners stats storage.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/stats"
+)
+
+// addCountersQuery is the query for adding the counters values to the stored
+// counters with one query. The counters of the purged banners are skipped.
+const addCountersQuery = `INSERT INTO banner_stats (banner_id, tag_id, day, impressions, clicks) 
+	SELECT c.banner_id, c.tag_id, c.day, c.impressions, c.clicks 
+	FROM unnest($1::integer[], $2::integer[], $3::date[], $4::bigint[], $5::bigint[]) AS c (banner_id, tag_id, day, impressions, clicks) 
+	JOIN banners b ON b.id = c.banner_id 
+	ON CONFLICT (banner_id, tag_id, day) DO UPDATE 
+	SET impressions = banner_stats.impressions + EXCLUDED.impressions, clicks = banner_stats.clicks + EXCLUDED.clicks`
+
+// Repository contains storage objects for storing the banners stats.
+type Repository struct {
+	db *sql.DB
+}
+
+// NewStatsRepository returns new banners stats repository object.
+func NewStatsRepository(ctx context.Context, db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// AddCounters adds the counters values to the stored counters
+// of the same banner, tag and day.
+func (r *Repository) AddCounters(ctx context.Context, counters []*stats.Counter) error {
+	bannerIDs := make([]int, len(counters))
+	tagIDs := make([]int, len(counters))
+	days := make([]time.Time, len(counters))
+	impressions := make([]int64, len(counters))
+	clicks := make([]int64, len(counters))
+	for i, c := range counters {
+		bannerIDs[i] = c.BannerID
+		tagIDs[i] = c.TagID
+		days[i] = c.Day
+		impressions[i] = c.Impressions
+		clicks[i] = c.Clicks
+	}
+
+	_, err := r.db.ExecContext(ctx, addCountersQuery, bannerIDs, tagIDs, days, impressions, clicks)
+	if err != nil {
+		return fmt.Errorf("AddCounters: insert data failed %w", err)
+	}
+
+	return nil
+}
+
+// GetCounters gets and returns the banner counters from the day of from
+// till the day of to from the storage.
+func (r *Repository) GetCounters(ctx context.Context, bannerID int, from time.Time, to time.Time) ([]*stats.Counter, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT banner_id, tag_id, day, impressions, clicks 
+	FROM banner_stats WHERE banner_id = $1 AND day BETWEEN $2 AND $3`,
+		bannerID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("GetCounters: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	counters := make([]*stats.Counter, 0)
+	for rows.Next() {
+		var c stats.Counter
+		err = rows.Scan(&c.BannerID, &c.TagID, &c.Day, &c.Impressions, &c.Clicks)
+		if err != nil {
+			return nil, fmt.Errorf("GetCounters: scan row failed %w", err)
+		}
+		c.Day = stats.DayOf(c.Day)
+		counters = append(counters, &c)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetCounters: rows.Err %w", err)
+	}
+
+	return counters, nil
+}
@@ -0,0 +1,218 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// flushTimeout is the timeout of flushing the pending counters on shutdown.
+const flushTimeout = 5 * time.Second
+
+// StatsService contains objects for stats service. The tracked impressions
+// and clicks are buffered in memory and flushed into the storage periodically.
+type StatsService struct {
+	sync.Mutex
+	repo    Repository
+	banners Banners
+	pending map[key]*Counter
+}
+
+// NewStatsService returns new stats service.
+func NewStatsService(ctx context.Context, repo Repository, banners Banners) *StatsService {
+	return &StatsService{
+		repo:    repo,
+		banners: banners,
+		pending: make(map[key]*Counter),
+	}
+}
+
+// TrackImpression counts the impression of the banner for the tag.
+// The impression is counted in memory, so it never blocks on the storage.
+func (s *StatsService) TrackImpression(ctx context.Context, bannerID int, tagID int) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.counter(bannerID, tagID, time.Now()).Impressions++
+}
+
+// TrackClick counts the click on the requested by ID banner for the tag.
+func (s *StatsService) TrackClick(ctx context.Context, bannerID int, tagID int) error {
+	_, err := s.banners.GetBannerByID(ctx, bannerID)
+	if err != nil {
+		return fmt.Errorf("TrackClick: get banner failed %w", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.counter(bannerID, tagID, time.Now()).Clicks++
+
+	return nil
+}
+
+// counter returns the pending counter of the banner for the tag
+// during the day of the time, the lock must be held.
+func (s *StatsService) counter(bannerID int, tagID int, t time.Time) *Counter {
+	k := key{
+		bannerID: bannerID,
+		tagID:    tagID,
+		day:      DayOf(t),
+	}
+
+	c, ok := s.pending[k]
+	if !ok {
+		c = &Counter{
+			BannerID: bannerID,
+			TagID:    tagID,
+			Day:      k.day,
+		}
+		s.pending[k] = c
+	}
+
+	return c
+}
+
+// Stats returns the requested by ID banner impressions and clicks from the day of from
+// till the day of to, including the not yet flushed ones.
+func (s *StatsService) Stats(ctx context.Context, bannerID int, from time.Time, to time.Time) (*Stats, error) {
+	from, to = DayOf(from), DayOf(to)
+	err := ValidatePeriod(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Stats: validate period failed %w", err)
+	}
+
+	_, err = s.banners.GetBannerByID(ctx, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("Stats: get banner failed %w", err)
+	}
+
+	counters, err := s.repo.GetCounters(ctx, bannerID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Stats: get counters failed %w", err)
+	}
+
+	s.Lock()
+	for k, c := range s.pending {
+		if k.bannerID == bannerID && !k.day.Before(from) && !k.day.After(to) {
+			pending := *c
+			counters = append(counters, &pending)
+		}
+	}
+	s.Unlock()
+
+	return aggregate(bannerID, from, to, counters), nil
+}
+
+// aggregate returns the stats of the banner counters with the series of all
+// the days of the period and the tags ordered by ID.
+func aggregate(bannerID int, from time.Time, to time.Time, counters []*Counter) *Stats {
+	st := &Stats{
+		BannerID: bannerID,
+		From:     from.Format(DateLayout),
+		To:       to.Format(DateLayout),
+		Tags:     make([]Tag, 0),
+		Days:     make([]Day, 0),
+	}
+
+	days := make(map[string]int)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		days[day.Format(DateLayout)] = len(st.Days)
+		st.Days = append(st.Days, Day{Date: day.Format(DateLayout)})
+	}
+
+	tags := make(map[int]int)
+	for _, c := range counters {
+		i, ok := days[DayOf(c.Day).Format(DateLayout)]
+		if !ok {
+			continue
+		}
+		st.Days[i].add(c)
+		st.add(c)
+
+		j, ok := tags[c.TagID]
+		if !ok {
+			j = len(st.Tags)
+			tags[c.TagID] = j
+			st.Tags = append(st.Tags, Tag{TagID: c.TagID})
+		}
+		st.Tags[j].add(c)
+	}
+
+	st.rate()
+	for i := range st.Days {
+		st.Days[i].rate()
+	}
+	for i := range st.Tags {
+		st.Tags[i].rate()
+	}
+	sort.Slice(st.Tags, func(i, j int) bool { return st.Tags[i].TagID < st.Tags[j].TagID })
+
+	return st
+}
+
+// Flush stores the pending counters into the storage. The counters are kept
+// pending, if the storage failed, and flushed with the next counters.
+func (s *StatsService) Flush(ctx context.Context) error {
+	s.Lock()
+	pending := s.pending
+	s.pending = make(map[key]*Counter)
+	s.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	counters := make([]*Counter, 0, len(pending))
+	for _, c := range pending {
+		counters = append(counters, c)
+	}
+
+	err := s.repo.AddCounters(ctx, counters)
+	if err != nil {
+		s.Lock()
+		for k, c := range pending {
+			merged := s.counter(k.bannerID, k.tagID, k.day)
+			merged.Impressions += c.Impressions
+			merged.Clicks += c.Clicks
+		}
+		s.Unlock()
+
+		return fmt.Errorf("Flush: add counters failed %w", err)
+	}
+
+	return nil
+}
+
+// Run flushes the pending counters with requested interval until the context is done.
+// The counters pending on shutdown are flushed once more.
+func (s *StatsService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ctxFlush, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			defer cancel()
+
+			err := s.Flush(ctxFlush)
+			if err != nil {
+				logger.Log.Error("Run: flush counters on shutdown failed",
+					zap.Error(err))
+			}
+			return
+		case <-ticker.C:
+			err := s.Flush(ctx)
+			if err != nil {
+				logger.Log.Error("Run: flush counters failed",
+					zap.Error(err))
+			}
+		}
+	}
+}
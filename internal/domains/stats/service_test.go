@@ -0,0 +1,125 @@
+package stats_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/banner"
+	bannerrepo "github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	"github.com/pavlegich/banners-service/internal/domains/stats"
+	"github.com/pavlegich/banners-service/internal/domains/stats/repository"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingRepo fails to store the counters.
+type failingRepo struct {
+	*repository.MemoryRepository
+}
+
+// AddCounters implements the stats.Repository interface.
+func (r *failingRepo) AddCounters(ctx context.Context, counters []*stats.Counter) error {
+	return errors.New("storage is unavailable")
+}
+
+// newBanners returns the in-memory banners storage with the stored banner.
+func newBanners(t *testing.T) *bannerrepo.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+
+	banners := bannerrepo.NewBannerMemoryRepository(ctx, nil)
+	_, err := banners.CreateBanner(ctx, &banner.Banner{TagIDs: []int{1, 2}, FeatureID: 1, Content: &banner.Content{}})
+	require.NoError(t, err)
+
+	return banners
+}
+
+func TestStatsService_Stats(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewStatsMemoryRepository(ctx)
+	s := stats.NewStatsService(ctx, repo, newBanners(t))
+
+	today := stats.DayOf(time.Now())
+	yesterday := today.AddDate(0, 0, -1)
+	err := repo.AddCounters(ctx, []*stats.Counter{
+		{BannerID: 1, TagID: 1, Day: yesterday, Impressions: 10, Clicks: 1},
+		{BannerID: 1, TagID: 2, Day: yesterday.AddDate(0, 0, -5), Impressions: 100, Clicks: 100},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		s.TrackImpression(ctx, 1, 2)
+	}
+	require.NoError(t, s.TrackClick(ctx, 1, 2))
+
+	// The pending counters are included before the flush
+	st, err := s.Stats(ctx, 1, yesterday, today)
+	require.NoError(t, err)
+	want := &stats.Stats{
+		BannerID: 1,
+		From:     yesterday.Format(stats.DateLayout),
+		To:       today.Format(stats.DateLayout),
+		Totals:   stats.Totals{Impressions: 13, Clicks: 2, CTR: 2.0 / 13},
+		Tags: []stats.Tag{
+			{TagID: 1, Totals: stats.Totals{Impressions: 10, Clicks: 1, CTR: 0.1}},
+			{TagID: 2, Totals: stats.Totals{Impressions: 3, Clicks: 1, CTR: 1.0 / 3}},
+		},
+		Days: []stats.Day{
+			{Date: yesterday.Format(stats.DateLayout), Totals: stats.Totals{Impressions: 10, Clicks: 1, CTR: 0.1}},
+			{Date: today.Format(stats.DateLayout), Totals: stats.Totals{Impressions: 3, Clicks: 1, CTR: 1.0 / 3}},
+		},
+	}
+	assert.Equal(t, want, st)
+
+	require.NoError(t, s.Flush(ctx))
+	st, err = s.Stats(ctx, 1, yesterday, today)
+	require.NoError(t, err)
+	assert.Equal(t, want, st)
+
+	counters, err := repo.GetCounters(ctx, 1, today, today)
+	require.NoError(t, err)
+	require.Len(t, counters, 1)
+	assert.Equal(t, int64(3), counters[0].Impressions)
+
+	err = s.TrackClick(ctx, 2, 1)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+
+	_, err = s.Stats(ctx, 2, yesterday, today)
+	assert.ErrorIs(t, err, errs.ErrBannerNotFound)
+
+	var verr *errs.ValidationError
+	_, err = s.Stats(ctx, 1, today, yesterday)
+	require.ErrorAs(t, err, &verr)
+	_, err = s.Stats(ctx, 1, today.AddDate(-2, 0, 0), today)
+	require.ErrorAs(t, err, &verr)
+}
+
+func TestStatsService_FlushFailed(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewStatsMemoryRepository(ctx)
+	banners := newBanners(t)
+
+	failing := stats.NewStatsService(ctx, &failingRepo{repo}, banners)
+	failing.TrackImpression(ctx, 1, 1)
+	require.Error(t, failing.Flush(ctx))
+	failing.TrackImpression(ctx, 1, 1)
+
+	// The counters are kept pending until they are stored
+	today := stats.DayOf(time.Now())
+	st, err := failing.Stats(ctx, 1, today, today)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), st.Impressions)
+
+	s := stats.NewStatsService(ctx, repo, banners)
+	s.TrackImpression(ctx, 1, 1)
+	require.NoError(t, s.Flush(ctx))
+	require.NoError(t, s.Flush(ctx))
+
+	counters, err := repo.GetCounters(ctx, 1, today, today)
+	require.NoError(t, err)
+	require.Len(t, counters, 1)
+	assert.Equal(t, int64(1), counters[0].Impressions)
+}
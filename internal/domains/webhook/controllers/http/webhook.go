@@ -0,0 +1,287 @@
+// Package http contains webhook object functions
+// for activating the handler in controller, and handlers.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/banners-service/internal/domains/webhook"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"github.com/pavlegich/banners-service/internal/utils"
+	"go.uber.org/zap"
+)
+
+// maxBodySize is the maximum size of the request body in bytes.
+const maxBodySize = 1 << 16
+
+// defaultDeliveriesLimit is the number of the recent deliveries returned, if the limit is not set.
+const defaultDeliveriesLimit = 100
+
+// WebhookHandler contains objects for work with webhook handlers.
+type WebhookHandler struct {
+	Config  *config.Config
+	Service webhook.Service
+}
+
+// Activate activates handler for webhook object.
+func Activate(ctx context.Context, r *chi.Mux, cfg *config.Config, s webhook.Service) {
+	h := &WebhookHandler{
+		Config:  cfg,
+		Service: s,
+	}
+
+	r.Get("/webhook", h.HandleGetWebhooks)
+	r.Post("/webhook", h.HandleCreateWebhook)
+	r.Delete("/webhook/{id}", h.HandleDeleteWebhook)
+	r.Get("/webhook/delivery", h.HandleGetDeliveries)
+	r.Post("/webhook/delivery/{id}/redeliver", h.HandleRedeliver)
+}
+
+// HandleCreateWebhook handles admin's request to register new webhook.
+// The secret for checking the payload signatures is returned only in this response.
+func (h *WebhookHandler) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req webhook.Webhook
+
+	w.Header().Set("Content-Type", "application/json")
+
+	defer r.Body.Close()
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req)
+	if err != nil {
+		logger.Log.Error("HandleCreateWebhook: decode request body failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "request body must be a JSON object")
+		return
+	}
+
+	stored, err := h.Service.Register(ctx, &webhook.Webhook{
+		URL:    req.URL,
+		Events: req.Events,
+		Secret: req.Secret,
+	})
+	if err != nil {
+		logger.Log.Error("HandleCreateWebhook: register webhook failed",
+			zap.Error(err))
+
+		var verr *errs.ValidationError
+		if errors.As(err, &verr) {
+			utils.WriteValidationError(w, r, verr)
+			return
+		}
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	hookJSON, err := json.Marshal(stored)
+	if err != nil {
+		logger.Log.Error("HandleCreateWebhook: marshal webhook failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(hookJSON)
+}
+
+// HandleGetWebhooks handles admin's request to get list of the registered webhooks
+// without their secrets.
+func (h *WebhookHandler) HandleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	hooks, err := h.Service.List(ctx)
+	if err != nil {
+		logger.Log.Error("HandleGetWebhooks: get webhooks failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+
+	hooksJSON, err := json.Marshal(hooks)
+	if err != nil {
+		logger.Log.Error("HandleGetWebhooks: marshal webhooks failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(hooksJSON)
+}
+
+// HandleDeleteWebhook handles admin's request to delete the webhook with its deliveries.
+func (h *WebhookHandler) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	w.Header().Set("Content-Type", "application/json")
+	idString := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idString)
+	if err != nil || id < 1 {
+		logger.Log.Error("HandleDeleteWebhook: incorrect id parameter",
+			zap.String("id", idString),
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter must be a positive integer")
+		return
+	}
+
+	err = h.Service.Delete(ctx, id)
+	if err != nil {
+		logger.Log.Error("HandleDeleteWebhook: delete webhook failed",
+			zap.Error(err))
+
+		if errors.Is(err, errs.ErrWebhookNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "webhook not found")
+			return
+		}
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetDeliveries handles admin's request to get list of the recent webhook
+// deliveries, the dead letters are requested with the dead status.
+func (h *WebhookHandler) HandleGetDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter := webhook.DeliveryFilter{Limit: defaultDeliveriesLimit}
+	want := map[string]struct{}{
+		"webhook_id": {},
+		"status":     {},
+		"limit":      {},
+		"offset":     {},
+	}
+	statuses := []string{webhook.DeliveryPending, webhook.DeliveryDelivered, webhook.DeliveryDead}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	queries := r.URL.Query()
+	for val := range queries {
+		_, ok := want[val]
+		if !ok {
+			logger.Log.Error("HandleGetDeliveries: incorrect query",
+				zap.String("query", val))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query in request url")
+			return
+		}
+
+		if len(queries[val]) != 1 {
+			logger.Log.Error("HandleGetDeliveries: incorrect queries number",
+				zap.String("query_name", val),
+				zap.Int("query_number", len(queries[val])))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "incorrect query number in request url")
+			return
+		}
+
+		if val == "status" {
+			if !slices.Contains(statuses, queries[val][0]) {
+				logger.Log.Error("HandleGetDeliveries: unknown delivery status",
+					zap.String("query_value", queries[val][0]))
+
+				utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "status must be pending, delivered or dead")
+				return
+			}
+
+			filter.Status = queries[val][0]
+			continue
+		}
+
+		current, err := strconv.Atoi(queries[val][0])
+		if err != nil || current < 0 {
+			logger.Log.Error("HandleGetDeliveries: convert query to integer failed",
+				zap.String("query_name", val),
+				zap.String("query_value", queries[val][0]))
+
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidQuery, "query must be a non-negative integer")
+			return
+		}
+
+		switch val {
+		case "webhook_id":
+			filter.WebhookID = current
+		case "limit":
+			filter.Limit = current
+		case "offset":
+			filter.Offset = current
+		}
+	}
+
+	deliveries, err := h.Service.Deliveries(ctx, &filter)
+	if err != nil {
+		logger.Log.Error("HandleGetDeliveries: get webhook deliveries failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	deliveriesJSON, err := json.Marshal(deliveries)
+	if err != nil {
+		logger.Log.Error("HandleGetDeliveries: marshal webhook deliveries failed",
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(deliveriesJSON)
+}
+
+// HandleRedeliver handles admin's request to send the webhook delivery again,
+// e.g. the dead letter after the receiver is fixed.
+func (h *WebhookHandler) HandleRedeliver(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	w.Header().Set("Content-Type", "application/json")
+	idString := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idString, 10, 64)
+	if err != nil || id < 1 {
+		logger.Log.Error("HandleRedeliver: incorrect id parameter",
+			zap.String("id", idString),
+			zap.Error(err))
+
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidPath, "id parameter must be a positive integer")
+		return
+	}
+
+	err = h.Service.Redeliver(ctx, id)
+	if err != nil {
+		logger.Log.Error("HandleRedeliver: redeliver failed",
+			zap.Error(err))
+
+		if errors.Is(err, errs.ErrDeliveryNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "webhook delivery not found")
+			return
+		}
+
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
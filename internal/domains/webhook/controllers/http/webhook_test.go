@@ -0,0 +1,211 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/controllers/handlers"
+	auditrepo "github.com/pavlegich/banners-service/internal/domains/audit/repository"
+	"github.com/pavlegich/banners-service/internal/domains/banner/repository"
+	caprepo "github.com/pavlegich/banners-service/internal/domains/capping/repository"
+	"github.com/pavlegich/banners-service/internal/domains/catalog"
+	catalogrepo "github.com/pavlegich/banners-service/internal/domains/catalog/repository"
+	statsrepo "github.com/pavlegich/banners-service/internal/domains/stats/repository"
+	"github.com/pavlegich/banners-service/internal/domains/webhook"
+	webhookrepo "github.com/pavlegich/banners-service/internal/domains/webhook/repository"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCatalogRepo returns the in-memory catalog with the features and tags from 1 to count.
+func newCatalogRepo(t *testing.T, count int) *catalogrepo.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+
+	repo := catalogrepo.NewCatalogMemoryRepository(ctx)
+	for i := 1; i <= count; i++ {
+		for _, kind := range []string{catalog.KindFeature, catalog.KindTag} {
+			_, err := repo.CreateEntity(ctx, kind, &catalog.Entity{Name: fmt.Sprintf("%s %d", kind, i)})
+			require.NoError(t, err)
+		}
+	}
+
+	return repo
+}
+
+// serve sends the admin request to the route and returns the response.
+func serve(t *testing.T, h http.Handler, method string, url string, body string, headers map[string]string) (*http.Response, string) {
+	t.Helper()
+
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r.Header.Set("token", "admin_token")
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	gotBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(gotBody)
+}
+
+func TestWebhookHandler_BannerEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cfg := &config.Config{
+		WebhookTimeout:  time.Second,
+		WebhookAttempts: 3,
+		WebhookBackoff:  time.Millisecond,
+		WebhookInterval: 5 * time.Millisecond,
+		OutboxInterval:  5 * time.Millisecond,
+	}
+	ctrl := handlers.NewController(ctx, repository.NewBannerMemoryRepository(ctx, nil), repository.NewBannerCache(ctx, time.Minute, time.Minute), newCatalogRepo(t, 10),
+		auditrepo.NewAuditMemoryRepository(ctx), webhookrepo.NewWebhookMemoryRepository(ctx), statsrepo.NewStatsMemoryRepository(ctx), caprepo.NewCapMemoryRepository(ctx), cfg)
+	mh, err := ctrl.BuildRoute(ctx)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		ctrl.Relay(ctx)
+		wg.Done()
+	}()
+	go func() {
+		ctrl.Deliver(ctx)
+		wg.Done()
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	received := make(chan *webhook.Payload, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhook.Payload
+		json.NewDecoder(r.Body).Decode(&p)
+		received <- &p
+	}))
+	defer receiver.Close()
+
+	resp, body := serve(t, mh, http.MethodPost, "/webhook", `{"url":"`+receiver.URL+`","events":["created","deactivated"]}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+
+	var hook webhook.Webhook
+	require.NoError(t, json.Unmarshal([]byte(body), &hook))
+	assert.NotEmpty(t, hook.Secret)
+
+	resp, body = serve(t, mh, http.MethodPost, "/banner", `{"tag_ids":[1],"feature_id":1,"content":{"title":"some_title"},"is_active":true}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+	resp, body = serve(t, mh, http.MethodPatch, "/banner/1", `{"content":{"title":"new_title"}}`, map[string]string{"If-Match": `"1"`})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	resp, body = serve(t, mh, http.MethodPatch, "/banner/1", `{"is_active":false}`, map[string]string{"If-Match": `"2"`})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	// Only the subscribed events are delivered
+	for _, want := range []string{"created", "deactivated"} {
+		select {
+		case p := <-received:
+			assert.Equal(t, want, p.Event)
+			assert.Equal(t, 1, p.BannerID)
+			assert.NotEmpty(t, p.Key)
+		case <-time.After(2 * time.Second):
+			require.FailNow(t, "event not delivered", want)
+		}
+	}
+
+	require.Eventually(t, func() bool {
+		_, body := serve(t, mh, http.MethodGet, "/webhook/delivery?status=delivered", "", nil)
+		var deliveries []*webhook.Delivery
+		require.NoError(t, json.Unmarshal([]byte(body), &deliveries))
+		return len(deliveries) == 2
+	}, 2*time.Second, 5*time.Millisecond)
+
+	// Secrets are not listed
+	resp, body = serve(t, mh, http.MethodGet, "/webhook", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, body, hook.Secret)
+
+	resp, _ = serve(t, mh, http.MethodDelete, "/webhook/1", "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestWebhookHandler_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := handlers.NewController(ctx, repository.NewBannerMemoryRepository(ctx, nil), repository.NewBannerCache(ctx, time.Minute, time.Minute), newCatalogRepo(t, 10),
+		auditrepo.NewAuditMemoryRepository(ctx), webhookrepo.NewWebhookMemoryRepository(ctx), statsrepo.NewStatsMemoryRepository(ctx), caprepo.NewCapMemoryRepository(ctx), &config.Config{})
+	mh, err := ctrl.BuildRoute(ctx)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "invalid webhook",
+			method:   http.MethodPost,
+			url:      "/webhook",
+			body:     `{"url":"localhost","events":["viewed"]}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `"code":"validation_failed"`,
+		},
+		{
+			name:     "incorrect body",
+			method:   http.MethodPost,
+			url:      "/webhook",
+			body:     `[]`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `"code":"invalid_body"`,
+		},
+		{
+			name:     "unknown webhook",
+			method:   http.MethodDelete,
+			url:      "/webhook/5",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "unknown delivery status",
+			method:   http.MethodGet,
+			url:      "/webhook/delivery?status=failed",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown delivery",
+			method:   http.MethodPost,
+			url:      "/webhook/delivery/5/redeliver",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := serve(t, mh, tt.method, tt.url, tt.body, nil)
+			assert.Equal(t, tt.wantCode, resp.StatusCode, body)
+			assert.Contains(t, body, tt.wantBody)
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/webhook", nil)
+	r.Header.Set("token", "user_token")
+	w := httptest.NewRecorder()
+	mh.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,146 @@
+// Package webhook contains object and methods
+// for notifying the external systems about the banners changes.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+)
+
+// List of the banner lifecycle events, which might be delivered to the webhooks.
+var Events = []string{
+	audit.ActionCreated,
+	audit.ActionUpdated,
+	audit.ActionActivated,
+	audit.ActionDeactivated,
+	audit.ActionDeleted,
+	audit.ActionRestored,
+}
+
+// List of the webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// MaxURLLength is the maximum length of the webhook URL.
+const MaxURLLength = 2048
+
+// Webhook contains data of the registered webhook. Empty events
+// mean that the webhook receives all the events.
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Delivery contains data of the event delivery to the webhook. The key identifies
+// the banner change, so the change is delivered to the webhook only once.
+type Delivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	Key            string          `json:"key"`
+	Event          string          `json:"event"`
+	BannerID       int             `json:"banner_id"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// Payload contains data of the event sent to the webhook. The key identifies the banner
+// change, before and after are the banner states, null if there is no state.
+type Payload struct {
+	Key        string          `json:"key"`
+	Event      string          `json:"event"`
+	BannerID   int             `json:"banner_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// DeliveryFilter contains data for filtering the webhook deliveries,
+// zero values mean no filtering by the field.
+type DeliveryFilter struct {
+	WebhookID int
+	Status    string
+	Limit     int
+	Offset    int
+}
+
+// Service describes methods for communication between
+// handlers, other services and repositories.
+type Service interface {
+	Register(ctx context.Context, hook *Webhook) (*Webhook, error)
+	List(ctx context.Context) ([]*Webhook, error)
+	Delete(ctx context.Context, id int) error
+	Notify(ctx context.Context, key string, event string, bannerID int, before any, after any) error
+	Deliveries(ctx context.Context, filter *DeliveryFilter) ([]*Delivery, error)
+	Redeliver(ctx context.Context, id int64) error
+}
+
+// Repository describes methods related with webhooks
+// for interaction with the storage.
+type Repository interface {
+	CreateWebhook(ctx context.Context, hook *Webhook) (*Webhook, error)
+	GetWebhooks(ctx context.Context) ([]*Webhook, error)
+	DeleteWebhookByID(ctx context.Context, id int) error
+	CreateDeliveries(ctx context.Context, deliveries []*Delivery) (int, error)
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error)
+	UpdateDelivery(ctx context.Context, d *Delivery) error
+	GetDeliveryByID(ctx context.Context, id int64) (*Delivery, error)
+	GetDeliveriesByFilter(ctx context.Context, filter *DeliveryFilter) ([]*Delivery, error)
+}
+
+// Validate checks the webhook fields and returns the validation error
+// with all the failed fields, if any.
+func (h *Webhook) Validate() error {
+	verr := &errs.ValidationError{}
+
+	switch {
+	case h.URL == "":
+		verr.Add("url", errs.ValidationRequired, "is required")
+	case len(h.URL) > MaxURLLength:
+		verr.Add("url", errs.ValidationTooLong, fmt.Sprintf("must be at most %d characters", MaxURLLength))
+	default:
+		u, err := url.Parse(h.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			verr.Add("url", errs.ValidationInvalid, "must be an absolute HTTP or HTTPS URL")
+		}
+	}
+
+	seen := make(map[string]struct{}, len(h.Events))
+	for i, event := range h.Events {
+		field := fmt.Sprintf("events[%d]", i)
+		if !slices.Contains(Events, event) {
+			verr.Add(field, errs.ValidationInvalid, fmt.Sprintf("unknown event %q", event))
+			continue
+		}
+		if _, ok := seen[event]; ok {
+			verr.Add(field, errs.ValidationDuplicate, fmt.Sprintf("event %s is duplicated", event))
+			continue
+		}
+		seen[event] = struct{}{}
+	}
+
+	return verr.Err()
+}
+
+// Subscribed checks whether the webhook receives the event.
+func (h *Webhook) Subscribed(event string) bool {
+	return len(h.Events) == 0 || slices.Contains(h.Events, event)
+}
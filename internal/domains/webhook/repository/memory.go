@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/webhook"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+)
+
+// MemoryRepository contains webhooks and deliveries stored in memory
+// for tests and local development.
+type MemoryRepository struct {
+	sync.RWMutex
+	hooks      []webhook.Webhook
+	deliveries []webhook.Delivery
+	lastHookID int
+	lastID     int64
+}
+
+// NewWebhookMemoryRepository returns new in-memory webhooks repository object.
+func NewWebhookMemoryRepository(ctx context.Context) *MemoryRepository {
+	return &MemoryRepository{
+		hooks:      make([]webhook.Webhook, 0),
+		deliveries: make([]webhook.Delivery, 0),
+	}
+}
+
+// CreateWebhook stores new webhook into the storage.
+func (r *MemoryRepository) CreateWebhook(ctx context.Context, h *webhook.Webhook) (*webhook.Webhook, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastHookID++
+	h.ID = r.lastHookID
+	h.CreatedAt = time.Now().Round(time.Microsecond)
+
+	stored := *h
+	stored.Events = slices.Clone(h.Events)
+	r.hooks = append(r.hooks, stored)
+
+	return h, nil
+}
+
+// GetWebhooks gets and returns all the webhooks from the storage.
+func (r *MemoryRepository) GetWebhooks(ctx context.Context) ([]*webhook.Webhook, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	hooks := make([]*webhook.Webhook, 0, len(r.hooks))
+	for i := range r.hooks {
+		h := r.hooks[i]
+		h.Events = slices.Clone(h.Events)
+		hooks = append(hooks, &h)
+	}
+
+	return hooks, nil
+}
+
+// DeleteWebhookByID removes the requested webhook with its deliveries from the storage.
+func (r *MemoryRepository) DeleteWebhookByID(ctx context.Context, id int) error {
+	r.Lock()
+	defer r.Unlock()
+
+	i := slices.IndexFunc(r.hooks, func(h webhook.Webhook) bool { return h.ID == id })
+	if i == -1 {
+		return fmt.Errorf("DeleteWebhookByID: webhook %d %w", id, errs.ErrWebhookNotFound)
+	}
+
+	r.hooks = slices.Delete(r.hooks, i, i+1)
+	r.deliveries = slices.DeleteFunc(r.deliveries, func(d webhook.Delivery) bool { return d.WebhookID == id })
+
+	return nil
+}
+
+// CreateDeliveries stores new webhook deliveries into the storage and returns their number.
+// The deliveries with the key already stored for the webhook are skipped.
+func (r *MemoryRepository) CreateDeliveries(ctx context.Context, deliveries []*webhook.Delivery) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	created := 0
+	for _, d := range deliveries {
+		if d.Key != "" && slices.ContainsFunc(r.deliveries, func(stored webhook.Delivery) bool {
+			return stored.WebhookID == d.WebhookID && stored.Key == d.Key
+		}) {
+			continue
+		}
+
+		r.lastID++
+		d.ID = r.lastID
+		d.CreatedAt = time.Now().Round(time.Microsecond)
+		d.UpdatedAt = d.CreatedAt
+		r.deliveries = append(r.deliveries, *d)
+		created++
+	}
+
+	return created, nil
+}
+
+// ClaimDueDeliveries returns the pending deliveries, which next attempt time has come,
+// from oldest to newest, and postpones their next attempt for the lease time.
+func (r *MemoryRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*webhook.Delivery, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	deliveries := make([]*webhook.Delivery, 0)
+	for i := range r.deliveries {
+		if len(deliveries) == limit {
+			break
+		}
+
+		d := &r.deliveries[i]
+		if d.Status != webhook.DeliveryPending || d.NextAttemptAt.After(now) {
+			continue
+		}
+
+		d.NextAttemptAt = now.Add(lease)
+		claimed := *d
+		deliveries = append(deliveries, &claimed)
+	}
+
+	return deliveries, nil
+}
+
+// UpdateDelivery stores the delivery status and attempts into the storage.
+func (r *MemoryRepository) UpdateDelivery(ctx context.Context, d *webhook.Delivery) error {
+	r.Lock()
+	defer r.Unlock()
+
+	i := slices.IndexFunc(r.deliveries, func(stored webhook.Delivery) bool { return stored.ID == d.ID })
+	if i == -1 {
+		return fmt.Errorf("UpdateDelivery: delivery %d %w", d.ID, errs.ErrDeliveryNotFound)
+	}
+
+	d.UpdatedAt = time.Now().Round(time.Microsecond)
+	r.deliveries[i] = *d
+
+	return nil
+}
+
+// GetDeliveryByID gets and returns the requested delivery by ID from the storage.
+func (r *MemoryRepository) GetDeliveryByID(ctx context.Context, id int64) (*webhook.Delivery, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	i := slices.IndexFunc(r.deliveries, func(d webhook.Delivery) bool { return d.ID == id })
+	if i == -1 {
+		return nil, fmt.Errorf("GetDeliveryByID: delivery %d %w", id, errs.ErrDeliveryNotFound)
+	}
+
+	d := r.deliveries[i]
+	return &d, nil
+}
+
+// GetDeliveriesByFilter gets and returns the deliveries by filter
+// from the storage sorted from newest to oldest.
+func (r *MemoryRepository) GetDeliveriesByFilter(ctx context.Context, f *webhook.DeliveryFilter) ([]*webhook.Delivery, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	deliveries := make([]*webhook.Delivery, 0)
+	skipped := 0
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		d := r.deliveries[i]
+		if f.WebhookID != 0 && d.WebhookID != f.WebhookID {
+			continue
+		}
+		if f.Status != "" && d.Status != f.Status {
+			continue
+		}
+		if skipped < f.Offset {
+			skipped++
+			continue
+		}
+		if f.Limit != 0 && len(deliveries) == f.Limit {
+			break
+		}
+
+		deliveries = append(deliveries, &d)
+	}
+
+	return deliveries, nil
+}
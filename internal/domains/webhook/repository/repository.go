@@ -0,0 +1,246 @@
+// Package repository contains repository objects
+// and methods for interaction with webhooks storage.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pavlegich/banners-service/internal/domains/webhook"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+)
+
+// deliveryColumns are the selected columns of the webhook delivery.
+const deliveryColumns = "id, webhook_id, event_key, event, banner_id, payload, status, attempts, response_status, error, next_attempt_at, created_at, updated_at"
+
+// Repository contains storage objects for storing the webhooks.
+type Repository struct {
+	db *sql.DB
+}
+
+// NewWebhookRepository returns new webhooks repository object.
+func NewWebhookRepository(ctx context.Context, db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateWebhook stores new webhook into the storage.
+func (r *Repository) CreateWebhook(ctx context.Context, h *webhook.Webhook) (*webhook.Webhook, error) {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO webhooks (url, events, secret)
+	VALUES ($1, $2, $3) RETURNING id, created_at`,
+		h.URL, pq.StringArray(h.Events), h.Secret)
+
+	err := row.Scan(&h.ID, &h.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("CreateWebhook: scan row failed %w", err)
+	}
+
+	err = row.Err()
+	if err != nil {
+		return nil, fmt.Errorf("CreateWebhook: row.Err %w", err)
+	}
+
+	return h, nil
+}
+
+// GetWebhooks gets and returns all the webhooks from the storage.
+func (r *Repository) GetWebhooks(ctx context.Context) ([]*webhook.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, url, events, secret, created_at FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("GetWebhooks: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	hooks := make([]*webhook.Webhook, 0)
+	for rows.Next() {
+		var h webhook.Webhook
+		var events pq.StringArray
+		err = rows.Scan(&h.ID, &h.URL, &events, &h.Secret, &h.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("GetWebhooks: scan row failed %w", err)
+		}
+
+		h.Events = events
+		hooks = append(hooks, &h)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetWebhooks: rows.Err %w", err)
+	}
+
+	return hooks, nil
+}
+
+// DeleteWebhookByID removes the requested webhook with its deliveries from the storage.
+func (r *Repository) DeleteWebhookByID(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("DeleteWebhookByID: delete webhook failed %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteWebhookByID: get affected rows failed %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("DeleteWebhookByID: webhook %d %w", id, errs.ErrWebhookNotFound)
+	}
+
+	return nil
+}
+
+// CreateDeliveries stores new webhook deliveries into the storage in one transaction
+// and returns their number. The deliveries with the key already stored for the webhook are skipped.
+func (r *Repository) CreateDeliveries(ctx context.Context, deliveries []*webhook.Delivery) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("CreateDeliveries: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	created := 0
+	for _, d := range deliveries {
+		row := tx.QueryRowContext(ctx, `INSERT INTO webhook_deliveries (webhook_id, event_key, event, banner_id, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (webhook_id, event_key) WHERE event_key <> '' DO NOTHING
+		RETURNING id, created_at, updated_at`,
+			d.WebhookID, d.Key, d.Event, d.BannerID, string(d.Payload), d.Status, d.NextAttemptAt)
+
+		err = row.Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("CreateDeliveries: scan row failed %w", err)
+		}
+		created++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("CreateDeliveries: commit transaction failed %w", err)
+	}
+
+	return created, nil
+}
+
+// ClaimDueDeliveries returns the pending deliveries, which next attempt time has come,
+// from oldest to newest, and postpones their next attempt for the lease time.
+// The deliveries claimed by the other servers at the same time are skipped.
+func (r *Repository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*webhook.Delivery, error) {
+	rows, err := r.db.QueryContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = $2
+	WHERE id IN (SELECT id FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= $1
+	ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED)
+	RETURNING `+deliveryColumns, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("ClaimDueDeliveries: update rows failed %w", err)
+	}
+	defer rows.Close()
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("ClaimDueDeliveries: scan deliveries failed %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// UpdateDelivery stores the delivery status and attempts into the storage.
+func (r *Repository) UpdateDelivery(ctx context.Context, d *webhook.Delivery) error {
+	row := r.db.QueryRowContext(ctx, `UPDATE webhook_deliveries SET status = $1, attempts = $2, response_status = $3,
+	error = $4, next_attempt_at = $5, updated_at = NOW() WHERE id = $6 RETURNING updated_at`,
+		d.Status, d.Attempts, d.ResponseStatus, d.Error, d.NextAttemptAt, d.ID)
+
+	err := row.Scan(&d.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("UpdateDelivery: delivery %d %w", d.ID, errs.ErrDeliveryNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("UpdateDelivery: scan row failed %w", err)
+	}
+
+	return nil
+}
+
+// GetDeliveryByID gets and returns the requested delivery by ID from the storage.
+func (r *Repository) GetDeliveryByID(ctx context.Context, id int64) (*webhook.Delivery, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = $1", id)
+	if err != nil {
+		return nil, fmt.Errorf("GetDeliveryByID: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetDeliveryByID: scan deliveries failed %w", err)
+	}
+	if len(deliveries) == 0 {
+		return nil, fmt.Errorf("GetDeliveryByID: delivery %d %w", id, errs.ErrDeliveryNotFound)
+	}
+
+	return deliveries[0], nil
+}
+
+// GetDeliveriesByFilter gets and returns the deliveries by filter
+// from the storage sorted from newest to oldest.
+func (r *Repository) GetDeliveriesByFilter(ctx context.Context, f *webhook.DeliveryFilter) ([]*webhook.Delivery, error) {
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE true"
+	args := make([]any, 0)
+	if f.WebhookID != 0 {
+		args = append(args, f.WebhookID)
+		query += fmt.Sprintf(" AND webhook_id = $%d", len(args))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	query += " ORDER BY id DESC"
+
+	if f.Limit != 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+	query += fmt.Sprintf(" OFFSET %d", f.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetDeliveriesByFilter: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetDeliveriesByFilter: scan deliveries failed %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// scanDeliveries reads the webhook deliveries from the rows.
+func scanDeliveries(rows *sql.Rows) ([]*webhook.Delivery, error) {
+	deliveries := make([]*webhook.Delivery, 0)
+	for rows.Next() {
+		var d webhook.Delivery
+		var payload []byte
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Key, &d.Event, &d.BannerID, &payload, &d.Status, &d.Attempts,
+			&d.ResponseStatus, &d.Error, &d.NextAttemptAt, &d.CreatedAt, &d.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scanDeliveries: scan row failed %w", err)
+		}
+
+		d.Payload = payload
+		deliveries = append(deliveries, &d)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("scanDeliveries: rows.Err %w", err)
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,400 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/infra/logger"
+	"go.uber.org/zap"
+)
+
+// List of the headers of the delivered webhook request.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderKey       = "Idempotency-Key"
+)
+
+const (
+	// claimBatchSize is the number of the due deliveries sent at once.
+	claimBatchSize = 10
+	// deliveryLease is the time added to the timeouts of the batch requests, after which the
+	// claimed delivery is sent again, if its result has not been stored, e.g. the server stopped.
+	deliveryLease = time.Minute
+	// maxBackoff is the maximum delay between the delivery attempts.
+	maxBackoff = time.Hour
+	// secretSize is the size of the generated webhook secret in bytes.
+	secretSize = 32
+)
+
+// WebhookService contains objects for webhook service.
+type WebhookService struct {
+	repo        Repository
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	wake        chan struct{}
+}
+
+// NewWebhookService returns new webhook service. The failed delivery is attempted
+// again after the backoff doubled with every attempt, until the maximum attempts
+// number is reached and the delivery is moved to the dead letters.
+func NewWebhookService(ctx context.Context, repo Repository, client *http.Client, maxAttempts int, backoff time.Duration) *WebhookService {
+	return &WebhookService{
+		repo:        repo,
+		client:      client,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Register validates new webhook and puts it into the storage. The secret
+// for signing the payloads is generated, if it is not set.
+func (s *WebhookService) Register(ctx context.Context, hook *Webhook) (*Webhook, error) {
+	err := hook.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("Register: webhook is invalid %w", err)
+	}
+
+	if hook.Events == nil {
+		hook.Events = make([]string, 0)
+	}
+
+	if hook.Secret == "" {
+		secret := make([]byte, secretSize)
+		_, err = rand.Read(secret)
+		if err != nil {
+			return nil, fmt.Errorf("Register: generate secret failed %w", err)
+		}
+		hook.Secret = hex.EncodeToString(secret)
+	}
+
+	stored, err := s.repo.CreateWebhook(ctx, hook)
+	if err != nil {
+		return nil, fmt.Errorf("Register: create webhook failed %w", err)
+	}
+
+	return stored, nil
+}
+
+// List returns list of the registered webhooks.
+func (s *WebhookService) List(ctx context.Context) ([]*Webhook, error) {
+	hooks, err := s.repo.GetWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("List: get webhooks failed %w", err)
+	}
+
+	return hooks, nil
+}
+
+// Delete removes the requested webhook by ID with its deliveries from the storage.
+func (s *WebhookService) Delete(ctx context.Context, id int) error {
+	err := s.repo.DeleteWebhookByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("Delete: delete webhook failed %w", err)
+	}
+
+	return nil
+}
+
+// Notify stores the event deliveries to the webhooks subscribed to the event,
+// they are sent by Deliver. The deliveries of the key already notified are skipped,
+// so the event notified again is not delivered twice. Before and after are
+// the banner states, nil if there is no state.
+func (s *WebhookService) Notify(ctx context.Context, key string, event string, bannerID int, before any, after any) error {
+	hooks, err := s.repo.GetWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("Notify: get webhooks failed %w", err)
+	}
+
+	deliveries := make([]*Delivery, 0, len(hooks))
+	var payload json.RawMessage
+	for _, hook := range hooks {
+		if !hook.Subscribed(event) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = marshalPayload(key, event, bannerID, before, after)
+			if err != nil {
+				return fmt.Errorf("Notify: marshal payload failed %w", err)
+			}
+		}
+
+		deliveries = append(deliveries, &Delivery{
+			WebhookID:     hook.ID,
+			Key:           key,
+			Event:         event,
+			BannerID:      bannerID,
+			Payload:       payload,
+			Status:        DeliveryPending,
+			NextAttemptAt: time.Now(),
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	created, err := s.repo.CreateDeliveries(ctx, deliveries)
+	if err != nil {
+		return fmt.Errorf("Notify: create deliveries failed %w", err)
+	}
+
+	if created != 0 {
+		s.signal()
+	}
+
+	return nil
+}
+
+// Deliveries returns list of the webhook deliveries by filter from newest to oldest.
+func (s *WebhookService) Deliveries(ctx context.Context, filter *DeliveryFilter) ([]*Delivery, error) {
+	deliveries, err := s.repo.GetDeliveriesByFilter(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("Deliveries: get deliveries by filter failed %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver schedules the requested delivery by ID to be sent again
+// with the reset attempts, e.g. the dead letter after the receiver is fixed.
+func (s *WebhookService) Redeliver(ctx context.Context, id int64) error {
+	d, err := s.repo.GetDeliveryByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("Redeliver: get delivery failed %w", err)
+	}
+
+	d.Status = DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now()
+
+	err = s.repo.UpdateDelivery(ctx, d)
+	if err != nil {
+		return fmt.Errorf("Redeliver: update delivery failed %w", err)
+	}
+
+	s.signal()
+
+	return nil
+}
+
+// Deliver sends the due deliveries, when the new deliveries are stored and with
+// requested interval for the retries. The deliveries are claimed in the storage
+// before sending, so several servers might deliver them from the same storage.
+func (s *WebhookService) Deliver(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// The full batch means that more deliveries might be due
+		if s.deliverDue(ctx) == claimBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// signal wakes up the delivery without blocking.
+func (s *WebhookService) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliverDue claims and sends the batch of the due deliveries and returns the number
+// of the claimed deliveries. The webhooks are sent concurrently, but the deliveries
+// to the same webhook are sent in order of the events, except the retried ones.
+func (s *WebhookService) deliverDue(ctx context.Context) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, time.Now(), claimBatchSize*s.client.Timeout+deliveryLease, claimBatchSize)
+	if err != nil {
+		logger.Log.Error("deliverDue: claim due deliveries failed",
+			zap.Error(err))
+		return 0
+	}
+	if len(deliveries) == 0 {
+		return 0
+	}
+
+	hooks, err := s.repo.GetWebhooks(ctx)
+	if err != nil {
+		logger.Log.Error("deliverDue: get webhooks failed",
+			zap.Error(err))
+		return 0
+	}
+
+	byHook := make(map[int][]*Delivery, len(hooks))
+	for _, d := range deliveries {
+		byHook[d.WebhookID] = append(byHook[d.WebhookID], d)
+	}
+
+	var wg sync.WaitGroup
+	for _, hook := range hooks {
+		queue, ok := byHook[hook.ID]
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(hook *Webhook, queue []*Delivery) {
+			defer wg.Done()
+			for _, d := range queue {
+				s.attempt(ctx, hook, d)
+			}
+		}(hook, queue)
+	}
+	wg.Wait()
+
+	return len(deliveries)
+}
+
+// attempt sends the delivery to the webhook and stores the attempt result. The failed
+// delivery is scheduled for the next attempt or moved to the dead letters.
+func (s *WebhookService) attempt(ctx context.Context, hook *Webhook, d *Delivery) {
+	status, err := s.send(ctx, hook, d)
+
+	// The claim expires and the delivery is attempted again after restart
+	if ctx.Err() != nil {
+		return
+	}
+
+	d.Attempts++
+	d.ResponseStatus = status
+	d.Error = ""
+	switch {
+	case err == nil:
+		d.Status = DeliveryDelivered
+	case d.Attempts >= s.maxAttempts:
+		d.Status = DeliveryDead
+		d.Error = err.Error()
+	default:
+		d.Error = err.Error()
+		d.NextAttemptAt = time.Now().Add(s.delay(d.Attempts))
+	}
+
+	if err != nil {
+		logger.Log.Info("webhook delivery failed",
+			zap.Int64("delivery_id", d.ID),
+			zap.Int("webhook_id", hook.ID),
+			zap.Int("attempts", d.Attempts),
+			zap.String("status", d.Status),
+			zap.Error(err))
+	}
+
+	err = s.repo.UpdateDelivery(ctx, d)
+	if err != nil {
+		logger.Log.Error("attempt: update delivery failed",
+			zap.Int64("delivery_id", d.ID),
+			zap.Error(err))
+	}
+}
+
+// send posts the signed delivery payload to the webhook URL and returns
+// the response status code. Only 2xx status codes mean the successful delivery.
+func (s *WebhookService) send(ctx context.Context, hook *Webhook, d *Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("send: create request failed %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.ID, 10))
+	if d.Key != "" {
+		req.Header.Set(HeaderKey, d.Key)
+	}
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("send: request failed %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("send: unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// delay returns the delay before the next delivery attempt after the failed attempts.
+func (s *WebhookService) delay(attempts int) time.Duration {
+	delay := s.backoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxBackoff)
+}
+
+// Sign returns the signature of the payload sent at the timestamp in seconds,
+// which is the hex-encoded HMAC-SHA256 of "timestamp.payload" with the webhook secret.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// marshalPayload returns JSON representation of the event payload.
+func marshalPayload(key string, event string, bannerID int, before any, after any) (json.RawMessage, error) {
+	p := &Payload{
+		Key:        key,
+		Event:      event,
+		BannerID:   bannerID,
+		OccurredAt: time.Now(),
+	}
+
+	var err error
+	if before != nil {
+		p.Before, err = json.Marshal(before)
+		if err != nil {
+			return nil, fmt.Errorf("marshalPayload: marshal state before failed %w", err)
+		}
+	}
+	if after != nil {
+		p.After, err = json.Marshal(after)
+		if err != nil {
+			return nil, fmt.Errorf("marshalPayload: marshal state after failed %w", err)
+		}
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("marshalPayload: marshal failed %w", err)
+	}
+
+	return data, nil
+}
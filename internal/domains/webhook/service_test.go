@@ -0,0 +1,236 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/banners-service/internal/domains/audit"
+	"github.com/pavlegich/banners-service/internal/domains/webhook"
+	"github.com/pavlegich/banners-service/internal/domains/webhook/repository"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is the webhook receiver, which fails the requests until
+// the failures number is reached.
+type receiver struct {
+	sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.Lock()
+	defer rc.Unlock()
+
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	if rc.failures > 0 {
+		rc.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// received returns the number of the received requests.
+func (rc *receiver) received() int {
+	rc.Lock()
+	defer rc.Unlock()
+
+	return len(rc.requests)
+}
+
+// newService returns the webhook service on the in-memory storage delivering
+// the events until the test is finished.
+func newService(t *testing.T, maxAttempts int) *webhook.WebhookService {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := webhook.NewWebhookService(ctx, repository.NewWebhookMemoryRepository(ctx), &http.Client{Timeout: time.Second}, maxAttempts, time.Millisecond)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		s.Deliver(ctx, 5*time.Millisecond)
+		wg.Done()
+	}()
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	return s
+}
+
+// waitStatus waits until the delivery has the status and returns it.
+func waitStatus(t *testing.T, s *webhook.WebhookService, webhookID int, status string) *webhook.Delivery {
+	t.Helper()
+
+	var found *webhook.Delivery
+	require.Eventually(t, func() bool {
+		deliveries, err := s.Deliveries(context.Background(), &webhook.DeliveryFilter{WebhookID: webhookID, Status: status})
+		require.NoError(t, err)
+		if len(deliveries) == 0 {
+			return false
+		}
+		found = deliveries[0]
+		return true
+	}, 2*time.Second, 5*time.Millisecond)
+
+	return found
+}
+
+func TestWebhookService_Deliver(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, 5)
+
+	rc := &receiver{failures: 2}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	hook, err := s.Register(ctx, &webhook.Webhook{URL: srv.URL, Events: []string{audit.ActionCreated}, Secret: "secret"})
+	require.NoError(t, err)
+	other, err := s.Register(ctx, &webhook.Webhook{URL: srv.URL, Events: []string{audit.ActionDeleted}})
+	require.NoError(t, err)
+	assert.Len(t, other.Secret, 64)
+
+	err = s.Notify(ctx, "banner-7-1", audit.ActionCreated, 7, nil, map[string]any{"title": "some_title"})
+	require.NoError(t, err)
+
+	// Delivered after the retries
+	delivered := waitStatus(t, s, hook.ID, webhook.DeliveryDelivered)
+	assert.Equal(t, 3, delivered.Attempts)
+	assert.Equal(t, http.StatusOK, delivered.ResponseStatus)
+	assert.Empty(t, delivered.Error)
+	assert.Equal(t, 3, rc.received())
+
+	// Other webhook is not subscribed to the event
+	none, err := s.Deliveries(ctx, &webhook.DeliveryFilter{WebhookID: other.ID})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	rc.Lock()
+	defer rc.Unlock()
+
+	req, body := rc.requests[2], rc.bodies[2]
+	assert.Equal(t, audit.ActionCreated, req.Header.Get(webhook.HeaderEvent))
+	assert.Equal(t, strconv.FormatInt(delivered.ID, 10), req.Header.Get(webhook.HeaderDelivery))
+	assert.Equal(t, "banner-7-1", req.Header.Get(webhook.HeaderKey))
+
+	timestamp, err := strconv.ParseInt(req.Header.Get(webhook.HeaderTimestamp), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.Sign("secret", timestamp, body), req.Header.Get(webhook.HeaderSignature))
+	assert.NotEqual(t, webhook.Sign("other", timestamp, body), req.Header.Get(webhook.HeaderSignature))
+
+	var payload webhook.Payload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, "banner-7-1", payload.Key)
+	assert.Equal(t, audit.ActionCreated, payload.Event)
+	assert.Equal(t, 7, payload.BannerID)
+	assert.Equal(t, "null", string(payload.Before))
+	assert.JSONEq(t, `{"title": "some_title"}`, string(payload.After))
+}
+
+func TestWebhookService_DeadLetter(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, 2)
+
+	rc := &receiver{failures: 2}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	hook, err := s.Register(ctx, &webhook.Webhook{URL: srv.URL})
+	require.NoError(t, err)
+
+	err = s.Notify(ctx, "banner-1-2", audit.ActionDeleted, 1, map[string]any{"title": "some_title"}, nil)
+	require.NoError(t, err)
+
+	dead := waitStatus(t, s, hook.ID, webhook.DeliveryDead)
+	assert.Equal(t, 2, dead.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, dead.ResponseStatus)
+	assert.Contains(t, dead.Error, "unexpected response status 500")
+
+	// Dead letter is sent again after the receiver is fixed
+	err = s.Redeliver(ctx, dead.ID)
+	require.NoError(t, err)
+
+	delivered := waitStatus(t, s, hook.ID, webhook.DeliveryDelivered)
+	assert.Equal(t, dead.ID, delivered.ID)
+	assert.Equal(t, 1, delivered.Attempts)
+
+	err = s.Redeliver(ctx, 100)
+	assert.ErrorIs(t, err, errs.ErrDeliveryNotFound)
+
+	// Deliveries are deleted with the webhook
+	err = s.Delete(ctx, hook.ID)
+	require.NoError(t, err)
+	err = s.Delete(ctx, hook.ID)
+	assert.ErrorIs(t, err, errs.ErrWebhookNotFound)
+
+	none, err := s.Deliveries(ctx, &webhook.DeliveryFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func TestWebhookService_NotifyOnce(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, 1)
+
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	hook, err := s.Register(ctx, &webhook.Webhook{URL: srv.URL})
+	require.NoError(t, err)
+
+	// The change published again by the outbox relay is delivered once
+	for i := 0; i < 3; i++ {
+		err = s.Notify(ctx, "banner-3-2", audit.ActionUpdated, 3, nil, nil)
+		require.NoError(t, err)
+	}
+	err = s.Notify(ctx, "banner-3-3", audit.ActionDeleted, 3, nil, nil)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		deliveries, err := s.Deliveries(ctx, &webhook.DeliveryFilter{WebhookID: hook.ID, Status: webhook.DeliveryDelivered})
+		require.NoError(t, err)
+		return len(deliveries) == 2
+	}, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, 2, rc.received())
+}
+
+func TestWebhookService_Register(t *testing.T) {
+	ctx := context.Background()
+	s := webhook.NewWebhookService(ctx, repository.NewWebhookMemoryRepository(ctx), http.DefaultClient, 1, time.Second)
+
+	_, err := s.Register(ctx, &webhook.Webhook{URL: "ftp://example.com", Events: []string{"created", "viewed", "created"}})
+	var verr *errs.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []errs.FieldError{
+		{Field: "url", Code: errs.ValidationInvalid, Message: "must be an absolute HTTP or HTTPS URL"},
+		{Field: "events[1]", Code: errs.ValidationInvalid, Message: `unknown event "viewed"`},
+		{Field: "events[2]", Code: errs.ValidationDuplicate, Message: "event created is duplicated"},
+	}, verr.Fields)
+
+	_, err = s.Register(ctx, &webhook.Webhook{})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "url", verr.Fields[0].Field)
+	assert.Equal(t, errs.ValidationRequired, verr.Fields[0].Code)
+
+	hooks, err := s.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, hooks)
+}
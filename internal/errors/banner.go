@@ -8,4 +8,6 @@ var (
 	ErrBannerInCacheNotFound = errors.New("banner in cache not found")
 	ErrBannerExpired         = errors.New("banner content expired")
 	ErrBannerNotAllowed      = errors.New("not allowed for user")
+	ErrBannerVersionConflict = errors.New("banner version conflict")
+	ErrBannerCapReached      = errors.New("banner frequency cap reached")
 )
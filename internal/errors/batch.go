@@ -0,0 +1,25 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBatchNotApplied is returned when the batch is not applied because of the failed items.
+var ErrBatchNotApplied = errors.New("batch not applied")
+
+// BatchItemError contains the error of the batch item, which could not be applied.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("batch item %d: %s", e.Index, e.Err)
+}
+
+// Unwrap returns the error of the batch item.
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
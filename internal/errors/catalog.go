@@ -0,0 +1,50 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrFeatureNotFound  = errors.New("feature not found")
+	ErrEntityInUse      = errors.New("referenced by banners")
+	ErrUnknownReference = errors.New("unknown reference")
+)
+
+// ReferenceError contains the fields referencing the unknown tags or features.
+type ReferenceError struct {
+	Fields []FieldError
+}
+
+// Add appends the field referencing the unknown entity.
+func (e *ReferenceError) Add(field string, message string) {
+	e.Fields = append(e.Fields, FieldError{
+		Field:   field,
+		Code:    ValidationUnknown,
+		Message: message,
+	})
+}
+
+// Err returns the reference error, if any field references the unknown entity, or nil.
+func (e *ReferenceError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error implements the error interface.
+func (e *ReferenceError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		fields = append(fields, fmt.Sprintf("%s %s", f.Field, f.Message))
+	}
+	return fmt.Sprintf("%s: %s", ErrUnknownReference, strings.Join(fields, "; "))
+}
+
+// Unwrap returns ErrUnknownReference for errors.Is checks.
+func (e *ReferenceError) Unwrap() error {
+	return ErrUnknownReference
+}
@@ -0,0 +1,65 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrValidationFailed is wrapped by the validation errors.
+var ErrValidationFailed = errors.New("validation failed")
+
+// List of the codes of the failed field validations.
+const (
+	ValidationRequired    = "required"
+	ValidationPositive    = "positive"
+	ValidationDuplicate   = "duplicate"
+	ValidationTooMany     = "too_many"
+	ValidationTooLong     = "too_long"
+	ValidationInvalidType = "invalid_type"
+	ValidationInvalid     = "invalid"
+	ValidationUnknown     = "unknown"
+)
+
+// FieldError contains the failed validation of the object field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError contains the failed validations of the object fields.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Add appends the failed validation of the field.
+func (e *ValidationError) Add(field string, code string, message string) {
+	e.Fields = append(e.Fields, FieldError{
+		Field:   field,
+		Code:    code,
+		Message: message,
+	})
+}
+
+// Err returns the validation error, if any field validation failed, or nil.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		fields = append(fields, fmt.Sprintf("%s %s", f.Field, f.Message))
+	}
+	return fmt.Sprintf("%s: %s", ErrValidationFailed, strings.Join(fields, "; "))
+}
+
+// Unwrap returns ErrValidationFailed for errors.Is checks.
+func (e *ValidationError) Unwrap() error {
+	return ErrValidationFailed
+}
@@ -6,6 +6,9 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v6"
@@ -14,11 +17,39 @@ import (
 // Config contains values of server flags and environments.
 type Config struct {
 	Address           string        `env:"ADDRESS" json:"address"`
+	GRPCAddress       string        `env:"GRPC_ADDRESS" json:"grpc_address"`
 	DSN               string        `env:"DATABASE_DSN" json:"database_dsn"`
+	Storage           string        `env:"STORAGE" json:"storage"`
 	CleanupInterval   time.Duration `env:"CLEANUP_INTERVAL" json:"cleanup_interval"`
 	DefaultExpiration time.Duration `env:"DEFAULT_EXPIRATION" json:"default_expiration"`
+	MaxConns          int           `env:"DATABASE_MAX_CONNS" json:"database_max_conns"`
+	MinConns          int           `env:"DATABASE_MIN_CONNS" json:"database_min_conns"`
+	MaxConnLifetime   time.Duration `env:"DATABASE_MAX_CONN_LIFETIME" json:"database_max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `env:"DATABASE_MAX_CONN_IDLE_TIME" json:"database_max_conn_idle_time"`
+	ReplicaDSNs       []string      `env:"DATABASE_REPLICA_DSNS" envSeparator:"," json:"database_replica_dsns"`
+	ReplicaMaxLag     time.Duration `env:"DATABASE_REPLICA_MAX_LAG" json:"database_replica_max_lag"`
+	ReplicaCheck      time.Duration `env:"DATABASE_REPLICA_CHECK_INTERVAL" json:"database_replica_check_interval"`
+	DeletedRetention  time.Duration `env:"DELETED_RETENTION" json:"deleted_retention"`
+	PurgeInterval     time.Duration `env:"PURGE_INTERVAL" json:"purge_interval"`
+	WebhookTimeout    time.Duration `env:"WEBHOOK_TIMEOUT" json:"webhook_timeout"`
+	WebhookAttempts   int           `env:"WEBHOOK_MAX_ATTEMPTS" json:"webhook_max_attempts"`
+	WebhookBackoff    time.Duration `env:"WEBHOOK_BACKOFF" json:"webhook_backoff"`
+	WebhookInterval   time.Duration `env:"WEBHOOK_INTERVAL" json:"webhook_interval"`
+	OutboxInterval    time.Duration `env:"OUTBOX_INTERVAL" json:"outbox_interval"`
+	StatsInterval     time.Duration `env:"STATS_INTERVAL" json:"stats_interval"`
+	TemplateVars      []string      `env:"TEMPLATE_VARS" envSeparator:"," json:"template_vars"`
 }
 
+// templateVarPattern is the pattern of the request variable name available in the banner templates.
+var templateVarPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// List of available storage implementations.
+const (
+	StorageSQL    = "sql"
+	StoragePgx    = "pgx"
+	StorageMemory = "memory"
+)
+
 // NewConfig returns new server config.
 func NewConfig(ctx context.Context) *Config {
 	return &Config{}
@@ -27,16 +58,92 @@ func NewConfig(ctx context.Context) *Config {
 // ParseFlags handles and processes flags and environments values
 // when launching the server.
 func (cfg *Config) ParseFlags(ctx context.Context) error {
-	flag.StringVar(&cfg.Address, "a", "localhost:8080", "HTTP-server endpoint address host:port")
-	flag.StringVar(&cfg.DSN, "d", "postgresql://localhost:5432/postgres", "URI (DSN) to database")
-	flag.DurationVar(&cfg.CleanupInterval, "clean", time.Duration(10)*time.Minute, "HTTP-server endpoint address host:port")
-	flag.DurationVar(&cfg.DefaultExpiration, "exp", time.Duration(5)*time.Minute, "URI (DSN) to database")
+	return cfg.ParseArgs(ctx, flag.CommandLine, os.Args[1:])
+}
+
+// ParseArgs handles and processes the arguments with the flag set, which might
+// contain additional flags of the command, and environments values.
+func (cfg *Config) ParseArgs(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	fs.StringVar(&cfg.Address, "a", "localhost:8080", "HTTP-server endpoint address host:port")
+	fs.StringVar(&cfg.GRPCAddress, "g", "localhost:9090", "gRPC-server endpoint address host:port")
+	fs.StringVar(&cfg.DSN, "d", "postgresql://localhost:5432/postgres", "URI (DSN) to database")
+	fs.StringVar(&cfg.Storage, "s", StorageSQL, "banners storage implementation: sql, pgx or memory")
+	fs.DurationVar(&cfg.CleanupInterval, "clean", time.Duration(10)*time.Minute, "HTTP-server endpoint address host:port")
+	fs.DurationVar(&cfg.DefaultExpiration, "exp", time.Duration(5)*time.Minute, "URI (DSN) to database")
+	fs.IntVar(&cfg.MaxConns, "max-conns", 10, "maximum size of the database connection pool")
+	fs.IntVar(&cfg.MinConns, "min-conns", 2, "minimum size of the database connection pool")
+	fs.DurationVar(&cfg.MaxConnLifetime, "max-conn-lifetime", time.Hour, "maximum lifetime of the database connection")
+	fs.DurationVar(&cfg.MaxConnIdleTime, "max-conn-idle-time", time.Duration(30)*time.Minute, "maximum idle time of the database connection")
 
-	flag.Parse()
+	fs.Func("r", "comma-separated URIs (DSN) to database read replicas", func(s string) error {
+		cfg.ReplicaDSNs = strings.Split(s, ",")
+		return nil
+	})
+	fs.DurationVar(&cfg.ReplicaMaxLag, "replica-max-lag", time.Duration(10)*time.Second, "maximum replication lag for reading from the replica")
+	fs.DurationVar(&cfg.ReplicaCheck, "replica-check", time.Duration(5)*time.Second, "interval of the replicas health check")
+	fs.DurationVar(&cfg.DeletedRetention, "retention", time.Duration(30*24)*time.Hour, "retention period of the deleted banners before purge")
+	fs.DurationVar(&cfg.PurgeInterval, "purge", time.Hour, "interval of the deleted banners purge")
+	fs.DurationVar(&cfg.WebhookTimeout, "webhook-timeout", time.Duration(10)*time.Second, "timeout of the webhook delivery request")
+	fs.IntVar(&cfg.WebhookAttempts, "webhook-attempts", 8, "maximum attempts of the webhook delivery before moving it to the dead letters")
+	fs.DurationVar(&cfg.WebhookBackoff, "webhook-backoff", time.Duration(10)*time.Second, "delay after the first failed webhook delivery attempt, doubled with every attempt")
+	fs.DurationVar(&cfg.WebhookInterval, "webhook-interval", time.Duration(5)*time.Second, "interval of checking the webhook deliveries due for retry")
+	fs.DurationVar(&cfg.OutboxInterval, "outbox-interval", time.Duration(1)*time.Second, "interval of publishing the banner changes from the outbox")
+	fs.DurationVar(&cfg.StatsInterval, "stats-interval", time.Duration(10)*time.Second, "interval of flushing the counted banner impressions and clicks into the storage")
+
+	fs.Func("template-vars", "comma-separated names of the request variables available in the banner content templates, "+
+		"got from the var.<name> queries or X-Banner-Var-<Name> headers", func(s string) error {
+		cfg.TemplateVars = strings.Split(s, ",")
+		return nil
+	})
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("ParseArgs: parse arguments failed %w", err)
+	}
 
-	err := env.Parse(cfg)
+	err = env.Parse(cfg)
 	if err != nil {
-		return fmt.Errorf("ParseFlags: wrong environment values %w", err)
+		return fmt.Errorf("ParseArgs: wrong environment values %w", err)
+	}
+
+	switch cfg.Storage {
+	case StorageSQL, StoragePgx:
+	case StorageMemory:
+		if len(cfg.ReplicaDSNs) != 0 {
+			return fmt.Errorf("ParseArgs: replicas are not supported by %s storage", cfg.Storage)
+		}
+	default:
+		return fmt.Errorf("ParseArgs: unknown storage %s", cfg.Storage)
+	}
+
+	// The intervals are used by the tickers, which do not accept the non-positive duration
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"cache cleanup interval", cfg.CleanupInterval},
+		{"replicas health check interval", cfg.ReplicaCheck},
+		{"purge interval", cfg.PurgeInterval},
+		{"deleted banners retention", cfg.DeletedRetention},
+		{"webhook timeout", cfg.WebhookTimeout},
+		{"webhook backoff", cfg.WebhookBackoff},
+		{"webhook interval", cfg.WebhookInterval},
+		{"outbox interval", cfg.OutboxInterval},
+		{"stats interval", cfg.StatsInterval},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			return fmt.Errorf("ParseArgs: %s must be positive, got %s", d.name, d.value)
+		}
+	}
+	if cfg.WebhookAttempts <= 0 {
+		return fmt.Errorf("ParseArgs: webhook attempts must be positive, got %d", cfg.WebhookAttempts)
+	}
+
+	for _, name := range cfg.TemplateVars {
+		if !templateVarPattern.MatchString(name) {
+			return fmt.Errorf("ParseArgs: incorrect template variable name %q", name)
+		}
 	}
 
 	return nil
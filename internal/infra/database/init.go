@@ -16,26 +16,50 @@ var embedMigrations embed.FS
 // Init initializes database and creates the tables
 // from the specified migrations.
 func Init(ctx context.Context, path string) (*sql.DB, error) {
-	// Open and validate database
-	db, err := sql.Open("pgx", path)
+	db, err := Open(ctx, path)
 	if err != nil {
-		return nil, fmt.Errorf("Init: couldn't open database %w", err)
-	}
-	err = db.PingContext(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("Init: connection with database is died %w", err)
+		return nil, fmt.Errorf("Init: open database failed %w", err)
 	}
 
 	// Migrations
 	goose.SetBaseFS(embedMigrations)
 	err = goose.SetDialect("postgres")
 	if err != nil {
+		db.Close()
 		return nil, fmt.Errorf("Init: goose set dialect failed %w", err)
 	}
 	err = goose.Up(db, "migrations")
 	if err != nil {
+		db.Close()
 		return nil, fmt.Errorf("Init: goose up failed %w", err)
 	}
 
 	return db, nil
 }
+
+// Open opens and validates database without applying the migrations,
+// e.g. for the read-only replicas.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := Connect(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("Open: couldn't open database %w", err)
+	}
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Open: connection with database is died %w", err)
+	}
+
+	return db, nil
+}
+
+// Connect opens database without validating the connection, which is established
+// on the first query, e.g. for the replicas not available yet.
+func Connect(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", path)
+	if err != nil {
+		return nil, fmt.Errorf("Connect: couldn't open database %w", err)
+	}
+
+	return db, nil
+}
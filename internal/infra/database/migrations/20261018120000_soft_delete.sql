@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+-- create indexes
+CREATE INDEX IF NOT EXISTS deleted_at_idx ON banners (deleted_at);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX deleted_at_idx;
+ALTER TABLE banners DROP COLUMN deleted_at;
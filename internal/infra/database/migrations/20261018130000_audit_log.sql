@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id bigserial PRIMARY KEY,
+    banner_id integer NOT NULL,
+    actor text NOT NULL,
+    action text NOT NULL,
+    request_id text NOT NULL DEFAULT '',
+    before jsonb,
+    after jsonb,
+    diff jsonb NOT NULL DEFAULT '{}',
+    created_at timestamptz DEFAULT NOW()
+);
+
+-- create indexes
+CREATE INDEX IF NOT EXISTS audit_log_banner_id_idx ON audit_log (banner_id);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at);
+
+-- forbid changing the audit log entries
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION audit_log_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit log entries are immutable';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_log_immutable BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_immutable();
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TRIGGER audit_log_immutable ON audit_log;
+DROP FUNCTION audit_log_immutable;
+DROP INDEX audit_log_created_at_idx;
+DROP INDEX audit_log_actor_idx;
+DROP INDEX audit_log_banner_id_idx;
+DROP TABLE audit_log;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE IF NOT EXISTS banner_outbox (
+    id bigserial PRIMARY KEY,
+    key text NOT NULL,
+    action text NOT NULL,
+    banner_id integer NOT NULL,
+    before jsonb,
+    after jsonb,
+    created_at timestamptz DEFAULT NOW(),
+    locked_until timestamptz NOT NULL DEFAULT '-infinity'
+);
+
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS event_key text NOT NULL DEFAULT '';
+
+-- create indexes
+CREATE INDEX IF NOT EXISTS banner_outbox_locked_until_idx ON banner_outbox (locked_until);
+CREATE UNIQUE INDEX IF NOT EXISTS webhook_deliveries_event_key_idx ON webhook_deliveries (webhook_id, event_key) WHERE event_key <> '';
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX webhook_deliveries_event_key_idx;
+DROP INDEX banner_outbox_locked_until_idx;
+ALTER TABLE webhook_deliveries DROP COLUMN event_key;
+DROP TABLE banner_outbox;
@@ -0,0 +1,81 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE IF NOT EXISTS features (
+    id serial PRIMARY KEY,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    owner text NOT NULL DEFAULT '',
+    created_at timestamptz DEFAULT NOW(),
+    updated_at timestamptz DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id serial PRIMARY KEY,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    owner text NOT NULL DEFAULT '',
+    created_at timestamptz DEFAULT NOW(),
+    updated_at timestamptz DEFAULT NOW()
+);
+
+-- register the features and tags already referenced by the banners
+INSERT INTO features (id, name)
+SELECT DISTINCT feature_id, 'feature ' || feature_id FROM banners
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO tags (id, name)
+SELECT DISTINCT tag_id, 'tag ' || tag_id FROM banners, unnest(tag_ids) AS tag_id
+ON CONFLICT (id) DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('features', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM features;
+SELECT setval(pg_get_serial_sequence('tags', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM tags;
+
+ALTER TABLE banners ADD CONSTRAINT banners_feature_id_fkey FOREIGN KEY (feature_id) REFERENCES features (id);
+
+-- arrays can not be referenced by the foreign key, so tag_ids are checked by the triggers
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION banners_check_tag_ids() RETURNS trigger AS $$
+DECLARE
+    unknown integer;
+BEGIN
+    SELECT tag_id INTO unknown FROM unnest(NEW.tag_ids) AS tag_id
+    WHERE NOT EXISTS (SELECT 1 FROM tags WHERE tags.id = tag_id) LIMIT 1;
+    IF FOUND THEN
+        RAISE foreign_key_violation USING MESSAGE = format('tag %s is not present in table "tags"', unknown);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION tags_check_banners() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM banners WHERE OLD.id = ANY (banners.tag_ids)) THEN
+        RAISE foreign_key_violation USING MESSAGE = format('tag %s is still referenced from table "banners"', OLD.id);
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER banners_check_tag_ids BEFORE INSERT OR UPDATE OF tag_ids ON banners
+    FOR EACH ROW EXECUTE FUNCTION banners_check_tag_ids();
+CREATE TRIGGER tags_check_banners BEFORE DELETE ON tags
+    FOR EACH ROW EXECUTE FUNCTION tags_check_banners();
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TRIGGER tags_check_banners ON tags;
+DROP TRIGGER banners_check_tag_ids ON banners;
+DROP FUNCTION tags_check_banners();
+DROP FUNCTION banners_check_tag_ids();
+ALTER TABLE banners DROP CONSTRAINT banners_feature_id_fkey;
+DROP TABLE tags;
+DROP TABLE features;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS weight integer NOT NULL DEFAULT 0
+    CONSTRAINT banners_weight_check CHECK (weight BETWEEN 0 AND 10000);
+
+CREATE INDEX IF NOT EXISTS banners_variants_idx ON banners (feature_id)
+    WHERE weight > 0 AND is_active AND deleted_at IS NULL;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS banners_variants_idx;
+
+ALTER TABLE banners DROP COLUMN IF EXISTS weight;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE IF NOT EXISTS banner_stats (
+    banner_id integer NOT NULL REFERENCES banners (id) ON DELETE CASCADE,
+    tag_id integer NOT NULL,
+    day date NOT NULL,
+    impressions bigint NOT NULL DEFAULT 0,
+    clicks bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (banner_id, tag_id, day)
+);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE IF EXISTS banner_stats;
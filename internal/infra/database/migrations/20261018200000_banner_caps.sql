@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS cap_impressions integer NOT NULL DEFAULT 0
+    CONSTRAINT banners_cap_impressions_check CHECK (cap_impressions >= 0);
+
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS cap_window integer NOT NULL DEFAULT 0
+    CONSTRAINT banners_cap_window_check CHECK (cap_window >= 0);
+
+CREATE TABLE IF NOT EXISTS banner_caps (
+    banner_id integer NOT NULL REFERENCES banners (id) ON DELETE CASCADE,
+    user_id text NOT NULL,
+    impressions integer NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (banner_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS banner_caps_expires_at_idx ON banner_caps (expires_at);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE IF EXISTS banner_caps;
+
+ALTER TABLE banners DROP COLUMN IF EXISTS cap_window;
+
+ALTER TABLE banners DROP COLUMN IF EXISTS cap_impressions;
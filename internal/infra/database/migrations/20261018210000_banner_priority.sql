@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS priority integer NOT NULL DEFAULT 0
+    CONSTRAINT banners_priority_check CHECK (priority BETWEEN 0 AND 1000);
+
+-- the user banner is searched by the overlap of the tags, which the btree index does not serve
+CREATE INDEX IF NOT EXISTS banners_tag_ids_gin_idx ON banners USING gin (tag_ids)
+    WHERE is_active AND deleted_at IS NULL;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS banners_tag_ids_gin_idx;
+
+ALTER TABLE banners DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS is_default boolean NOT NULL DEFAULT false;
+
+-- the default banner of the feature is searched, when no banner matches the requested tags
+CREATE INDEX IF NOT EXISTS banners_default_idx ON banners (feature_id, priority DESC, updated_at DESC)
+    WHERE is_default AND is_active AND deleted_at IS NULL;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS banners_default_idx;
+
+ALTER TABLE banners DROP COLUMN IF EXISTS is_default;
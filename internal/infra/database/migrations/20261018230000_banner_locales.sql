@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS localized_content jsonb NOT NULL DEFAULT '{}';
+
+ALTER TABLE features ADD COLUMN IF NOT EXISTS default_locale text NOT NULL DEFAULT '';
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE features DROP COLUMN IF EXISTS default_locale;
+
+ALTER TABLE banners DROP COLUMN IF EXISTS localized_content;
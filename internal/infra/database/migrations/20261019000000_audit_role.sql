@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT '';
+
+-- the actor of the entries recorded before is the role
+ALTER TABLE audit_log DISABLE TRIGGER audit_log_immutable;
+UPDATE audit_log SET role = actor WHERE role = '';
+ALTER TABLE audit_log ENABLE TRIGGER audit_log_immutable;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE audit_log DROP COLUMN IF EXISTS role;
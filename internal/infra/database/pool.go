@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pavlegich/banners-service/internal/infra/config"
+)
+
+// InitPool applies the migrations and returns new native pgx connection pool
+// configured with the pool settings from config. The afterConnect function,
+// if set, is called for every new connection in the pool.
+func InitPool(ctx context.Context, cfg *config.Config, afterConnect func(context.Context, *pgx.Conn) error) (*pgxpool.Pool, error) {
+	// Migrations
+	db, err := Init(ctx, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("InitPool: apply migrations failed %w", err)
+	}
+	err = db.Close()
+	if err != nil {
+		return nil, fmt.Errorf("InitPool: close migrations connection failed %w", err)
+	}
+
+	pool, err := OpenPool(ctx, cfg.DSN, cfg, afterConnect)
+	if err != nil {
+		return nil, fmt.Errorf("InitPool: open pool failed %w", err)
+	}
+
+	return pool, nil
+}
+
+// OpenPool opens and validates native pgx connection pool to the database
+// from path without applying the migrations, e.g. for the read-only replicas.
+func OpenPool(ctx context.Context, path string, cfg *config.Config, afterConnect func(context.Context, *pgx.Conn) error) (*pgxpool.Pool, error) {
+	pool, err := ConnectPool(ctx, path, cfg, afterConnect)
+	if err != nil {
+		return nil, fmt.Errorf("OpenPool: create pool failed %w", err)
+	}
+
+	err = pool.Ping(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("OpenPool: connection with database is died %w", err)
+	}
+
+	return pool, nil
+}
+
+// ConnectPool returns new native pgx connection pool without validating the connection,
+// the connections are established on the first query, e.g. for the replicas not available yet.
+func ConnectPool(ctx context.Context, path string, cfg *config.Config, afterConnect func(context.Context, *pgx.Conn) error) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("ConnectPool: parse pool config failed %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = int32(cfg.MaxConns)
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = int32(cfg.MinConns)
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	poolCfg.AfterConnect = afterConnect
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("ConnectPool: create pool failed %w", err)
+	}
+
+	return pool, nil
+}
@@ -51,13 +51,21 @@ func (r *LoggingResponseWriter) WriteHeader(statusCode int) {
 }
 
 // Write implements writing the response and capturing the body size
-// and body itself.
+// and body itself, if the body buffer is set.
 func (r *LoggingResponseWriter) Write(b []byte) (int, error) {
 	size, err := r.ResponseWriter.Write(b)
 	if err != nil {
 		return size, fmt.Errorf("Write: response write %w", err)
 	}
 	r.ResponseData.Size += size
-	r.ResponseData.Body.Write(b)
+	if r.ResponseData.Body != nil {
+		r.ResponseData.Body.Write(b)
+	}
 	return size, nil
 }
+
+// Unwrap returns the original response writer, so the response controller
+// might flush the streamed response.
+func (r *LoggingResponseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
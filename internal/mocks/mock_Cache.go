@@ -49,6 +49,62 @@ func (mr *MockCacheMockRecorder) CreateBanner(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBanner", reflect.TypeOf((*MockCache)(nil).CreateBanner), arg0, arg1)
 }
 
+// CreateBannerByFilter mocks base method.
+func (m *MockCache) CreateBannerByFilter(arg0 context.Context, arg1 int, arg2 []int, arg3 *banner.Banner) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBannerByFilter", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBannerByFilter indicates an expected call of CreateBannerByFilter.
+func (mr *MockCacheMockRecorder) CreateBannerByFilter(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBannerByFilter", reflect.TypeOf((*MockCache)(nil).CreateBannerByFilter), arg0, arg1, arg2, arg3)
+}
+
+// CreateBannerVariants mocks base method.
+func (m *MockCache) CreateBannerVariants(arg0 context.Context, arg1, arg2 int, arg3 []*banner.Banner) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBannerVariants", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBannerVariants indicates an expected call of CreateBannerVariants.
+func (mr *MockCacheMockRecorder) CreateBannerVariants(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBannerVariants", reflect.TypeOf((*MockCache)(nil).CreateBannerVariants), arg0, arg1, arg2, arg3)
+}
+
+// CreateBanners mocks base method.
+func (m *MockCache) CreateBanners(arg0 context.Context, arg1 []*banner.Banner) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBanners", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBanners indicates an expected call of CreateBanners.
+func (mr *MockCacheMockRecorder) CreateBanners(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBanners", reflect.TypeOf((*MockCache)(nil).CreateBanners), arg0, arg1)
+}
+
+// CreateDefaultBanner mocks base method.
+func (m *MockCache) CreateDefaultBanner(arg0 context.Context, arg1 int, arg2 *banner.Banner) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDefaultBanner", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateDefaultBanner indicates an expected call of CreateDefaultBanner.
+func (mr *MockCacheMockRecorder) CreateDefaultBanner(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDefaultBanner", reflect.TypeOf((*MockCache)(nil).CreateDefaultBanner), arg0, arg1, arg2)
+}
+
 // DeleteBanner mocks base method.
 func (m *MockCache) DeleteBanner(arg0 context.Context, arg1, arg2, arg3 int) error {
 	m.ctrl.T.Helper()
@@ -76,7 +132,7 @@ func (mr *MockCacheMockRecorder) GarbageCollect(arg0 interface{}) *gomock.Call {
 }
 
 // GetBannerByFilter mocks base method.
-func (m *MockCache) GetBannerByFilter(arg0 context.Context, arg1, arg2 int) (*banner.Banner, error) {
+func (m *MockCache) GetBannerByFilter(arg0 context.Context, arg1 int, arg2 []int) (*banner.Banner, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetBannerByFilter", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*banner.Banner)
@@ -89,3 +145,33 @@ func (mr *MockCacheMockRecorder) GetBannerByFilter(arg0, arg1, arg2 interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBannerByFilter", reflect.TypeOf((*MockCache)(nil).GetBannerByFilter), arg0, arg1, arg2)
 }
+
+// GetBannerVariants mocks base method.
+func (m *MockCache) GetBannerVariants(arg0 context.Context, arg1, arg2 int) ([]*banner.Banner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBannerVariants", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*banner.Banner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBannerVariants indicates an expected call of GetBannerVariants.
+func (mr *MockCacheMockRecorder) GetBannerVariants(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBannerVariants", reflect.TypeOf((*MockCache)(nil).GetBannerVariants), arg0, arg1, arg2)
+}
+
+// GetDefaultBanner mocks base method.
+func (m *MockCache) GetDefaultBanner(arg0 context.Context, arg1 int) (*banner.Banner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDefaultBanner", arg0, arg1)
+	ret0, _ := ret[0].(*banner.Banner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDefaultBanner indicates an expected call of GetDefaultBanner.
+func (mr *MockCacheMockRecorder) GetDefaultBanner(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefaultBanner", reflect.TypeOf((*MockCache)(nil).GetDefaultBanner), arg0, arg1)
+}
@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	banner "github.com/pavlegich/banners-service/internal/domains/banner"
@@ -35,6 +36,21 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ClaimOutboxEvents mocks base method.
+func (m *MockRepository) ClaimOutboxEvents(arg0 context.Context, arg1 time.Time, arg2 time.Duration, arg3 int) ([]*banner.OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimOutboxEvents", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]*banner.OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimOutboxEvents indicates an expected call of ClaimOutboxEvents.
+func (mr *MockRepositoryMockRecorder) ClaimOutboxEvents(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOutboxEvents", reflect.TypeOf((*MockRepository)(nil).ClaimOutboxEvents), arg0, arg1, arg2, arg3)
+}
+
 // CreateBanner mocks base method.
 func (m *MockRepository) CreateBanner(arg0 context.Context, arg1 *banner.Banner) (*banner.Banner, error) {
 	m.ctrl.T.Helper()
@@ -51,21 +67,35 @@ func (mr *MockRepositoryMockRecorder) CreateBanner(arg0, arg1 interface{}) *gomo
 }
 
 // DeleteBannerByID mocks base method.
-func (m *MockRepository) DeleteBannerByID(arg0 context.Context, arg1 int) error {
+func (m *MockRepository) DeleteBannerByID(arg0 context.Context, arg1, arg2 int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteBannerByID", arg0, arg1)
+	ret := m.ctrl.Call(m, "DeleteBannerByID", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteBannerByID indicates an expected call of DeleteBannerByID.
-func (mr *MockRepositoryMockRecorder) DeleteBannerByID(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) DeleteBannerByID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBannerByID", reflect.TypeOf((*MockRepository)(nil).DeleteBannerByID), arg0, arg1, arg2)
+}
+
+// DeleteOutboxEvents mocks base method.
+func (m *MockRepository) DeleteOutboxEvents(arg0 context.Context, arg1 []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOutboxEvents", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOutboxEvents indicates an expected call of DeleteOutboxEvents.
+func (mr *MockRepositoryMockRecorder) DeleteOutboxEvents(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBannerByID", reflect.TypeOf((*MockRepository)(nil).DeleteBannerByID), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOutboxEvents", reflect.TypeOf((*MockRepository)(nil).DeleteOutboxEvents), arg0, arg1)
 }
 
 // GetBannerByFilter mocks base method.
-func (m *MockRepository) GetBannerByFilter(arg0 context.Context, arg1, arg2 int) (*banner.Banner, error) {
+func (m *MockRepository) GetBannerByFilter(arg0 context.Context, arg1 int, arg2 []int) (*banner.Banner, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetBannerByFilter", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*banner.Banner)
@@ -79,19 +109,109 @@ func (mr *MockRepositoryMockRecorder) GetBannerByFilter(arg0, arg1, arg2 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBannerByFilter", reflect.TypeOf((*MockRepository)(nil).GetBannerByFilter), arg0, arg1, arg2)
 }
 
+// GetBannerByID mocks base method.
+func (m *MockRepository) GetBannerByID(arg0 context.Context, arg1 int) (*banner.Banner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBannerByID", arg0, arg1)
+	ret0, _ := ret[0].(*banner.Banner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBannerByID indicates an expected call of GetBannerByID.
+func (mr *MockRepositoryMockRecorder) GetBannerByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBannerByID", reflect.TypeOf((*MockRepository)(nil).GetBannerByID), arg0, arg1)
+}
+
+// GetBannerVariants mocks base method.
+func (m *MockRepository) GetBannerVariants(arg0 context.Context, arg1 int, arg2 []int) ([]*banner.Banner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBannerVariants", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*banner.Banner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBannerVariants indicates an expected call of GetBannerVariants.
+func (mr *MockRepositoryMockRecorder) GetBannerVariants(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBannerVariants", reflect.TypeOf((*MockRepository)(nil).GetBannerVariants), arg0, arg1, arg2)
+}
+
 // GetBannersByFilter mocks base method.
-func (m *MockRepository) GetBannersByFilter(arg0 context.Context, arg1, arg2, arg3, arg4 int) ([]*banner.Banner, error) {
+func (m *MockRepository) GetBannersByFilter(arg0 context.Context, arg1, arg2, arg3, arg4 int, arg5 bool) ([]*banner.Banner, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetBannersByFilter", arg0, arg1, arg2, arg3, arg4)
+	ret := m.ctrl.Call(m, "GetBannersByFilter", arg0, arg1, arg2, arg3, arg4, arg5)
 	ret0, _ := ret[0].([]*banner.Banner)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetBannersByFilter indicates an expected call of GetBannersByFilter.
-func (mr *MockRepositoryMockRecorder) GetBannersByFilter(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetBannersByFilter(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBannersByFilter", reflect.TypeOf((*MockRepository)(nil).GetBannersByFilter), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// GetDefaultBanner mocks base method.
+func (m *MockRepository) GetDefaultBanner(arg0 context.Context, arg1 int) (*banner.Banner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDefaultBanner", arg0, arg1)
+	ret0, _ := ret[0].(*banner.Banner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDefaultBanner indicates an expected call of GetDefaultBanner.
+func (mr *MockRepositoryMockRecorder) GetDefaultBanner(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefaultBanner", reflect.TypeOf((*MockRepository)(nil).GetDefaultBanner), arg0, arg1)
+}
+
+// PurgeDeletedBanners mocks base method.
+func (m *MockRepository) PurgeDeletedBanners(arg0 context.Context, arg1 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedBanners", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedBanners indicates an expected call of PurgeDeletedBanners.
+func (mr *MockRepositoryMockRecorder) PurgeDeletedBanners(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedBanners", reflect.TypeOf((*MockRepository)(nil).PurgeDeletedBanners), arg0, arg1)
+}
+
+// RestoreBannerByID mocks base method.
+func (m *MockRepository) RestoreBannerByID(arg0 context.Context, arg1 int) (*banner.Banner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreBannerByID", arg0, arg1)
+	ret0, _ := ret[0].(*banner.Banner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreBannerByID indicates an expected call of RestoreBannerByID.
+func (mr *MockRepositoryMockRecorder) RestoreBannerByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreBannerByID", reflect.TypeOf((*MockRepository)(nil).RestoreBannerByID), arg0, arg1)
+}
+
+// SaveBanners mocks base method.
+func (m *MockRepository) SaveBanners(arg0 context.Context, arg1 []*banner.Banner) ([]*banner.Banner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveBanners", arg0, arg1)
+	ret0, _ := ret[0].([]*banner.Banner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveBanners indicates an expected call of SaveBanners.
+func (mr *MockRepositoryMockRecorder) SaveBanners(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBannersByFilter", reflect.TypeOf((*MockRepository)(nil).GetBannersByFilter), arg0, arg1, arg2, arg3, arg4)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveBanners", reflect.TypeOf((*MockRepository)(nil).SaveBanners), arg0, arg1)
 }
 
 // UpdateBanner mocks base method.
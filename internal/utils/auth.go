@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// RoleByToken returns user role for the authorization token
+// or empty string, if the token is unknown.
+func RoleByToken(token string) string {
+	switch token {
+	case "admin_token":
+		return "admin"
+	case "user_token":
+		return "user"
+	default:
+		return ""
+	}
+}
+
+// SubjectByToken returns the identity of the authorization token holder, which is
+// stable for the token and does not reveal it, or empty string for the empty token.
+func SubjectByToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:8])
+}
@@ -12,6 +12,9 @@ const (
 	// List of const variables contains variables for
 	// put values into and get values from the context.
 	ContextRoleKey contextKey = iota
+	ContextReplicaReadKey
+	ContextUserIDKey
+	ContextSubjectKey
 )
 
 // GetUserRoleFromContext finds and returns user role from the context.
@@ -26,3 +29,42 @@ func GetUserRoleFromContext(ctx context.Context) (string, error) {
 	}
 	return userRole, nil
 }
+
+// WithReplicaRead returns the copy of context, which allows
+// the storage to serve the reads from the replicas.
+func WithReplicaRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ContextReplicaReadKey, true)
+}
+
+// GetReplicaReadFromContext reports whether the reads from the replicas
+// are allowed by the context.
+func GetReplicaReadFromContext(ctx context.Context) bool {
+	allowed, _ := ctx.Value(ContextReplicaReadKey).(bool)
+	return allowed
+}
+
+// WithUserID returns the copy of context with the ID of the user,
+// which the banner variants are chosen for.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, ContextUserIDKey, userID)
+}
+
+// GetUserIDFromContext returns the ID of the user from the context
+// or the empty string for the anonymous user.
+func GetUserIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(ContextUserIDKey).(string)
+	return userID
+}
+
+// WithSubject returns the copy of context with the identity of the authenticated
+// token holder, which the changes are made by.
+func WithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, ContextSubjectKey, subject)
+}
+
+// GetSubjectFromContext returns the identity of the authenticated token holder
+// from the context or the empty string, if there is none.
+func GetSubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(ContextSubjectKey).(string)
+	return subject
+}
@@ -2,8 +2,43 @@ package utils
 
 import (
 	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	errs "github.com/pavlegich/banners-service/internal/errors"
+)
+
+// List of the machine-readable codes of the error responses.
+const (
+	CodeInvalidQuery         = "invalid_query"
+	CodeInvalidPath          = "invalid_path"
+	CodeInvalidHeader        = "invalid_header"
+	CodeInvalidBody          = "invalid_body"
+	CodeBodyTooLarge         = "body_too_large"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnknownReference     = "unknown_reference"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeBannerNotActive      = "banner_not_active"
+	CodeFrequencyCapped      = "frequency_capped"
+	CodeNotFound             = "not_found"
+	CodeInUse                = "in_use"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeVersionConflict      = "version_conflict"
+	CodeBatchNotApplied      = "batch_not_applied"
+	CodePreconditionRequired = "precondition_required"
+	CodeInternal             = "internal_error"
 )
 
+// ErrorResponse contains data of the error response.
+type ErrorResponse struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"error"`
+	Details   []errs.FieldError `json:"details,omitempty"`
+	Version   int               `json:"version,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
 // ParamToJSON converts param with requested name to JSON output format.
 func ParamToJSON(name string, desc string) []byte {
 	resp := map[string]string{
@@ -13,3 +48,42 @@ func ParamToJSON(name string, desc string) []byte {
 
 	return out
 }
+
+// WriteError writes the error response with the requested status code,
+// machine-readable code and message.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code string, message string) {
+	WriteErrorResponse(w, r, status, &ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
+
+// WriteValidationError writes the error response with the failed field validations.
+func WriteValidationError(w http.ResponseWriter, r *http.Request, verr *errs.ValidationError) {
+	WriteErrorResponse(w, r, http.StatusBadRequest, &ErrorResponse{
+		Code:    CodeValidationFailed,
+		Message: "request validation failed",
+		Details: verr.Fields,
+	})
+}
+
+// WriteReferenceError writes the error response with the fields referencing
+// the unknown tags or features.
+func WriteReferenceError(w http.ResponseWriter, r *http.Request, rerr *errs.ReferenceError) {
+	WriteErrorResponse(w, r, http.StatusUnprocessableEntity, &ErrorResponse{
+		Code:    CodeUnknownReference,
+		Message: "request references unknown entities",
+		Details: rerr.Fields,
+	})
+}
+
+// WriteErrorResponse writes the error response with the requested status code
+// and the request ID.
+func WriteErrorResponse(w http.ResponseWriter, r *http.Request, status int, resp *ErrorResponse) {
+	resp.RequestID = middleware.GetReqID(r.Context())
+	out, _ := json.Marshal(resp)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(out)
+}
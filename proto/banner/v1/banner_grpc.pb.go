@@ -0,0 +1,269 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: banner/v1/banner.proto
+
+// Banners service API for internal clients.
+
+package bannerv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	BannerService_GetUserBanner_FullMethodName = "/banner.v1.BannerService/GetUserBanner"
+	BannerService_CreateBanner_FullMethodName  = "/banner.v1.BannerService/CreateBanner"
+	BannerService_ListBanners_FullMethodName   = "/banner.v1.BannerService/ListBanners"
+	BannerService_UpdateBanner_FullMethodName  = "/banner.v1.BannerService/UpdateBanner"
+	BannerService_DeleteBanner_FullMethodName  = "/banner.v1.BannerService/DeleteBanner"
+)
+
+// BannerServiceClient is the client API for BannerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BannerServiceClient interface {
+	// GetUserBanner returns the banner content for the user by feature and tag.
+	GetUserBanner(ctx context.Context, in *GetUserBannerRequest, opts ...grpc.CallOption) (*GetUserBannerResponse, error)
+	// CreateBanner creates new banner.
+	CreateBanner(ctx context.Context, in *CreateBannerRequest, opts ...grpc.CallOption) (*CreateBannerResponse, error)
+	// ListBanners returns the banners by filter.
+	ListBanners(ctx context.Context, in *ListBannersRequest, opts ...grpc.CallOption) (*ListBannersResponse, error)
+	// UpdateBanner partially updates the banner.
+	UpdateBanner(ctx context.Context, in *UpdateBannerRequest, opts ...grpc.CallOption) (*UpdateBannerResponse, error)
+	// DeleteBanner deletes the banner.
+	DeleteBanner(ctx context.Context, in *DeleteBannerRequest, opts ...grpc.CallOption) (*DeleteBannerResponse, error)
+}
+
+type bannerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBannerServiceClient(cc grpc.ClientConnInterface) BannerServiceClient {
+	return &bannerServiceClient{cc}
+}
+
+func (c *bannerServiceClient) GetUserBanner(ctx context.Context, in *GetUserBannerRequest, opts ...grpc.CallOption) (*GetUserBannerResponse, error) {
+	out := new(GetUserBannerResponse)
+	err := c.cc.Invoke(ctx, BannerService_GetUserBanner_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannerServiceClient) CreateBanner(ctx context.Context, in *CreateBannerRequest, opts ...grpc.CallOption) (*CreateBannerResponse, error) {
+	out := new(CreateBannerResponse)
+	err := c.cc.Invoke(ctx, BannerService_CreateBanner_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannerServiceClient) ListBanners(ctx context.Context, in *ListBannersRequest, opts ...grpc.CallOption) (*ListBannersResponse, error) {
+	out := new(ListBannersResponse)
+	err := c.cc.Invoke(ctx, BannerService_ListBanners_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannerServiceClient) UpdateBanner(ctx context.Context, in *UpdateBannerRequest, opts ...grpc.CallOption) (*UpdateBannerResponse, error) {
+	out := new(UpdateBannerResponse)
+	err := c.cc.Invoke(ctx, BannerService_UpdateBanner_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannerServiceClient) DeleteBanner(ctx context.Context, in *DeleteBannerRequest, opts ...grpc.CallOption) (*DeleteBannerResponse, error) {
+	out := new(DeleteBannerResponse)
+	err := c.cc.Invoke(ctx, BannerService_DeleteBanner_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BannerServiceServer is the server API for BannerService service.
+// All implementations must embed UnimplementedBannerServiceServer
+// for forward compatibility
+type BannerServiceServer interface {
+	// GetUserBanner returns the banner content for the user by feature and tag.
+	GetUserBanner(context.Context, *GetUserBannerRequest) (*GetUserBannerResponse, error)
+	// CreateBanner creates new banner.
+	CreateBanner(context.Context, *CreateBannerRequest) (*CreateBannerResponse, error)
+	// ListBanners returns the banners by filter.
+	ListBanners(context.Context, *ListBannersRequest) (*ListBannersResponse, error)
+	// UpdateBanner partially updates the banner.
+	UpdateBanner(context.Context, *UpdateBannerRequest) (*UpdateBannerResponse, error)
+	// DeleteBanner deletes the banner.
+	DeleteBanner(context.Context, *DeleteBannerRequest) (*DeleteBannerResponse, error)
+	mustEmbedUnimplementedBannerServiceServer()
+}
+
+// UnimplementedBannerServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedBannerServiceServer struct {
+}
+
+func (UnimplementedBannerServiceServer) GetUserBanner(context.Context, *GetUserBannerRequest) (*GetUserBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserBanner not implemented")
+}
+func (UnimplementedBannerServiceServer) CreateBanner(context.Context, *CreateBannerRequest) (*CreateBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBanner not implemented")
+}
+func (UnimplementedBannerServiceServer) ListBanners(context.Context, *ListBannersRequest) (*ListBannersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBanners not implemented")
+}
+func (UnimplementedBannerServiceServer) UpdateBanner(context.Context, *UpdateBannerRequest) (*UpdateBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateBanner not implemented")
+}
+func (UnimplementedBannerServiceServer) DeleteBanner(context.Context, *DeleteBannerRequest) (*DeleteBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteBanner not implemented")
+}
+func (UnimplementedBannerServiceServer) mustEmbedUnimplementedBannerServiceServer() {}
+
+// UnsafeBannerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BannerServiceServer will
+// result in compilation errors.
+type UnsafeBannerServiceServer interface {
+	mustEmbedUnimplementedBannerServiceServer()
+}
+
+func RegisterBannerServiceServer(s grpc.ServiceRegistrar, srv BannerServiceServer) {
+	s.RegisterService(&BannerService_ServiceDesc, srv)
+}
+
+func _BannerService_GetUserBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerServiceServer).GetUserBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerService_GetUserBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerServiceServer).GetUserBanner(ctx, req.(*GetUserBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannerService_CreateBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerServiceServer).CreateBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerService_CreateBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerServiceServer).CreateBanner(ctx, req.(*CreateBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannerService_ListBanners_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBannersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerServiceServer).ListBanners(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerService_ListBanners_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerServiceServer).ListBanners(ctx, req.(*ListBannersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannerService_UpdateBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerServiceServer).UpdateBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerService_UpdateBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerServiceServer).UpdateBanner(ctx, req.(*UpdateBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannerService_DeleteBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerServiceServer).DeleteBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerService_DeleteBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerServiceServer).DeleteBanner(ctx, req.(*DeleteBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BannerService_ServiceDesc is the grpc.ServiceDesc for BannerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BannerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "banner.v1.BannerService",
+	HandlerType: (*BannerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetUserBanner",
+			Handler:    _BannerService_GetUserBanner_Handler,
+		},
+		{
+			MethodName: "CreateBanner",
+			Handler:    _BannerService_CreateBanner_Handler,
+		},
+		{
+			MethodName: "ListBanners",
+			Handler:    _BannerService_ListBanners_Handler,
+		},
+		{
+			MethodName: "UpdateBanner",
+			Handler:    _BannerService_UpdateBanner_Handler,
+		},
+		{
+			MethodName: "DeleteBanner",
+			Handler:    _BannerService_DeleteBanner_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "banner/v1/banner.proto",
+}